@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/daniel-butler/rss-graph/pkg/feed"
+	"github.com/daniel-butler/rss-graph/pkg/fetcher"
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+	"github.com/daniel-butler/rss-graph/pkg/schedule"
+	"github.com/daniel-butler/rss-graph/pkg/websub"
+)
+
+// idleCheck is the longest the daemon sleeps, so that new subscriptions are
+// picked up promptly.
+const idleCheck = 5 * time.Minute
+
+func cmdDaemon(fs *flag.FlagSet, args []string, dbPath *string) error {
+	policy := schedule.DefaultPolicy()
+	workers := fs.Int("workers", 4, "Feeds to fetch at once")
+	fs.DurationVar(&policy.Min, "min-interval", policy.Min, "Shortest time between polls of a feed")
+	fs.DurationVar(&policy.Max, "max-interval", policy.Max, "Longest time between polls of a healthy feed")
+	fs.DurationVar(&policy.MaxBackoff, "max-backoff", policy.MaxBackoff, "Longest time between polls of a failing feed")
+	snapshotDays := fs.Int("snapshot-days", 1, "Days between mention snapshots (0 to disable)")
+	callback := fs.String("websub-callback", "", "Public URL hubs can push to, e.g. https://example.com/websub (enables WebSub)")
+	listen := fs.String("websub-addr", "localhost:8081", "Address to serve WebSub callbacks on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *workers < 1 {
+		*workers = 1
+	}
+	var callbackPath string
+	if *callback != "" {
+		u, err := url.Parse(*callback)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid --websub-callback %q", *callback)
+		}
+		callbackPath = strings.TrimSuffix(u.Path, "/")
+	}
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	// The first SIGINT or SIGTERM lets in-flight fetches finish; restoring
+	// the default handling means a second one quits at once
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	d := &daemon{
+		g:            g,
+		fetcher:      newFetcher(),
+		policy:       policy,
+		workers:      *workers,
+		snapshotDays: *snapshotDays,
+		log:          log.New(os.Stderr, "", log.LstdFlags),
+	}
+	if *callback == "" {
+		return d.run(ctx)
+	}
+
+	d.websub = websub.New(g, *callback, d.deliver, websub.WithLogger(d.log))
+	mux := http.NewServeMux()
+	mux.Handle(callbackPath+"/", http.StripPrefix(callbackPath, d.websub.Handler()))
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: mux}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(ln)
+	}()
+	d.log.Printf("Serving WebSub callbacks for %s on %s", *callback, *listen)
+
+	err = d.run(ctx)
+	shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server.Shutdown(shutdown)
+	if serveErr := <-serveErr; err == nil && !errors.Is(serveErr, http.ErrServerClosed) {
+		err = serveErr
+	}
+	return err
+}
+
+// daemon polls subscribed feeds, each on its own schedule, and with
+// WebSub enabled takes content their hubs push in between.
+type daemon struct {
+	g            *graph.Graph
+	fetcher      *fetcher.Fetcher
+	policy       schedule.Policy
+	workers      int
+	snapshotDays int
+	log          *log.Logger
+	websub       *websub.Subscriber // Nil unless WebSub is enabled
+
+	// mu serializes storing polls with storing pushes, which arrive on
+	// the callback server's goroutines
+	mu sync.Mutex
+}
+
+// poll is one feed to fetch and, once fetched, the outcome.
+type poll struct {
+	feed     *graph.FeedNode
+	schedule graph.FeedSchedule
+	fetched  *fetchedFeed
+	err      error
+}
+
+func (d *daemon) run(ctx context.Context) error {
+	d.log.Printf("Daemon started with %d workers, polling every %s to %s", d.workers, d.policy.Min, d.policy.Max)
+	for {
+		due, next, err := d.due(time.Now())
+		if err != nil {
+			return err
+		}
+		if len(due) > 0 {
+			d.pollAll(ctx, due)
+			if ctx.Err() == nil {
+				continue
+			}
+		}
+		if ctx.Err() == nil {
+			d.renew()
+			d.snapshot()
+		}
+
+		wait := min(time.Until(next), idleCheck)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			d.log.Printf("Daemon stopped")
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// due returns the subscribed feeds due for a poll at now, and when the
+// next one not yet due will be. Feeds never polled are due at once.
+func (d *daemon) due(now time.Time) ([]poll, time.Time, error) {
+	feeds, err := d.g.GetSubscribedFeeds()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	schedules, err := d.g.GetSchedules()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	next := now.Add(d.policy.MaxBackoff)
+	var due []poll
+	for _, f := range feeds {
+		s, ok := schedules[f.ID]
+		if !ok {
+			s = graph.FeedSchedule{FeedID: f.ID, Interval: d.policy.Default}
+		}
+		if s.NextFetch.After(now) {
+			if s.NextFetch.Before(next) {
+				next = s.NextFetch
+			}
+			continue
+		}
+		due = append(due, poll{feed: f, schedule: s})
+	}
+	return due, next, nil
+}
+
+// pollAll fetches feeds on a pool of workers and stores the results as they
+// arrive. Once ctx is cancelled no more fetches start, but those in flight
+// are finished and stored.
+func (d *daemon) pollAll(ctx context.Context, due []poll) {
+	d.log.Printf("Polling %d feeds", len(due))
+
+	jobs := make(chan poll)
+	results := make(chan poll)
+	go func() {
+		defer close(jobs)
+		for _, p := range due {
+			select {
+			case jobs <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				p.fetched, p.err = fetchFeed(d.fetcher, p.feed.URL)
+				results <- p
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Store from this goroutine only, keeping writes to the database serial
+	for p := range results {
+		if err := d.store(p); err != nil {
+			d.log.Printf("%s: failed to store: %v", feedLabel(p.feed), err)
+		}
+		d.follow(p)
+	}
+}
+
+// store records when to poll the feed next, then the poll and the feed's
+// posts and links. Scheduling comes first so that a feed whose poll or
+// posts can't be stored isn't polled again straight away.
+func (d *daemon) store(p poll) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	s := p.schedule
+	parsed := p.fetched.feed
+	if p.err != nil {
+		s.Failures++
+		wait := d.policy.Backoff(s.Interval, s.Failures)
+		s.NextFetch = now.Add(wait)
+		d.log.Printf("%s: %v (%d failures, retrying in %s)", feedLabel(p.feed), p.err, s.Failures, wait)
+	} else {
+		hints := schedule.Hints{TTL: parsed.TTL, UpdatePeriod: parsed.UpdatePeriod, MaxAge: p.fetched.maxAge}
+		for _, item := range parsed.Items {
+			hints.Published = append(hints.Published, item.PublishedAt)
+		}
+		s.Interval = d.policy.Interval(hints, now)
+		if d.pushed(p.feed.ID) {
+			// The hub pushes new posts; polling only catches what it misses
+			s.Interval = max(s.Interval, d.policy.Max)
+		}
+		s.Failures = 0
+		s.NextFetch = now.Add(s.Interval)
+	}
+	if err := d.g.SetSchedule(&s); err != nil {
+		return err
+	}
+
+	p.fetched.attempt.FeedID = p.feed.ID
+	if err := d.g.RecordFetch(p.fetched.attempt); err != nil {
+		return err
+	}
+	if p.err != nil {
+		return nil
+	}
+
+	siteURL := p.feed.SiteURL
+	if siteURL == "" {
+		siteURL = p.feed.URL
+	}
+	scanner := &postScanner{g: d.g, feedID: p.feed.ID, siteURL: siteURL, warn: d.log.Writer()}
+	if err := scanner.scanFeed(parsed); err != nil {
+		return err
+	}
+
+	if stats := scanner.stats; stats.unchanged < len(parsed.Items) || stats.removed > 0 {
+		d.log.Printf("%s: %d new or changed posts, %d links, %d mentions, %d retracted; next poll in %s",
+			feedLabel(p.feed), len(parsed.Items)-stats.unchanged, stats.links, stats.mentions, stats.retracted, s.Interval)
+	}
+	return nil
+}
+
+// pushed reports whether a feed's hub has verified our subscription.
+func (d *daemon) pushed(feedID int64) bool {
+	if d.websub == nil {
+		return false
+	}
+	sub, err := d.g.GetWebSub(feedID)
+	return err == nil && sub != nil && sub.State == graph.WebSubActive
+}
+
+// follow subscribes to the hub of a feed that was just polled, if it
+// advertises one. Hubs may verify before answering, so this must not hold
+// mu.
+func (d *daemon) follow(p poll) {
+	if d.websub == nil || p.err != nil {
+		return
+	}
+	topic := p.fetched.feed.Self
+	if topic == "" {
+		topic = p.feed.URL
+	}
+	asked, err := d.websub.Follow(p.feed.ID, p.fetched.feed.Hubs, topic)
+	switch {
+	case err != nil:
+		d.log.Printf("%s: WebSub subscription failed: %v", feedLabel(p.feed), err)
+	case asked:
+		d.log.Printf("%s: subscribing through WebSub hub %s", feedLabel(p.feed), p.fetched.feed.Hubs[0])
+	}
+}
+
+// renew asks hubs again for leases about to run out.
+func (d *daemon) renew() {
+	if d.websub == nil {
+		return
+	}
+	n, err := d.websub.Renew(time.Now())
+	if err != nil {
+		d.log.Printf("Warning: WebSub renewal failed: %v", err)
+	}
+	if n > 0 {
+		d.log.Printf("Renewing %d WebSub subscriptions", n)
+	}
+}
+
+// deliver stores content a hub pushed for a feed. Pushes carry only new and
+// updated entries, so posts missing from them aren't marked removed.
+func (d *daemon) deliver(feedID int64, body []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	node, err := d.g.GetFeedByID(feedID)
+	if err != nil {
+		return err
+	}
+	if node == nil || !node.Subscribed {
+		return websub.ErrGone
+	}
+	parsed, err := feed.ParseFeed(body)
+	if err != nil {
+		return err
+	}
+
+	siteURL := node.SiteURL
+	if siteURL == "" {
+		siteURL = node.URL
+	}
+	scanner := &postScanner{g: d.g, feedID: feedID, siteURL: siteURL, partial: true, warn: d.log.Writer()}
+	if err := scanner.scanFeed(parsed); err != nil {
+		return err
+	}
+	stats := scanner.stats
+	d.log.Printf("%s: pushed %d posts, %d links, %d mentions, %d retracted",
+		feedLabel(node), len(parsed.Items), stats.links, stats.mentions, stats.retracted)
+	return nil
+}
+
+// snapshot takes a mention snapshot if the latest one is at least
+// snapshotDays old.
+func (d *daemon) snapshot() {
+	if d.snapshotDays <= 0 {
+		return
+	}
+	dates, err := d.g.GetSnapshotDates()
+	if err != nil {
+		d.log.Printf("Warning: failed to read snapshots: %v", err)
+		return
+	}
+	today := time.Now().Format("2006-01-02")
+	if len(dates) > 0 {
+		last, err := time.Parse("2006-01-02", dates[0])
+		if err == nil && last.AddDate(0, 0, d.snapshotDays).Format("2006-01-02") > today {
+			return
+		}
+	}
+	n, err := d.g.TakeSnapshot(today)
+	if err != nil {
+		d.log.Printf("Warning: failed to take snapshot: %v", err)
+		return
+	}
+	d.log.Printf("Snapshot saved: %s (%d entries)", today, n)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+	"github.com/daniel-butler/rss-graph/pkg/output"
+)
+
+const entityUsage = `usage: rss-graph entity merge <canonical name> <name>...
+       rss-graph entity alias <canonical name> <alias>...
+       rss-graph entity split <name>...
+       rss-graph entity suggest [--apply]
+       rss-graph entity list`
+
+func cmdEntity(fs *flag.FlagSet, args []string, dbPath *string) error {
+	entityType := fs.String("type", "PERSON", "Entity type (PERSON, ORG)")
+	apply := fs.Bool("apply", false, "Merge every suggestion (with suggest)")
+	if len(args) == 0 {
+		return fmt.Errorf(entityUsage)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
+	}
+	*entityType = strings.ToUpper(*entityType)
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	switch args[0] {
+	case "merge", "alias":
+		if fs.NArg() < 2 {
+			return fmt.Errorf(entityUsage)
+		}
+		merge := g.MergeEntities
+		if args[0] == "alias" {
+			merge = g.AddAliases
+		}
+		e, err := merge(*entityType, fs.Arg(0), fs.Args()[1:])
+		if err != nil {
+			return err
+		}
+		printEntity(e)
+		return nil
+	case "split":
+		if fs.NArg() < 1 {
+			return fmt.Errorf(entityUsage)
+		}
+		for _, name := range fs.Args() {
+			split, err := g.SplitEntity(*entityType, name)
+			if err != nil {
+				return err
+			}
+			if !split {
+				return fmt.Errorf("%q isn't an alias of any entity", name)
+			}
+			fmt.Printf("%s now counts on its own\n", name)
+		}
+		return nil
+	case "suggest":
+		return suggestMerges(g, out, *entityType, *apply)
+	case "list":
+		return listEntities(g, out, *entityType)
+	default:
+		return fmt.Errorf(entityUsage)
+	}
+}
+
+func printEntity(e *graph.Entity) {
+	fmt.Printf("%s [%d mentions]\n", e.Name, e.Mentions)
+	for _, alias := range e.Aliases {
+		if alias != e.Name {
+			fmt.Printf("    aka %s\n", alias)
+		}
+	}
+}
+
+// suggestMerges shows the names that likely refer to the same entity as
+// another, merging them with apply.
+func suggestMerges(g *graph.Graph, out *output.Renderer, entityType string, apply bool) error {
+	suggestions, err := g.SuggestMerges(entityType)
+	if err != nil {
+		return err
+	}
+
+	progress := os.Stdout
+	if !out.Table() {
+		progress = os.Stderr
+		records := make([]output.MergeSuggestion, 0, len(suggestions))
+		for _, s := range suggestions {
+			records = append(records, output.NewMergeSuggestion(s))
+		}
+		if err := out.Render(records); err != nil {
+			return err
+		}
+	} else if len(suggestions) == 0 {
+		fmt.Println("No merges to suggest.")
+	} else {
+		fmt.Println("Names that likely refer to the same entity:")
+		for _, s := range suggestions {
+			fmt.Printf("  %-30s -> %s (%s, %d mentions)\n", s.Name, s.Into, s.Reason, s.Mentions)
+		}
+		if !apply {
+			fmt.Println("\nMerge them all with 'entity suggest --apply', or one at a time with 'entity merge'.")
+		}
+	}
+
+	if !apply || len(suggestions) == 0 {
+		return nil
+	}
+	for _, s := range suggestions {
+		if _, err := g.MergeEntities(entityType, s.Into, []string{s.Name}); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(progress, "Merged %d names\n", len(suggestions))
+	return nil
+}
+
+func listEntities(g *graph.Graph, out *output.Renderer, entityType string) error {
+	entities, err := g.GetEntities(entityType)
+	if err != nil {
+		return err
+	}
+
+	if !out.Table() {
+		records := make([]output.Entity, 0, len(entities))
+		for _, e := range entities {
+			records = append(records, output.NewEntity(e))
+		}
+		return out.Render(records)
+	}
+
+	if len(entities) == 0 {
+		fmt.Println("No entities yet. Merge names with 'entity merge' or 'entity suggest --apply'.")
+		return nil
+	}
+	for i := range entities {
+		printEntity(&entities[i])
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/daniel-butler/rss-graph/pkg/discover"
+	"github.com/daniel-butler/rss-graph/pkg/fetcher"
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+	"github.com/daniel-butler/rss-graph/pkg/output"
+)
+
+func cmdExpand(fs *flag.FlagSet, args []string, dbPath *string) error {
+	depth := fs.Int("depth", 1, "Hops out from our subscriptions to expand")
+	maxFeeds := fs.Int("max-feeds", 100, "Max sites to expand in this run")
+	minLinks := fs.Int("min-links", 1, "Only expand sites linked from at least this many feeds")
+	allow := fs.String("allow", "", "Only expand these domains and their subdomains (comma-separated)")
+	deny := fs.String("deny", "", "Never expand these domains and their subdomains (comma-separated)")
+	retry := fs.Bool("retry", false, "Requeue sites that failed or were skipped before")
+	list := fs.Bool("list", false, "Show the queue instead of expanding")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
+	}
+	progress := os.Stdout
+	if !out.Table() {
+		progress = os.Stderr
+	}
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	if *retry {
+		n, err := g.RequeueFrontier()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(progress, "Requeued %d sites\n", n)
+	}
+	queued, err := g.SeedFrontier()
+	if err != nil {
+		return err
+	}
+	if queued > 0 {
+		fmt.Fprintf(progress, "Queued %d sites linked from our subscriptions\n", queued)
+	}
+
+	if *list {
+		return listFrontier(g, out, *maxFeeds)
+	}
+
+	allowed, denied := splitDomains(*allow), splitDomains(*deny)
+	f := newFetcher()
+	records := []output.ExpandResult{}
+	for len(records) < *maxFeeds {
+		next, err := g.NextFrontier(*depth, float64(*minLinks), 1)
+		if err != nil {
+			return err
+		}
+		if len(next) == 0 {
+			break
+		}
+		entry := next[0]
+		result := expandSite(g, f, entry, allowed, denied)
+		records = append(records, result)
+
+		line := fmt.Sprintf("[%d/%d] %s", len(records), *maxFeeds, entry.URL)
+		switch result.Status {
+		case graph.FrontierDone:
+			line += fmt.Sprintf(": %s, %d links, %d new sites queued", result.FeedURL, result.Links, result.Queued)
+		default:
+			line += fmt.Sprintf(": %s (%s)", result.Status, result.Error)
+		}
+		fmt.Fprintln(progress, line)
+	}
+
+	counts, err := g.CountFrontier()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(progress, "\nExpanded %d sites; %d queued, %d done, %d failed, %d skipped in total\n",
+		len(records), counts[graph.FrontierQueued], counts[graph.FrontierDone],
+		counts[graph.FrontierFailed], counts[graph.FrontierSkipped])
+
+	if !out.Table() {
+		return out.Render(records)
+	}
+	return nil
+}
+
+// expandSite finds a queued site's feed, scans it and queues the sites it
+// links to one hop further out, recording the outcome in the frontier.
+func expandSite(g *graph.Graph, f *fetcher.Fetcher, entry graph.FrontierEntry, allowed, denied []string) output.ExpandResult {
+	result := output.NewExpandResult(entry)
+	finish := func(status, feedURL string, err error) output.ExpandResult {
+		result.Status, result.FeedURL = status, feedURL
+		if err != nil {
+			result.Error = err.Error()
+		}
+		if err := g.SetFrontierStatus(entry.FeedID, status, feedURL, result.Error); err != nil {
+			result.Error = err.Error()
+		}
+		return result
+	}
+
+	// Sites the domain filters deny are skipped too. The common domains
+	// match their subdomains, so blogs hosted on e.g. substack.com need an
+	// allow rule to be expanded
+	switch host := urlHost(entry.URL); {
+	case len(allowed) > 0 && !domainMatches(host, allowed):
+		return finish(graph.FrontierSkipped, "", errors.New("not in --allow"))
+	case domainMatches(host, denied):
+		return finish(graph.FrontierSkipped, "", errors.New("in --deny"))
+	}
+	filtered, err := g.HostFiltered(entry.URL)
+	if err != nil {
+		return finish(graph.FrontierFailed, "", err)
+	}
+	if filtered {
+		return finish(graph.FrontierSkipped, "", errors.New("denied by domain filters"))
+	}
+
+	found, err := discoverSite(f, entry.URL)
+	if err != nil {
+		return finish(graph.FrontierFailed, "", err)
+	}
+	feedURL := found[0].URL
+
+	fetched, err := fetchFeed(f, feedURL)
+	if err != nil {
+		return finish(graph.FrontierFailed, feedURL, err)
+	}
+	feedID, err := g.AddFeed(&graph.FeedNode{URL: feedURL, Title: fetched.feed.Title, SiteURL: entry.URL})
+	if err != nil {
+		return finish(graph.FrontierFailed, feedURL, err)
+	}
+	fetched.attempt.FeedID = feedID
+	if err := g.RecordFetch(fetched.attempt); err != nil {
+		return finish(graph.FrontierFailed, feedURL, err)
+	}
+
+	scanner := &postScanner{g: g, feedID: feedID, siteURL: entry.URL, warn: os.Stderr}
+	if err := scanner.scanFeed(fetched.feed); err != nil {
+		return finish(graph.FrontierFailed, feedURL, err)
+	}
+	result.Links = scanner.stats.links
+	if result.Queued, err = g.EnqueueTargets(feedID, entry.Depth+1); err != nil {
+		return finish(graph.FrontierFailed, feedURL, err)
+	}
+	return finish(graph.FrontierDone, feedURL, nil)
+}
+
+// discoverSite finds the feed for a linked page, falling back to the site's
+// homepage since links usually point at posts rather than the site itself.
+func discoverSite(f *fetcher.Fetcher, pageURL string) ([]discover.Feed, error) {
+	found, err := discover.Discover(f, pageURL)
+	if err == nil {
+		return found, nil
+	}
+	u, perr := url.Parse(pageURL)
+	if perr != nil || u.Path == "" || u.Path == "/" {
+		return nil, err
+	}
+	home := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}).String()
+	return discover.Discover(f, home)
+}
+
+func listFrontier(g *graph.Graph, out *output.Renderer, limit int) error {
+	entries, err := g.GetFrontier(graph.FrontierQueued, limit)
+	if err != nil {
+		return err
+	}
+	if !out.Table() {
+		records := make([]output.ExpandResult, 0, len(entries))
+		for _, e := range entries {
+			records = append(records, output.NewExpandResult(e))
+		}
+		return out.Render(records)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("The frontier is empty.")
+		return nil
+	}
+	fmt.Println("Next sites to expand:")
+	for i, e := range entries {
+		fmt.Printf("%3d. [%.0f feeds, depth %d] %s\n", i+1, e.Priority, e.Depth, e.URL)
+	}
+	return nil
+}
+
+// splitDomains parses a comma-separated list of domains.
+func splitDomains(list string) []string {
+	var domains []string
+	for _, d := range strings.Split(list, ",") {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			domains = append(domains, strings.TrimPrefix(d, "www."))
+		}
+	}
+	return domains
+}
+
+// urlHost returns the lowercased host of a URL without a leading "www.".
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// domainMatches reports whether host is one of domains or a subdomain of
+// one.
+func domainMatches(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+	"github.com/daniel-butler/rss-graph/pkg/output"
+)
+
+const filterUsage = `usage: rss-graph filter add [--allow] [--match exact|suffix|regex] <pattern>
+       rss-graph filter rm <id|pattern>
+       rss-graph filter list`
+
+func cmdFilter(fs *flag.FlagSet, args []string, dbPath *string) error {
+	allow := fs.Bool("allow", false, "Keep matching sites, overriding deny rules")
+	match := fs.String("match", graph.MatchSuffix, "How the pattern matches hosts: exact, suffix or regex")
+	if len(args) == 0 {
+		return fmt.Errorf(filterUsage)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
+	}
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	switch args[0] {
+	case "add":
+		if fs.NArg() != 1 {
+			return fmt.Errorf(filterUsage)
+		}
+		f := &graph.DomainFilter{Action: graph.FilterDeny, Match: *match, Pattern: fs.Arg(0)}
+		if *allow {
+			f.Action = graph.FilterAllow
+		}
+		id, err := g.AddDomainFilter(f)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Added rule %d: %s %s %s\n", id, f.Action, f.Match, f.Pattern)
+		return nil
+	case "rm":
+		if fs.NArg() != 1 {
+			return fmt.Errorf(filterUsage)
+		}
+		return removeFilters(g, fs.Arg(0))
+	case "list":
+		return listFilters(g, out)
+	default:
+		return fmt.Errorf(filterUsage)
+	}
+}
+
+// removeFilters removes the rule with an ID, or every rule with a pattern.
+func removeFilters(g *graph.Graph, ref string) error {
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		removed, err := g.DeleteDomainFilter(id)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return fmt.Errorf("no filter rule %d", id)
+		}
+		fmt.Printf("Removed rule %d\n", id)
+		return nil
+	}
+
+	filters, err := g.GetDomainFilters()
+	if err != nil {
+		return err
+	}
+	host := strings.TrimPrefix(strings.ToLower(ref), "www.")
+	removed := 0
+	for _, f := range filters {
+		if f.Pattern != ref && f.Pattern != host {
+			continue
+		}
+		if _, err := g.DeleteDomainFilter(f.ID); err != nil {
+			return err
+		}
+		fmt.Printf("Removed rule %d: %s %s %s\n", f.ID, f.Action, f.Match, f.Pattern)
+		removed++
+	}
+	if removed > 0 {
+		return nil
+	}
+	for _, f := range g.CommonDomains() {
+		if f.Pattern == host {
+			return fmt.Errorf("%s is a common domain from the config file; remove it there, or keep it with 'filter add --allow %s'", host, host)
+		}
+	}
+	return fmt.Errorf("no filter rule matching %s", ref)
+}
+
+// listFilters shows the rules in the database, then the common domains.
+func listFilters(g *graph.Graph, out *output.Renderer) error {
+	filters, err := g.GetDomainFilters()
+	if err != nil {
+		return err
+	}
+	common := g.CommonDomains()
+
+	if !out.Table() {
+		records := make([]output.DomainFilter, 0, len(filters)+len(common))
+		for _, f := range append(filters, common...) {
+			records = append(records, output.NewDomainFilter(f))
+		}
+		return out.Render(records)
+	}
+
+	if len(filters) == 0 {
+		fmt.Println("No filter rules yet. Add some with 'filter add'.")
+	} else {
+		fmt.Println("Filter rules:")
+		for _, f := range filters {
+			fmt.Printf("%4d  %-5s  %-6s  %s\n", f.ID, f.Action, f.Match, f.Pattern)
+		}
+	}
+	if len(common) > 0 {
+		fmt.Println("\nCommon domains from the config file (deny, suffix):")
+		for _, f := range common {
+			fmt.Printf("      %s\n", f.Pattern)
+		}
+	}
+	return nil
+}
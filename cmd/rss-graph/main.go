@@ -1,26 +1,48 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/daniel-butler/rss-graph/pkg/api"
+	"github.com/daniel-butler/rss-graph/pkg/config"
+	"github.com/daniel-butler/rss-graph/pkg/discover"
+	"github.com/daniel-butler/rss-graph/pkg/export"
 	"github.com/daniel-butler/rss-graph/pkg/extractor"
-	"github.com/daniel-butler/rss-graph/pkg/feed"
 	"github.com/daniel-butler/rss-graph/pkg/fetcher"
 	"github.com/daniel-butler/rss-graph/pkg/graph"
 	"github.com/daniel-butler/rss-graph/pkg/miniflux"
 	"github.com/daniel-butler/rss-graph/pkg/ner"
+	"github.com/daniel-butler/rss-graph/pkg/opml"
+	"github.com/daniel-butler/rss-graph/pkg/output"
+	"github.com/daniel-butler/rss-graph/pkg/source"
+	"github.com/daniel-butler/rss-graph/pkg/web"
 )
 
 var Version = "dev"
 
+// conf holds the settings from the config file, overridden by the
+// environment. Flags default to them, so flags override both.
+var conf = config.Default()
+
+// configFile and configProfile are where conf was read from, if anywhere,
+// and configErr why the config file couldn't be read.
+var (
+	configFile, configProfile string
+	configErr                 error
+)
+
 func main() {
-	if err := run(os.Args[1:]); err != nil {
+	// Subcommand -h prints usage and returns flag.ErrHelp
+	if err := run(os.Args[1:]); err != nil && err != flag.ErrHelp {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -29,8 +51,31 @@ func main() {
 func run(args []string) error {
 	fs := flag.NewFlagSet("rss-graph", flag.ContinueOnError)
 
-	dbPath := fs.String("db", defaultDBPath(), "Path to SQLite database")
-	_ = fs.Bool("version", false, "Show version")
+	fs.Usage = printUsage
+
+	// The config file sets the other flags' defaults, so it is loaded first
+	configPath := firstNonEmpty(flagValue(args, "config"), os.Getenv("RSS_GRAPH_CONFIG"))
+	profile := firstNonEmpty(flagValue(args, "profile"), os.Getenv("RSS_GRAPH_PROFILE"))
+	configErr = loadConfig(configPath, profile)
+	fs.String("config", configPath, "Config file (default: ~/.rss-graph/config.toml)")
+	fs.String("profile", profile, "Config file profile to use")
+
+	dbPath := fs.String("db", conf.DB, "Path to SQLite database")
+	showVersion := fs.Bool("version", false, "Show version")
+	fs.String("o", string(output.Table), "Output format: table, json, jsonl, csv, tsv")
+
+	// Global flags can come before the subcommand as well as after it
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	if *showVersion {
+		fmt.Println(Version)
+		return nil
+	}
+	args = fs.Args()
 
 	// Subcommands
 	if len(args) == 0 {
@@ -40,10 +85,10 @@ func run(args []string) error {
 
 	cmd := args[0]
 
-	// Handle version flag specially
-	if cmd == "-version" || cmd == "--version" {
-		fmt.Println(Version)
-		return nil
+	// Without a readable config file only the commands that help fix it
+	// run, so its mistakes can be diagnosed with config show
+	if configErr != nil && cmd != "help" && cmd != "version" && cmd != "config" {
+		return configErr
 	}
 
 	switch cmd {
@@ -61,12 +106,46 @@ func run(args []string) error {
 		return cmdCrawl(fs, args[1:], dbPath)
 	case "mentions":
 		return cmdMentions(fs, args[1:], dbPath)
+	case "entity":
+		return cmdEntity(fs, args[1:], dbPath)
+	case "people":
+		return cmdPeople(fs, args[1:], dbPath)
 	case "snapshot":
 		return cmdSnapshot(fs, args[1:], dbPath)
+	case "recommend":
+		return cmdRecommend(fs, args[1:], dbPath)
+	case "subscribe":
+		return cmdSubscribe(fs, args[1:], dbPath)
+	case "path":
+		return cmdPath(fs, args[1:], dbPath)
+	case "neighborhood":
+		return cmdNeighborhood(fs, args[1:], dbPath)
+	case "clusters":
+		return cmdClusters(fs, args[1:], dbPath)
+	case "similar":
+		return cmdSimilar(fs, args[1:], dbPath)
+	case "conversations":
+		return cmdConversations(fs, args[1:], dbPath)
+	case "search":
+		return cmdSearch(fs, args[1:], dbPath)
+	case "health":
+		return cmdHealth(fs, args[1:], dbPath)
+	case "daemon":
+		return cmdDaemon(fs, args[1:], dbPath)
+	case "expand":
+		return cmdExpand(fs, args[1:], dbPath)
+	case "export":
+		return cmdExport(fs, args[1:], dbPath)
+	case "serve":
+		return cmdServe(fs, args[1:], dbPath)
+	case "filter":
+		return cmdFilter(fs, args[1:], dbPath)
+	case "config":
+		return cmdConfig(fs, args[1:], dbPath)
 	case "version":
 		fmt.Println(Version)
 		return nil
-	case "help", "-h", "--help":
+	case "help":
 		printUsage()
 		return nil
 	default:
@@ -82,30 +161,201 @@ Commands:
   scan <url>    Fetch feed and extract outbound links
   rank          Show feeds ranked by inbound links
                   --new         Show recently added feeds (last 30 days)
-                  --filter      Filter out common domains
-  links <url>   Show links to/from a feed
-  import        Import feeds from Miniflux
-  crawl         Import and scan all feeds from Miniflux
+                  --filter      Leave out sites the domain filters deny
+                  --category    Only count links from feeds in a category
+                  --star-weight Weight of links from starred posts
+                  --cluster     Only rank feeds in a cluster (see clusters)
+  links <url>   Show links to/from a feed, grouped by site, with posts
+                  --in, --out   Only show inbound or outbound links
+                  --limit       Max sites per direction (default: 20)
+                  --posts       Max posts per site (default: 3)
+                  --sort        Sort sites by count or recent
+                  --category    Only show links to/from feeds in a category
+                  --include-retracted  Also show links edited out of their posts
+  path <a> <b>  Show the shortest chains of links from one site to another
+                  --max         Max paths to show (default: 3)
+                  --undirected  Follow links in either direction
+                  --posts       Max posts to show per hop (default: 2)
+  neighborhood <url>
+                Show the sites within a few hops of a site
+                  --depth       Hops to include (default: 2)
+                  --limit       Max edges to show (default: 50)
+  import        Import feeds from your reader
+                  --source      Reader backend: miniflux, greader
+                  --opml <file> Import feeds from an OPML file instead
+  crawl         Import and scan new entries from your reader
+                  --source      Reader backend: miniflux, greader
+                  --full        Rescan already-processed entries
+                  --since       Only entries published after a date
                   --snapshot    Take a snapshot after crawling
+  daemon        Keep polling subscribed feeds, each as often as it publishes
+                  --workers     Feeds to fetch at once (default: 4)
+                  --min-interval  Shortest time between polls (default: 15m)
+                  --max-interval  Longest time between polls (default: 24h)
+                  --max-backoff Longest wait after failed polls (default: 168h)
+                  --snapshot-days Days between mention snapshots (default: 1)
+                  --websub-callback Public URL for WebSub hubs to push to
+                  --websub-addr Address to serve WebSub callbacks on (default: localhost:8081)
+  expand        Find, scan and follow the feeds of sites our feeds link to
+                  --depth       Hops out from subscriptions (default: 1)
+                  --max-feeds   Max sites to expand this run (default: 100)
+                  --min-links   Only sites linked from this many feeds (default: 1)
+                  --allow, --deny  Comma-separated domains to limit expansion
+                  --retry       Requeue sites that failed or were skipped
+                  --list        Show the queue instead of expanding
   mentions      Show most-mentioned people/orgs
                   --rising      Sort by velocity (growth rate)
+                  --category    Only count mentions from feeds in a category
+  entity        Count the names of one person as one entity in mentions
+                  merge <name> <other>...  Merge names and their entities
+                  alias <name> <alias>...  Add aliases to an entity
+                  split <name>...  Make names count on their own again
+                  suggest       Show likely merges (--apply merges them)
+                  list          Show entities and their aliases
+                  --type        Entity type (default: PERSON)
+  people        Show the sites people likely run, from links with their
+                name, feed authors and homepages
+                  --fetch       Fetch homepages for their author and rel="me" links
+                  --days        Days before fetching a homepage again (default: 30)
   snapshot      Manage velocity snapshots
                   --list        Show available snapshots
                   --prune       Remove old snapshots (>90 days)
+  recommend     Show sites our subscriptions cite that we don't follow
+                  --min         Minimum number of citing subscriptions
+                  --push        Offer to subscribe to each in Miniflux
+  subscribe <site>
+                Find a site's feed and subscribe to it in Miniflux
+                  --category    Miniflux category (default: Discovered)
+                  --dry-run     Show what would be done
+  search <query>
+                Search crawled posts and link text (SQLite FTS5 syntax)
+  similar <url> Show sites cited by and citing the same sites as a site
+                  --metric      cosine or jaccard (default: cosine)
+                  --evidence    Max shared sites to list (default: 3)
+                  --filter      Leave out sites the domain filters deny
+  conversations [url]
+                Show pairs of sites that link to each other; with a site,
+                show the back-and-forth threads between it and each other
+                  --days        Max days between replies in a thread (default: 30)
+                  --max         Max threads per pair with a site (default: 3)
+  clusters      Group sites into communities by how they link
+                  --detect      Recompute clusters from the current graph
+                  --min-size    Smallest cluster to show (default: 3)
+                  --sites       Top sites per cluster (default: 5)
+                  --people      Most-mentioned people per cluster (default: 3)
+  health        Show dead, stale, moved and malformed feeds
+                  --check       Fetch every feed now before reporting
+                  --failures    Failed fetches in a row to count as dead (default: 3)
+                  --stale       Months without a post to count as stale (default: 6)
+                  --update-moved  Follow permanent redirects to new feed URLs
+                  --all         Also list healthy feeds
+  export        Export the graph for Gephi, Cytoscape or Graphviz
+                  --format      graphml, gexf, dot, cytoscape-json
+                  --ego <url>   Only the network around a feed or host
+                  --radius      Hops from --ego (default: 1)
+                  --min-degree  Drop feeds with fewer neighbors
+                  --filter      Leave out sites the domain filters deny
+  filter        Manage the domain filters behind --filter, recommend and expand
+                  add <pattern> Deny a domain and its subdomains
+                    --allow     Keep matching sites, overriding deny rules
+                    --match     exact, suffix or regex (default: suffix)
+                  rm <id|pattern>  Remove a rule
+                  list          Show the rules and the common domains
+  serve         Browse the graph in a web UI, with a JSON API under /api/
+                  --addr        Listen address (default: localhost:8080)
+  config show   Print the effective settings as a config file
+                  --secrets     Show credentials instead of masking them
   version       Show version
   help          Show this help
 
 Options:
   -db <path>    SQLite database path (default: ~/.rss-graph/graph.db)
+  -config <path>  Config file (default: ~/.rss-graph/config.toml)
+  -profile <name> Config file profile to use
+  -o <format>   Output format for rank, links, path, neighborhood, search,
+                similar, conversations, clusters, mentions, snapshot --list,
+                crawl, health, expand, filter list, entity list, entity
+                suggest and people: table (default), json, jsonl, csv, tsv
+
+Settings come from flags, then the environment, then the config file.
 
 Environment:
+  RSS_GRAPH_DB      SQLite database path
+  RSS_GRAPH_CONFIG  Config file
+  RSS_GRAPH_PROFILE Config file profile
+  RSS_GRAPH_SOURCE  Default reader backend (miniflux, greader)
   MINIFLUX_URL      Miniflux server URL
-  MINIFLUX_API_KEY  Miniflux API key`)
+  MINIFLUX_API_KEY  Miniflux API key
+  GREADER_URL       Google Reader API URL (e.g. FreshRSS .../api/greader.php)
+  GREADER_USER      Google Reader API username
+  GREADER_PASSWORD  Google Reader API password`)
+}
+
+// renderer returns a renderer for the global -o flag, writing to stdout.
+func renderer(fs *flag.FlagSet) (*output.Renderer, error) {
+	format, err := output.ParseFormat(fs.Lookup("o").Value.String())
+	if err != nil {
+		return nil, err
+	}
+	return output.New(os.Stdout, format), nil
+}
+
+// flagValue finds a global flag's value in args before they are parsed,
+// for the flags that decide the others' defaults.
+func flagValue(args []string, name string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		flagName, value, hasValue := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=")
+		if flagName != name {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
 }
 
-func defaultDBPath() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".rss-graph", "graph.db")
+// loadConfig reads the config file into conf, then applies the
+// environment and the settings other packages hold. If the file can't be
+// read, conf is left with the defaults and the environment.
+func loadConfig(path, profile string) error {
+	var err error
+	if path == "" {
+		conf, configProfile, err = config.LoadDefault(profile)
+		if _, statErr := os.Stat(config.DefaultPath()); statErr == nil {
+			configFile = config.DefaultPath()
+		}
+	} else {
+		conf, configProfile, err = config.Load(path, profile)
+		configFile = path
+	}
+	if err != nil {
+		conf, configProfile = config.Default(), ""
+	}
+	conf.ApplyEnv(os.Getenv)
+
+	commonDomains = conf.Filter.CommonDomains
+	ner.SetNotPeople(conf.NER.NotPeople)
+	return err
+}
+
+// newFetcher returns a fetcher with the configured timeout, user agent and
+// rate limit.
+func newFetcher() *fetcher.Fetcher {
+	return fetcher.New(
+		fetcher.WithTimeout(conf.Fetcher.Timeout),
+		fetcher.WithUserAgent(conf.Fetcher.UserAgent),
+		fetcher.WithRateLimit(conf.Fetcher.RateLimit),
+	)
 }
 
 func ensureDB(path string) (*graph.Graph, error) {
@@ -113,7 +363,12 @@ func ensureDB(path string) (*graph.Graph, error) {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("creating db directory: %w", err)
 	}
-	return graph.NewGraph(path)
+	g, err := graph.NewGraph(path)
+	if err != nil {
+		return nil, err
+	}
+	g.SetCommonDomains(commonDomains)
+	return g, nil
 }
 
 func cmdAdd(fs *flag.FlagSet, args []string, dbPath *string) error {
@@ -134,8 +389,10 @@ func cmdAdd(fs *flag.FlagSet, args []string, dbPath *string) error {
 	defer g.Close()
 
 	id, err := g.AddFeed(&graph.FeedNode{
-		URL:   feedURL,
-		Title: *title,
+		URL:        feedURL,
+		Title:      *title,
+		Subscribed: true,
+		Source:     "manual",
 	})
 	if err != nil {
 		return err
@@ -161,18 +418,18 @@ func cmdScan(fs *flag.FlagSet, args []string, dbPath *string) error {
 	}
 	defer g.Close()
 
-	// Fetch the feed
-	f := fetcher.New()
-	data, err := f.Fetch(feedURL)
-	if err != nil {
-		return fmt.Errorf("fetching feed: %w", err)
-	}
-
-	// Parse it
-	parsed, err := feed.ParseFeed(data)
+	// Fetch and parse the feed, recording failures for feeds we already know
+	fetched, err := fetchFeed(newFetcher(), feedURL)
 	if err != nil {
-		return fmt.Errorf("parsing feed: %w", err)
+		if existing, _ := g.GetFeedByURL(feedURL); existing != nil {
+			fetched.attempt.FeedID = existing.ID
+			if err := g.RecordFetch(fetched.attempt); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to record fetch: %v\n", err)
+			}
+		}
+		return err
 	}
+	parsed := fetched.feed
 
 	// Add/update source feed
 	sourceID, err := g.AddFeed(&graph.FeedNode{
@@ -182,88 +439,62 @@ func cmdScan(fs *flag.FlagSet, args []string, dbPath *string) error {
 	if err != nil {
 		return err
 	}
+	fetched.attempt.FeedID = sourceID
+	if err := g.RecordFetch(fetched.attempt); err != nil {
+		return err
+	}
 
 	fmt.Printf("Scanning: %s (%d items)\n", parsed.Title, len(parsed.Items))
 
-	// Process each item
-	var totalLinks int
-	for _, item := range parsed.Items {
-		for _, link := range item.ExtractedLinks {
-			// Skip links to same domain (internal links)
-			if isSameDomain(feedURL, link.URL) {
-				continue
-			}
-
-			// Try to identify if this is a blog/feed URL
-			targetURL := normalizeToFeedURL(link.URL)
-
-			// Add target as a potential feed
-			targetID, err := g.AddFeed(&graph.FeedNode{
-				URL:   targetURL,
-				Title: link.Text,
-			})
-			if err != nil {
-				continue
-			}
-
-			// Add the link
-			err = g.AddLink(&graph.LinkEdge{
-				SourceID:  sourceID,
-				TargetID:  targetID,
-				Context:   link.Text,
-				PostURL:   item.URL,
-				PostTitle: item.Title,
-			})
-			if err == nil {
-				totalLinks++
-			}
-		}
+	scanner := &postScanner{g: g, feedID: sourceID, siteURL: feedURL, warn: os.Stdout}
+	if err := scanner.scanFeed(parsed); err != nil {
+		return err
 	}
 
-	fmt.Printf("Found %d outbound links to other sites\n", totalLinks)
+	stats := scanner.stats
+	if stats.unchanged > 0 {
+		fmt.Printf("Skipped %d unchanged items\n", stats.unchanged)
+	}
+	if stats.removed > 0 {
+		fmt.Printf("Marked %d posts no longer in the feed as removed\n", stats.removed)
+	}
+	if stats.retracted > 0 {
+		fmt.Printf("Retracted %d links and mentions edited out of their posts\n", stats.retracted)
+	}
+	fmt.Printf("Found %d outbound links to other sites and %d people mentions\n", stats.links, stats.mentions)
 	return nil
 }
 
-// Common domains to filter out when showing rankings
-var commonDomains = []string{
-	"github.com",
-	"twitter.com",
-	"x.com",
-	"youtube.com",
-	"linkedin.com",
-	"huggingface.co",
-	"news.ycombinator.com",
-	"arxiv.org",
-	"nytimes.com",
-	"openai.com",
-	"anthropic.com",
-	"google.com",
-	"medium.com",
-	"substack.com",
-	"podcasts.apple.com",
-	"scholar.google.com",
-	"en.wikipedia.org",
-	"reddit.com",
-	"facebook.com",
-}
+// commonDomains are the built-in deny rules of the domain filters, from
+// the config file or its defaults.
+var commonDomains []string
 
-func isCommonDomain(feedURL string) bool {
-	for _, domain := range commonDomains {
-		if strings.Contains(feedURL, domain) {
-			return true
-		}
+// filterNetwork drops the feeds the domain filters leave out.
+func filterNetwork(g *graph.Graph, network *graph.Network) (*graph.Network, error) {
+	filtered, err := g.FilteredFeeds()
+	if err != nil {
+		return nil, err
 	}
-	return false
+	return network.Subgraph(func(f *graph.FeedNode) bool {
+		return !filtered[f.ID]
+	}), nil
 }
 
 func cmdRank(fs *flag.FlagSet, args []string, dbPath *string) error {
-	limit := fs.Int("n", 20, "Number of results")
-	filterCommon := fs.Bool("filter", false, "Filter out common domains (github, twitter, etc)")
+	limit := fs.Int("n", conf.Rank.Limit, "Number of results")
+	filterCommon := fs.Bool("filter", conf.Rank.Filter, "Leave out sites the domain filters deny (see filter)")
 	showNew := fs.Bool("new", false, "Show recently added feeds (last 30 days)")
 	newDays := fs.Int("days", 30, "Days to consider 'new' (use with --new)")
+	category := fs.String("category", "", "Only count links from feeds in this category")
+	starWeight := fs.Float64("star-weight", conf.Rank.StarWeight, "Weight of links from starred posts")
+	cluster := fs.Int("cluster", 0, "Only rank feeds in this cluster (see clusters)")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
+	}
 
 	g, err := ensureDB(*dbPath)
 	if err != nil {
@@ -278,6 +509,14 @@ func cmdRank(fs *flag.FlagSet, args []string, dbPath *string) error {
 			return err
 		}
 
+		if !out.Table() {
+			records := make([]output.FeedRank, 0, len(newFeeds))
+			for i, r := range newFeeds {
+				records = append(records, output.NewFeedRank(i+1, r))
+			}
+			return out.Render(records)
+		}
+
 		if len(newFeeds) == 0 {
 			fmt.Printf("No feeds added in the last %d days.\n", *newDays)
 			return nil
@@ -296,53 +535,85 @@ func cmdRank(fs *flag.FlagSet, args []string, dbPath *string) error {
 		return nil
 	}
 
-	// Fetch more results if filtering
-	fetchLimit := *limit
-	if *filterCommon {
-		fetchLimit = *limit * 5
+	shown, err := g.RankFeeds(graph.RankOptions{
+		Limit:      *limit,
+		Category:   *category,
+		Cluster:    *cluster,
+		StarWeight: starWeight,
+		Filter:     *filterCommon,
+	})
+	if err != nil {
+		return err
 	}
-
-	ranked, err := g.GetMostLinked(fetchLimit)
+	hosts := make([]string, 0, len(shown))
+	for _, r := range shown {
+		hosts = append(hosts, urlHost(r.Feed.URL))
+	}
+	owners, err := g.SiteOwners(hosts)
 	if err != nil {
 		return err
 	}
 
-	if len(ranked) == 0 {
+	if !out.Table() {
+		records := make([]output.FeedRank, 0, len(shown))
+		for i, r := range shown {
+			record := output.NewFeedRank(i+1, r)
+			record.RunBy = owners[urlHost(r.Feed.URL)].Name
+			records = append(records, record)
+		}
+		return out.Render(records)
+	}
+
+	if len(shown) == 0 {
 		fmt.Println("No feeds with inbound links yet.")
 		return nil
 	}
 
-	fmt.Println("Feeds ranked by inbound links:")
-	shown := 0
-	for _, r := range ranked {
-		if shown >= *limit {
-			break
-		}
-
-		// Skip common domains if filtering
-		if *filterCommon && isCommonDomain(r.Feed.URL) {
-			continue
-		}
-
+	if *cluster != 0 {
+		fmt.Printf("Feeds in cluster %d ranked by inbound links:\n", *cluster)
+	} else {
+		fmt.Println("Feeds ranked by inbound links:")
+	}
+	for i, r := range shown {
 		title := r.Feed.Title
 		if title == "" {
 			title = "(untitled)"
 		}
-		shown++
-		fmt.Printf("%2d. [%d links] %s\n    %s\n", shown, r.InboundCount, title, r.Feed.URL)
+		if owner, ok := owners[urlHost(r.Feed.URL)]; ok {
+			title += " (run by " + owner.Name + ")"
+		}
+		if *starWeight != 1 {
+			fmt.Printf("%2d. [%.1f score, %d links] %s\n    %s\n", i+1, r.Score, r.InboundCount, title, r.Feed.URL)
+			continue
+		}
+		fmt.Printf("%2d. [%d links] %s\n    %s\n", i+1, r.InboundCount, title, r.Feed.URL)
 	}
 	return nil
 }
 
 func cmdLinks(fs *flag.FlagSet, args []string, dbPath *string) error {
+	category := fs.String("category", "", "Only show links to/from feeds in this category")
+	inOnly := fs.Bool("in", false, "Only show inbound links")
+	outOnly := fs.Bool("out", false, "Only show outbound links")
+	limit := fs.Int("limit", 20, "Max sites to show per direction (0 for all)")
+	posts := fs.Int("posts", 3, "Max posts to show per site (0 for all)")
+	sortBy := fs.String("sort", "count", "Sort sites by: count, recent")
+	includeRetracted := fs.Bool("include-retracted", false, "Also show links later edited out of their posts")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
 	if fs.NArg() < 1 {
-		return fmt.Errorf("usage: rss-graph links <url>")
+		return fmt.Errorf("usage: rss-graph links <url or host>")
+	}
+	ref := fs.Arg(0)
+	if *sortBy != "count" && *sortBy != "recent" {
+		return fmt.Errorf("unknown sort: %s (want count or recent)", *sortBy)
+	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
 	}
-	feedURL := fs.Arg(0)
 
 	g, err := ensureDB(*dbPath)
 	if err != nil {
@@ -350,21 +621,222 @@ func cmdLinks(fs *flag.FlagSet, args []string, dbPath *string) error {
 	}
 	defer g.Close()
 
-	feedNode, err := g.GetFeedByURL(feedURL)
+	feeds, err := g.FindFeeds(ref)
 	if err != nil {
 		return err
 	}
-	if feedNode == nil {
-		return fmt.Errorf("feed not found: %s", feedURL)
+	if len(feeds) == 0 {
+		return fmt.Errorf("feed not found: %s", ref)
+	}
+
+	// A host can match several nodes, e.g. the feed we subscribe to and the
+	// site root other feeds link to, so gather edges across all of them
+	getInbound, getOutbound := g.GetInboundLinks, g.GetOutboundLinks
+	if *includeRetracted {
+		getInbound, getOutbound = g.GetInboundLinkHistory, g.GetOutboundLinkHistory
+	}
+	var inbound, outbound []graph.LinkEdge
+	for _, f := range feeds {
+		in, err := getInbound(f.ID)
+		if err != nil {
+			return err
+		}
+		out, err := getOutbound(f.ID)
+		if err != nil {
+			return err
+		}
+		inbound = append(inbound, in...)
+		outbound = append(outbound, out...)
+	}
+
+	if *category != "" {
+		inCategory, err := g.GetFeedIDsByTag(*category)
+		if err != nil {
+			return err
+		}
+		var in, out []graph.LinkEdge
+		for _, l := range inbound {
+			if inCategory[l.SourceID] {
+				in = append(in, l)
+			}
+		}
+		for _, l := range outbound {
+			if inCategory[l.TargetID] {
+				out = append(out, l)
+			}
+		}
+		inbound, outbound = in, out
+	}
+
+	showIn, showOut := !*outOnly || *inOnly, !*inOnly || *outOnly
+
+	if !out.Table() {
+		records := []output.Link{}
+		if showIn {
+			groups := groupLinks(inbound, func(l graph.LinkEdge) int64 { return l.SourceID }, *sortBy)
+			if records, err = appendLinkRecords(g, records, "in", groups, *limit, *posts); err != nil {
+				return err
+			}
+		}
+		if showOut {
+			groups := groupLinks(outbound, func(l graph.LinkEdge) int64 { return l.TargetID }, *sortBy)
+			if records, err = appendLinkRecords(g, records, "out", groups, *limit, *posts); err != nil {
+				return err
+			}
+		}
+		return out.Render(records)
+	}
+
+	for _, f := range feeds {
+		title := f.Title
+		if title == "" {
+			title = "(untitled)"
+		}
+		fmt.Printf("Feed: %s\n      %s\n", title, f.URL)
+	}
+
+	if showIn {
+		groups := groupLinks(inbound, func(l graph.LinkEdge) int64 { return l.SourceID }, *sortBy)
+		fmt.Printf("\nInbound: %d links from %d sites\n", len(inbound), len(groups))
+		if err := printLinkGroups(g, groups, *limit, *posts); err != nil {
+			return err
+		}
+	}
+	if showOut {
+		groups := groupLinks(outbound, func(l graph.LinkEdge) int64 { return l.TargetID }, *sortBy)
+		fmt.Printf("\nOutbound: %d links to %d sites\n", len(outbound), len(groups))
+		if err := printLinkGroups(g, groups, *limit, *posts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkGroup is the set of links between a feed and one other site.
+type linkGroup struct {
+	feedID int64
+	links  []graph.LinkEdge // Newest first
+	latest time.Time
+}
+
+// linkDate is when a link's post was published, or when we found it if unknown.
+func linkDate(l graph.LinkEdge) time.Time {
+	if !l.PublishedAt.IsZero() {
+		return l.PublishedAt
+	}
+	return l.DiscoveredAt
+}
+
+// groupLinks groups links by the feed on the other end, sorted by link count
+// or by the most recent link.
+func groupLinks(links []graph.LinkEdge, other func(graph.LinkEdge) int64, sortBy string) []*linkGroup {
+	byFeed := make(map[int64]*linkGroup)
+	var groups []*linkGroup
+	for _, l := range links {
+		id := other(l)
+		group, ok := byFeed[id]
+		if !ok {
+			group = &linkGroup{feedID: id}
+			byFeed[id] = group
+			groups = append(groups, group)
+		}
+		group.links = append(group.links, l)
+		if d := linkDate(l); d.After(group.latest) {
+			group.latest = d
+		}
+	}
+
+	for _, group := range groups {
+		sort.SliceStable(group.links, func(i, j int) bool {
+			return linkDate(group.links[i]).After(linkDate(group.links[j]))
+		})
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if sortBy == "recent" {
+			return groups[i].latest.After(groups[j].latest)
+		}
+		return len(groups[i].links) > len(groups[j].links)
+	})
+	return groups
+}
+
+// appendLinkRecords flattens link groups into output records, applying the
+// same site and post limits as the text output.
+func appendLinkRecords(g *graph.Graph, records []output.Link, direction string, groups []*linkGroup, limit, posts int) ([]output.Link, error) {
+	feeds := make(map[int64]*graph.FeedNode)
+	lookup := func(id int64) (*graph.FeedNode, error) {
+		if f, ok := feeds[id]; ok {
+			return f, nil
+		}
+		f, err := g.GetFeedByID(id)
+		feeds[id] = f
+		return f, err
 	}
 
-	inbound, _ := g.GetInboundLinks(feedNode.ID)
-	outbound, _ := g.GetOutboundLinks(feedNode.ID)
+	for i, group := range groups {
+		if limit > 0 && i >= limit {
+			break
+		}
+		for j, l := range group.links {
+			if posts > 0 && j >= posts {
+				break
+			}
+			source, err := lookup(l.SourceID)
+			if err != nil {
+				return nil, err
+			}
+			target, err := lookup(l.TargetID)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, output.NewLink(direction, l, source, target))
+		}
+	}
+	return records, nil
+}
 
-	fmt.Printf("Feed: %s\n", feedURL)
-	fmt.Printf("Inbound links: %d\n", len(inbound))
-	fmt.Printf("Outbound links: %d\n", len(outbound))
+func printLinkGroups(g *graph.Graph, groups []*linkGroup, limit, posts int) error {
+	shown := 0
+	for i, group := range groups {
+		if limit > 0 && shown >= limit {
+			fmt.Printf("    ... and %d more sites\n", len(groups)-i)
+			break
+		}
+		feed, err := g.GetFeedByID(group.feedID)
+		if err != nil {
+			return err
+		}
+		if feed == nil {
+			continue
+		}
+		title := feed.Title
+		if title == "" {
+			title = feed.URL
+		}
+		shown++
+		fmt.Printf("%2d. [%d links] %s\n    %s\n", shown, len(group.links), title, feed.URL)
 
+		for j, l := range group.links {
+			if posts > 0 && j >= posts {
+				fmt.Printf("      ... and %d more posts\n", len(group.links)-posts)
+				break
+			}
+			postTitle := l.PostTitle
+			if postTitle == "" {
+				postTitle = "(untitled post)"
+			}
+			if !l.RetractedAt.IsZero() {
+				postTitle += fmt.Sprintf(" (retracted %s)", l.RetractedAt.Format("2006-01-02"))
+			}
+			fmt.Printf("      %s  %s\n", linkDate(l).Format("2006-01-02"), postTitle)
+			if l.PostURL != "" {
+				fmt.Printf("                  %s\n", l.PostURL)
+			}
+			if l.Context != "" {
+				fmt.Printf("                  %q\n", l.Context)
+			}
+		}
+	}
 	return nil
 }
 
@@ -402,14 +874,19 @@ func normalizeToFeedURL(rawURL string) string {
 var _ = extractor.Link{}
 
 func cmdImport(fs *flag.FlagSet, args []string, dbPath *string) error {
-	minifluxURL := fs.String("url", os.Getenv("MINIFLUX_URL"), "Miniflux server URL")
-	apiKey := fs.String("api-key", os.Getenv("MINIFLUX_API_KEY"), "Miniflux API key")
+	sf := addSourceFlags(fs)
+	opmlPath := fs.String("opml", "", "Import feeds from an OPML file instead of a reader")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
-	if *minifluxURL == "" || *apiKey == "" {
-		return fmt.Errorf("MINIFLUX_URL and MINIFLUX_API_KEY required (env or flags)")
+	if *opmlPath != "" {
+		return importOPML(*opmlPath, *dbPath)
+	}
+
+	src, err := sf.open()
+	if err != nil {
+		return err
 	}
 
 	g, err := ensureDB(*dbPath)
@@ -418,19 +895,14 @@ func cmdImport(fs *flag.FlagSet, args []string, dbPath *string) error {
 	}
 	defer g.Close()
 
-	client := miniflux.NewClient(*minifluxURL, *apiKey)
-	feeds, err := client.GetFeeds()
+	feeds, err := src.ListFeeds()
 	if err != nil {
-		return fmt.Errorf("fetching feeds from Miniflux: %w", err)
+		return fmt.Errorf("fetching feeds from %s: %w", src.Name(), err)
 	}
 
-	fmt.Printf("Importing %d feeds from Miniflux...\n", len(feeds))
+	fmt.Printf("Importing %d feeds from %s...\n", len(feeds), src.Name())
 	for _, f := range feeds {
-		_, err := g.AddFeed(&graph.FeedNode{
-			URL:   f.FeedURL,
-			Title: f.Title,
-		})
-		if err != nil {
+		if _, err := addSubscription(g, src, f); err != nil {
 			fmt.Printf("  Warning: failed to add %s: %v\n", f.FeedURL, err)
 			continue
 		}
@@ -441,17 +913,80 @@ func cmdImport(fs *flag.FlagSet, args []string, dbPath *string) error {
 	return nil
 }
 
+func importOPML(path, dbPath string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	subs, err := opml.Parse(data)
+	if err != nil {
+		return fmt.Errorf("parsing OPML: %w", err)
+	}
+
+	g, err := ensureDB(dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	fmt.Printf("Importing %d feeds from %s...\n", len(subs), path)
+	imported := 0
+	for _, sub := range subs {
+		id, err := g.AddFeed(&graph.FeedNode{
+			URL:        sub.FeedURL,
+			Title:      sub.Title,
+			SiteURL:    sub.SiteURL,
+			Subscribed: true,
+			Source:     "opml",
+		})
+		if err != nil {
+			fmt.Printf("  Warning: failed to add %s: %v\n", sub.FeedURL, err)
+			continue
+		}
+		if sub.Category != "" {
+			if err := g.AddFeedTag(id, sub.Category); err != nil {
+				return err
+			}
+		}
+		imported++
+		fmt.Printf("  + %s\n", sub.Title)
+	}
+
+	fmt.Printf("Imported %d feeds.\n", imported)
+	return nil
+}
+
 func cmdCrawl(fs *flag.FlagSet, args []string, dbPath *string) error {
-	minifluxURL := fs.String("url", os.Getenv("MINIFLUX_URL"), "Miniflux server URL")
-	apiKey := fs.String("api-key", os.Getenv("MINIFLUX_API_KEY"), "Miniflux API key")
-	entriesPerFeed := fs.Int("entries", 50, "Entries to scan per feed")
+	sf := addSourceFlags(fs)
+	entriesPerFeed := fs.Int("entries", conf.Crawl.Entries, "Entries to scan per feed on a first or full crawl (0 for all)")
+	full := fs.Bool("full", false, "Rescan entries already processed by earlier crawls")
+	since := fs.String("since", "", "Only scan entries published after this date (YYYY-MM-DD)")
 	takeSnapshot := fs.Bool("snapshot", false, "Take a snapshot after crawling (for velocity tracking)")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
+	}
+	// Progress goes to stderr when stdout is machine-readable
+	progress := os.Stdout
+	if !out.Table() {
+		progress = os.Stderr
+	}
 
-	if *minifluxURL == "" || *apiKey == "" {
-		return fmt.Errorf("MINIFLUX_URL and MINIFLUX_API_KEY required (env or flags)")
+	src, err := sf.open()
+	if err != nil {
+		return err
+	}
+
+	var publishedAfter time.Time
+	if *since != "" {
+		t, err := time.Parse("2006-01-02", *since)
+		if err != nil {
+			return fmt.Errorf("invalid --since date: %w", err)
+		}
+		publishedAfter = t
 	}
 
 	g, err := ensureDB(*dbPath)
@@ -460,96 +995,120 @@ func cmdCrawl(fs *flag.FlagSet, args []string, dbPath *string) error {
 	}
 	defer g.Close()
 
-	client := miniflux.NewClient(*minifluxURL, *apiKey)
-	feeds, err := client.GetFeeds()
+	feeds, err := src.ListFeeds()
 	if err != nil {
-		return fmt.Errorf("fetching feeds from Miniflux: %w", err)
+		return fmt.Errorf("fetching feeds from %s: %w", src.Name(), err)
 	}
 
-	fmt.Printf("Crawling %d feeds from Miniflux...\n\n", len(feeds))
+	fmt.Fprintf(progress, "Crawling %d feeds from %s...\n\n", len(feeds), src.Name())
 
-	var totalLinks, totalMentions int
-	for _, mf := range feeds {
+	var totalEntries, totalUnchanged, totalLinks, totalMentions int
+	records := []output.CrawlResult{}
+	for _, sub := range feeds {
 		// Add source feed
-		sourceID, err := g.AddFeed(&graph.FeedNode{
-			URL:   mf.FeedURL,
-			Title: mf.Title,
-		})
+		sourceID, err := addSubscription(g, src, sub)
 		if err != nil {
+			records = append(records, output.CrawlResult{URL: sub.FeedURL, Title: sub.Title, Error: err.Error()})
 			continue
 		}
 
-		// Get entries from Miniflux (already fetched, no need to re-fetch)
-		entries, err := client.GetEntries(mf.ID, *entriesPerFeed)
+		state, err := g.GetCrawlState(sourceID)
 		if err != nil {
-			fmt.Printf("  Warning: failed to get entries for %s: %v\n", mf.Title, err)
-			continue
+			return err
 		}
 
-		feedLinks := 0
-		feedMentions := 0
-		for _, entry := range entries {
-			// Extract links from entry content
-			links := extractor.ExtractLinks(entry.Content)
-			for _, link := range links {
-				if isSameDomain(mf.SiteURL, link.URL) {
-					continue
-				}
-
-				targetURL := normalizeToFeedURL(link.URL)
-				targetID, err := g.AddFeed(&graph.FeedNode{
-					URL:   targetURL,
-					Title: link.Text,
-				})
-				if err != nil {
-					continue
-				}
+		// On a first or full crawl take the newest entries; afterwards
+		// everything newer than the high-water mark.
+		cursor := source.Cursor{PublishedAfter: publishedAfter}
+		maxEntries := *entriesPerFeed
+		if state == nil {
+			state = &graph.CrawlState{FeedID: sourceID}
+		} else if !*full {
+			cursor.AfterEntryID = state.LastEntryID
+			maxEntries = 0
+		}
 
-				err = g.AddLink(&graph.LinkEdge{
-					SourceID:  sourceID,
-					TargetID:  targetID,
-					Context:   link.Text,
-					PostURL:   entry.URL,
-					PostTitle: entry.Title,
-				})
-				if err == nil {
-					feedLinks++
-				}
+		// Get entries from the reader (already fetched, no need to re-fetch)
+		it := src.ListEntries(sub.ID, cursor)
+		scanner := &postScanner{g: g, feedID: sourceID, siteURL: sub.SiteURL, full: *full, warn: progress}
+		entries := 0
+		for (maxEntries == 0 || entries < maxEntries) && it.Next() {
+			entry := it.Entry()
+			entries++
+			if entry.ID > state.LastEntryID {
+				state.LastEntryID = entry.ID
+			}
+			if entry.PublishedAt.After(state.LastPublishedAt) {
+				state.LastPublishedAt = entry.PublishedAt
 			}
 
-			// Extract people mentions using NER
-			people := ner.ExtractPeople(entry.Content)
-			for _, name := range people {
-				err := g.AddMention(&graph.Mention{
-					SourceID:   sourceID,
-					Name:       name,
-					EntityType: "PERSON",
-					PostURL:    entry.URL,
-					PostTitle:  entry.Title,
-				})
-				if err == nil {
-					feedMentions++
-				}
+			// Entries we've already processed and that haven't changed
+			// are skipped, unless asked to rescan everything
+			post := &graph.Post{
+				FeedID:      sourceID,
+				URL:         entry.URL,
+				GUID:        entry.GUID,
+				Title:       entry.Title,
+				Author:      entry.Author,
+				PublishedAt: entry.PublishedAt,
+				UpdatedAt:   entry.UpdatedAt,
+			}
+			if err := scanner.scan(post, entry.Content, extractor.ExtractLinks(entry.Content), entry.Starred); err != nil {
+				return err
 			}
 		}
-		totalLinks += feedLinks
-		totalMentions += feedMentions
-		fmt.Printf("  %s: %d entries, %d links, %d mentions\n", mf.Title, len(entries), feedLinks, feedMentions)
-	}
+		if err := it.Err(); err != nil {
+			fmt.Fprintf(progress, "  Warning: failed to get entries for %s: %v\n", sub.Title, err)
+			records = append(records, output.CrawlResult{FeedID: sourceID, URL: sub.FeedURL, Title: sub.Title, Error: err.Error()})
+			continue
+		}
 
-	fmt.Printf("\nTotal: %d feeds crawled, %d outbound links, %d people mentions\n", len(feeds), totalLinks, totalMentions)
+		if err := g.SetCrawlState(state); err != nil {
+			return err
+		}
 
-	// Take snapshot if requested
-	if *takeSnapshot {
-		today := time.Now().Format("2006-01-02")
+		stats := scanner.stats
+		totalEntries += entries
+		totalUnchanged += stats.unchanged
+		totalLinks += stats.links
+		totalMentions += stats.mentions
+		summary := fmt.Sprintf("%d new entries", entries)
+		if stats.unchanged > 0 {
+			summary += fmt.Sprintf(" (%d unchanged)", stats.unchanged)
+		}
+		summary += fmt.Sprintf(", %d links, %d mentions", stats.links, stats.mentions)
+		if stats.retracted > 0 {
+			summary += fmt.Sprintf(", %d retracted", stats.retracted)
+		}
+		fmt.Fprintf(progress, "  %s: %s\n", sub.Title, summary)
+		records = append(records, output.CrawlResult{
+			FeedID:    sourceID,
+			URL:       sub.FeedURL,
+			Title:     sub.Title,
+			Entries:   entries,
+			Unchanged: stats.unchanged,
+			Links:     stats.links,
+			Mentions:  stats.mentions,
+			Retracted: stats.retracted,
+		})
+	}
+
+	fmt.Fprintf(progress, "\nTotal: %d feeds crawled, %d entries (%d unchanged), %d outbound links, %d people mentions\n", len(feeds), totalEntries, totalUnchanged, totalLinks, totalMentions)
+
+	// Take snapshot if requested
+	if *takeSnapshot {
+		today := time.Now().Format("2006-01-02")
 		n, err := g.TakeSnapshot(today)
 		if err != nil {
-			fmt.Printf("Warning: failed to take snapshot: %v\n", err)
+			fmt.Fprintf(progress, "Warning: failed to take snapshot: %v\n", err)
 		} else {
-			fmt.Printf("Snapshot saved: %s (%d entries)\n", today, n)
+			fmt.Fprintf(progress, "Snapshot saved: %s (%d entries)\n", today, n)
 		}
 	}
 
+	if !out.Table() {
+		return out.Render(records)
+	}
 	return nil
 }
 
@@ -557,10 +1116,19 @@ func cmdMentions(fs *flag.FlagSet, args []string, dbPath *string) error {
 	limit := fs.Int("n", 30, "Number of results")
 	entityType := fs.String("type", "PERSON", "Entity type (PERSON, ORG)")
 	rising := fs.Bool("rising", false, "Sort by velocity (growth rate)")
+	category := fs.String("category", "", "Only count mentions from feeds in this category")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
+	if *rising && *category != "" {
+		return fmt.Errorf("--category can't be combined with --rising (snapshots cover all feeds)")
+	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
+	}
+
 	g, err := ensureDB(*dbPath)
 	if err != nil {
 		return err
@@ -575,9 +1143,14 @@ func cmdMentions(fs *flag.FlagSet, args []string, dbPath *string) error {
 		}
 		
 		if len(dates) < 2 {
-			fmt.Println("Need at least 2 snapshots for velocity calculation.")
-			fmt.Println("Run 'rss-graph snapshot' after each crawl to build history.")
-			fmt.Println("\nFalling back to standard ranking...")
+			// Keep machine-readable stdout clean
+			w := os.Stdout
+			if !out.Table() {
+				w = os.Stderr
+			}
+			fmt.Fprintln(w, "Need at least 2 snapshots for velocity calculation.")
+			fmt.Fprintln(w, "Run 'rss-graph snapshot' after each crawl to build history.")
+			fmt.Fprintln(w, "\nFalling back to standard ranking...")
 			*rising = false
 		} else {
 			currentDate := dates[0]
@@ -588,6 +1161,14 @@ func cmdMentions(fs *flag.FlagSet, args []string, dbPath *string) error {
 				return err
 			}
 
+			if !out.Table() {
+				records := make([]output.RisingMention, 0, len(risingMentions))
+				for i, m := range risingMentions {
+					records = append(records, output.NewRisingMention(i+1, m, currentDate, previousDate))
+				}
+				return out.Render(records)
+			}
+
 			if len(risingMentions) == 0 {
 				fmt.Println("No rising mentions found.")
 				return nil
@@ -639,11 +1220,36 @@ func cmdMentions(fs *flag.FlagSet, args []string, dbPath *string) error {
 	}
 
 	// Standard ranking
-	mentions, err := g.GetMostMentioned(*entityType, *limit)
+	mentions, err := g.RankMentions(graph.MentionOptions{
+		EntityType: *entityType,
+		Category:   *category,
+		Limit:      *limit,
+	})
 	if err != nil {
 		return err
 	}
 
+	var sites map[string]graph.PersonSite
+	if *entityType == "PERSON" {
+		names := make([]string, 0, len(mentions))
+		for _, m := range mentions {
+			names = append(names, m.Name)
+		}
+		if sites, err = g.PeopleSites(names); err != nil {
+			return err
+		}
+	}
+
+	if !out.Table() {
+		records := make([]output.MentionRank, 0, len(mentions))
+		for i, m := range mentions {
+			record := output.NewMentionRank(i+1, m)
+			record.Site = sites[m.Name].Host
+			records = append(records, record)
+		}
+		return out.Render(records)
+	}
+
 	if len(mentions) == 0 {
 		fmt.Println("No mentions found. Run 'crawl' first to extract mentions.")
 		return nil
@@ -651,6 +1257,10 @@ func cmdMentions(fs *flag.FlagSet, args []string, dbPath *string) error {
 
 	fmt.Printf("Most mentioned %ss:\n", strings.ToLower(*entityType))
 	for i, m := range mentions {
+		if site, ok := sites[m.Name]; ok {
+			fmt.Printf("%2d. [%d mentions] %s (%s)\n", i+1, m.MentionCount, m.Name, site.Host)
+			continue
+		}
 		fmt.Printf("%2d. [%d mentions] %s\n", i+1, m.MentionCount, m.Name)
 	}
 	return nil
@@ -662,6 +1272,10 @@ func cmdSnapshot(fs *flag.FlagSet, args []string, dbPath *string) error {
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
+	}
 
 	g, err := ensureDB(*dbPath)
 	if err != nil {
@@ -674,6 +1288,13 @@ func cmdSnapshot(fs *flag.FlagSet, args []string, dbPath *string) error {
 		if err != nil {
 			return err
 		}
+		if !out.Table() {
+			records := make([]output.Snapshot, 0, len(dates))
+			for _, d := range dates {
+				records = append(records, output.Snapshot{Date: d})
+			}
+			return out.Render(records)
+		}
 		if len(dates) == 0 {
 			fmt.Println("No snapshots yet. Run 'rss-graph snapshot' to create one.")
 			return nil
@@ -705,3 +1326,1082 @@ func cmdSnapshot(fs *flag.FlagSet, args []string, dbPath *string) error {
 	fmt.Printf("Snapshot saved: %s (%d entries)\n", today, n)
 	return nil
 }
+
+func cmdRecommend(fs *flag.FlagSet, args []string, dbPath *string) error {
+	limit := fs.Int("n", 20, "Number of results")
+	minCiting := fs.Int("min", 1, "Minimum number of subscriptions citing a site")
+	push := fs.Bool("push", false, "Offer to subscribe to each recommendation in Miniflux")
+	category := fs.String("category", "Discovered", "Miniflux category for new subscriptions (with --push)")
+	minifluxURL := fs.String("url", conf.Miniflux.URL, "Miniflux server URL")
+	apiKey := fs.String("api-key", conf.Miniflux.APIKey, "Miniflux API key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *push && (*minifluxURL == "" || *apiKey == "") {
+		return fmt.Errorf("MINIFLUX_URL and MINIFLUX_API_KEY required (flags, env or config file)")
+	}
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	all, err := g.GetRecommendations(true)
+	if err != nil {
+		return err
+	}
+
+	var recs []graph.Recommendation
+	for _, r := range all {
+		if len(recs) >= *limit {
+			break
+		}
+		if r.CitingFeeds < *minCiting {
+			continue
+		}
+		recs = append(recs, r)
+	}
+
+	if len(recs) == 0 {
+		fmt.Println("No recommendations yet. Import subscriptions and run 'crawl' first.")
+		return nil
+	}
+
+	fmt.Println("Sites your subscriptions cite that you don't follow:")
+	for i, r := range recs {
+		title := r.Feed.Title
+		if title == "" {
+			title = "(untitled)"
+		}
+		fmt.Printf("%2d. [%d feeds, %d links] %s\n    %s\n", i+1, r.CitingFeeds, r.LinkCount, title, r.Feed.URL)
+	}
+
+	if !*push {
+		return nil
+	}
+
+	// The category is only created once a subscription needs it, so
+	// declining every recommendation leaves Miniflux as it was
+	client := miniflux.NewClient(*minifluxURL, *apiKey)
+	categoryID, err := findCategory(client, *category, false)
+	if err != nil {
+		return err
+	}
+
+	in := bufio.NewScanner(os.Stdin)
+	fmt.Println()
+	for _, r := range recs {
+		fmt.Printf("Subscribe to %s? [y/N] ", r.Feed.URL)
+		if !in.Scan() {
+			break
+		}
+		if answer := strings.ToLower(strings.TrimSpace(in.Text())); answer != "y" && answer != "yes" {
+			continue
+		}
+
+		if categoryID == 0 {
+			if categoryID, err = findCategory(client, *category, true); err != nil {
+				return err
+			}
+		}
+		feedURL, err := subscribeSite(client, g, r.Feed.URL, categoryID, false)
+		if err != nil {
+			fmt.Printf("  Warning: %v\n", err)
+			continue
+		}
+		fmt.Printf("  + %s\n", feedURL)
+	}
+	return nil
+}
+
+func cmdSubscribe(fs *flag.FlagSet, args []string, dbPath *string) error {
+	category := fs.String("category", "Discovered", "Miniflux category (created if missing)")
+	dryRun := fs.Bool("dry-run", false, "Resolve the feed but don't subscribe")
+	minifluxURL := fs.String("url", conf.Miniflux.URL, "Miniflux server URL")
+	apiKey := fs.String("api-key", conf.Miniflux.APIKey, "Miniflux API key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: rss-graph subscribe <site>")
+	}
+	siteURL := fs.Arg(0)
+
+	if *minifluxURL == "" || *apiKey == "" {
+		return fmt.Errorf("MINIFLUX_URL and MINIFLUX_API_KEY required (flags, env or config file)")
+	}
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	client := miniflux.NewClient(*minifluxURL, *apiKey)
+	categoryID, err := findCategory(client, *category, !*dryRun)
+	if err != nil {
+		return err
+	}
+	if categoryID == 0 {
+		fmt.Printf("Would create category %q\n", *category)
+	}
+
+	feedURL, err := subscribeSite(client, g, siteURL, categoryID, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		fmt.Printf("Would subscribe to %s in %q\n", feedURL, *category)
+		return nil
+	}
+	fmt.Printf("Subscribed to %s in %q\n", feedURL, *category)
+	return nil
+}
+
+// findCategory returns the ID of the Miniflux category with the given title,
+// creating it if allowed. It returns 0 if the category is missing and create is false.
+func findCategory(client *miniflux.Client, title string, create bool) (int64, error) {
+	categories, err := client.GetCategories()
+	if err != nil {
+		return 0, fmt.Errorf("fetching categories from Miniflux: %w", err)
+	}
+	for _, c := range categories {
+		if strings.EqualFold(c.Title, title) {
+			return c.ID, nil
+		}
+	}
+
+	if !create {
+		return 0, nil
+	}
+	category, err := client.CreateCategory(title)
+	if err != nil {
+		return 0, fmt.Errorf("creating category %q: %w", title, err)
+	}
+	return category.ID, nil
+}
+
+// subscribeSite resolves a site's feed via autodiscovery, subscribes to it in
+// Miniflux and records the subscription in the graph. It returns the feed URL.
+func subscribeSite(client *miniflux.Client, g *graph.Graph, siteURL string, categoryID int64, dryRun bool) (string, error) {
+	var feedURL, title string
+	found, err := discover.Discover(newFetcher(), siteURL)
+	if err == nil {
+		feedURL, title = found[0].URL, found[0].Title
+	} else {
+		// Fall back to Miniflux's own discovery, which may see pages we can't
+		subs, mfErr := client.Discover(siteURL)
+		if mfErr != nil || len(subs) == 0 {
+			return "", fmt.Errorf("no feed found for %s: %v", siteURL, err)
+		}
+		feedURL, title = subs[0].URL, subs[0].Title
+	}
+
+	if dryRun {
+		return feedURL, nil
+	}
+
+	if _, err := client.CreateFeed(feedURL, categoryID); err != nil {
+		return "", fmt.Errorf("subscribing to %s: %w", feedURL, err)
+	}
+
+	// Record the subscription locally so it drops out of future recommendations
+	_, err = g.AddFeed(&graph.FeedNode{
+		URL:        feedURL,
+		Title:      title,
+		SiteURL:    siteURL,
+		Subscribed: true,
+		Source:     "miniflux",
+	})
+	return feedURL, err
+}
+
+func cmdServe(fs *flag.FlagSet, args []string, dbPath *string) error {
+	addr := fs.String("addr", "localhost:8080", "Address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	server, err := web.New(g)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/", http.StripPrefix("/api", api.New(g).Handler()))
+	mux.Handle("/", server.Handler())
+
+	fmt.Printf("Serving on http://%s/ (JSON API under /api/)\n", *addr)
+	return http.ListenAndServe(*addr, mux)
+}
+
+func cmdConfig(fs *flag.FlagSet, args []string, dbPath *string) error {
+	secrets := fs.Bool("secrets", false, "Show credentials instead of masking them")
+	if len(args) == 0 || args[0] != "show" {
+		return fmt.Errorf("usage: rss-graph config show [--secrets]")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	// Global flags given with this command override the settings as usual
+	effective := *conf
+	effective.DB = *dbPath
+	if !*secrets {
+		effective = *effective.Redacted()
+	}
+
+	switch {
+	case configErr != nil:
+		fmt.Printf("# Config file %s couldn't be read, so it is left out:\n# %s\n",
+			firstNonEmpty(configFile, config.DefaultPath()), strings.ReplaceAll(configErr.Error(), "\n", "\n# "))
+	case configFile == "":
+		fmt.Printf("# No config file; %s would be read if it existed\n", config.DefaultPath())
+	case configProfile != "":
+		fmt.Printf("# Config file: %s, profile %s\n", configFile, configProfile)
+	default:
+		fmt.Printf("# Config file: %s\n", configFile)
+	}
+	return effective.Write(os.Stdout)
+}
+
+func cmdClusters(fs *flag.FlagSet, args []string, dbPath *string) error {
+	limit := fs.Int("n", 10, "Number of clusters")
+	detect := fs.Bool("detect", false, "Recompute clusters from the current graph")
+	minSize := fs.Int("min-size", 3, "Smallest cluster to show")
+	sites := fs.Int("sites", 5, "Top sites per cluster")
+	people := fs.Int("people", 3, "Most-mentioned people per cluster")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
+	}
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	clusters, err := g.GetClusters(1)
+	if err != nil {
+		return err
+	}
+	// Detect on first use so the command works without --detect
+	if *detect || len(clusters) == 0 {
+		n, err := g.DetectClusters()
+		if err != nil {
+			return err
+		}
+		msg := os.Stdout
+		if !out.Table() {
+			msg = os.Stderr
+		}
+		fmt.Fprintf(msg, "Detected %d clusters.\n", n)
+	}
+	clusters, err = g.GetClusters(*minSize)
+	if err != nil {
+		return err
+	}
+	if len(clusters) > *limit {
+		clusters = clusters[:*limit]
+	}
+
+	records := []output.ClusterTop{}
+	for _, c := range clusters {
+		ranked, err := g.RankFeeds(graph.RankOptions{Limit: *sites, Cluster: c.ID})
+		if err != nil {
+			return err
+		}
+		mentions, err := g.RankMentions(graph.MentionOptions{EntityType: "PERSON", Cluster: c.ID, Limit: *people})
+		if err != nil {
+			return err
+		}
+
+		if !out.Table() {
+			for i, r := range ranked {
+				records = append(records, output.NewClusterSite(c, i+1, r))
+			}
+			for i, m := range mentions {
+				records = append(records, output.NewClusterPerson(c, i+1, m))
+			}
+			continue
+		}
+
+		fmt.Printf("\nCluster %d (%d feeds)\n", c.ID, c.Size)
+		for i, r := range ranked {
+			fmt.Printf("  %d. [%d links] %s\n     %s\n", i+1, r.InboundCount, feedLabel(r.Feed), r.Feed.URL)
+		}
+		if len(mentions) > 0 {
+			names := make([]string, 0, len(mentions))
+			for _, m := range mentions {
+				names = append(names, fmt.Sprintf("%s (%d)", m.Name, m.MentionCount))
+			}
+			fmt.Printf("  People: %s\n", strings.Join(names, ", "))
+		}
+	}
+
+	if !out.Table() {
+		return out.Render(records)
+	}
+	if len(clusters) == 0 {
+		fmt.Printf("No clusters with at least %d feeds yet.\n", *minSize)
+	}
+	return nil
+}
+
+func cmdExport(fs *flag.FlagSet, args []string, dbPath *string) error {
+	format := fs.String("format", "graphml", "Format: "+strings.Join(export.Formats, ", "))
+	ego := fs.String("ego", "", "Only export the network around this feed URL or host")
+	radius := fs.Int("radius", 1, "Hops from --ego to include")
+	minDegree := fs.Int("min-degree", 0, "Drop feeds with fewer distinct neighbors")
+	filterCommon := fs.Bool("filter", false, "Leave out sites the domain filters deny (see filter)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	network, err := g.LoadNetwork()
+	if err != nil {
+		return err
+	}
+
+	if *filterCommon {
+		if network, err = filterNetwork(g, network); err != nil {
+			return err
+		}
+	}
+	if *ego != "" {
+		centers, err := g.FindFeeds(*ego)
+		if err != nil {
+			return err
+		}
+		if len(centers) == 0 {
+			return fmt.Errorf("feed not found: %s", *ego)
+		}
+		var ids []int64
+		for _, f := range centers {
+			ids = append(ids, f.ID)
+		}
+		network = network.Ego(ids, *radius)
+	}
+	if *minDegree > 0 {
+		network = network.MinDegree(*minDegree)
+	}
+
+	return export.Write(os.Stdout, *format, network)
+}
+
+// loadSiteNetwork loads the graph with each site's feeds merged into one
+// node, so chains of links aren't broken between the feed URL a site
+// publishes and the site root other feeds link to.
+func loadSiteNetwork(g *graph.Graph) (*graph.Network, error) {
+	network, err := g.LoadNetwork()
+	if err != nil {
+		return nil, err
+	}
+	return network.MergeHosts(), nil
+}
+
+// resolveSite returns the merged nodes for a feed URL or host.
+func resolveSite(g *graph.Graph, network *graph.Network, ref string) ([]int64, error) {
+	feeds, err := g.FindFeeds(ref)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int64]bool)
+	var ids []int64
+	for _, f := range feeds {
+		if id := network.Resolve(f.ID); id != 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("feed not found: %s", ref)
+	}
+	return ids, nil
+}
+
+func feedLabel(f *graph.FeedNode) string {
+	if f.Title != "" {
+		return f.Title
+	}
+	return f.URL
+}
+
+func cmdHealth(fs *flag.FlagSet, args []string, dbPath *string) error {
+	check := fs.Bool("check", false, "Fetch every feed now before reporting")
+	failures := fs.Int("failures", 3, "Consecutive failed fetches that make a feed dead")
+	staleMonths := fs.Int("stale", 6, "Months without a new post that make a feed stale (0 to disable)")
+	updateMoved := fs.Bool("update-moved", false, "Update the stored URL of feeds that permanently redirect")
+	all := fs.Bool("all", false, "Also list healthy feeds")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
+	}
+	progress := os.Stdout
+	if !out.Table() {
+		progress = os.Stderr
+	}
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	opts := graph.HealthOptions{Failures: *failures}
+	if *staleMonths > 0 {
+		opts.StaleSince = time.Now().AddDate(0, -*staleMonths, 0)
+	}
+	health, err := g.GetFeedHealth(opts)
+	if err != nil {
+		return err
+	}
+
+	if *check {
+		f := newFetcher()
+		fmt.Fprintf(progress, "Checking %d feeds...\n", len(health))
+		for _, h := range health {
+			fetched, err := fetchFeed(f, h.Feed.URL)
+			if err != nil {
+				fmt.Fprintf(progress, "  %s: %v\n", feedLabel(h.Feed), err)
+			}
+			fetched.attempt.FeedID = h.Feed.ID
+			if err := g.RecordFetch(fetched.attempt); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(progress)
+		if health, err = g.GetFeedHealth(opts); err != nil {
+			return err
+		}
+	}
+
+	if *updateMoved {
+		moved := 0
+		for _, h := range health {
+			if h.Status != graph.HealthMoved {
+				continue
+			}
+			if err := g.UpdateFeedURL(h.Feed.ID, h.MovedTo); err != nil {
+				fmt.Fprintf(progress, "Warning: could not move %s: %v\n", h.Feed.URL, err)
+				continue
+			}
+			fmt.Fprintf(progress, "Updated %s\n     -> %s\n", h.Feed.URL, h.MovedTo)
+			moved++
+		}
+		if moved > 0 {
+			fmt.Fprintln(progress)
+			if health, err = g.GetFeedHealth(opts); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !out.Table() {
+		records := []output.FeedHealth{}
+		for _, h := range health {
+			if h.Status != graph.HealthOK || *all {
+				records = append(records, output.NewFeedHealth(h))
+			}
+		}
+		return out.Render(records)
+	}
+
+	if len(health) == 0 {
+		fmt.Println("No feeds to check. Subscribe to or scan some feeds first.")
+		return nil
+	}
+
+	headings := map[string]string{
+		graph.HealthDead:      "Dead",
+		graph.HealthMalformed: "Malformed",
+		graph.HealthMoved:     "Moved",
+		graph.HealthStale:     "Stale",
+		graph.HealthOK:        "OK",
+	}
+	counts := make(map[string]int)
+	for _, h := range health {
+		counts[h.Status]++
+	}
+	if counts[graph.HealthOK] == len(health) && !*all {
+		fmt.Printf("All %d feeds are healthy.\n", len(health))
+		return nil
+	}
+
+	status := ""
+	for _, h := range health {
+		if h.Status == graph.HealthOK && !*all {
+			break
+		}
+		if h.Status != status {
+			status = h.Status
+			fmt.Printf("%s (%d):\n", headings[status], counts[status])
+		}
+		fmt.Printf("  %s\n      %s\n", feedLabel(h.Feed), h.Feed.URL)
+		fmt.Printf("      %s\n", healthDetail(h))
+	}
+	if n := counts[graph.HealthOK]; n > 0 && !*all {
+		fmt.Printf("\nThe other %d of %d feeds are healthy.\n", n, len(health))
+	}
+	return nil
+}
+
+// healthDetail describes why a feed has its health status.
+func healthDetail(h graph.FeedHealth) string {
+	date := func(t time.Time) string {
+		if t.IsZero() {
+			return "never"
+		}
+		return t.Format("2006-01-02")
+	}
+	switch h.Status {
+	case graph.HealthDead:
+		detail := fmt.Sprintf("%d failed fetches in a row, last success %s", h.Failures, date(h.LastSuccess))
+		if h.Last != nil {
+			detail += fmt.Sprintf("; last error (%s): %s", h.Last.ErrorClass, h.Last.Error)
+		}
+		return detail
+	case graph.HealthMalformed:
+		return fmt.Sprintf("Fetched %s but couldn't parse it: %s", date(h.Last.AttemptedAt), h.Last.Error)
+	case graph.HealthMoved:
+		return fmt.Sprintf("Permanently redirects to %s (use --update-moved to follow)", h.MovedTo)
+	case graph.HealthStale:
+		return fmt.Sprintf("No new post since %s", date(h.NewestPost))
+	}
+	if h.Last == nil {
+		return fmt.Sprintf("Never fetched here, newest post %s", date(h.NewestPost))
+	}
+	if h.Last.Failed() {
+		return fmt.Sprintf("Last fetch %s failed (%s): %s; last success %s",
+			date(h.Last.AttemptedAt), h.Last.ErrorClass, h.Last.Error, date(h.LastSuccess))
+	}
+	return fmt.Sprintf("Fetched %s (HTTP %d, %d ms, %d items), newest post %s",
+		date(h.Last.AttemptedAt), h.Last.Status, h.Last.Latency.Milliseconds(), h.Last.ItemCount, date(h.NewestPost))
+}
+
+func cmdPath(fs *flag.FlagSet, args []string, dbPath *string) error {
+	maxPaths := fs.Int("max", 3, "Max paths to show")
+	undirected := fs.Bool("undirected", false, "Follow links in either direction")
+	posts := fs.Int("posts", 2, "Max posts to show per hop (0 for all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return fmt.Errorf("usage: rss-graph path <from url or host> <to url or host>")
+	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
+	}
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	network, err := loadSiteNetwork(g)
+	if err != nil {
+		return err
+	}
+	from, err := resolveSite(g, network, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	to, err := resolveSite(g, network, fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	paths := network.ShortestPaths(from, to, *undirected, *maxPaths)
+
+	records := []output.PathPost{}
+	if out.Table() {
+		if len(paths) == 0 {
+			fmt.Printf("No chain of links from %s to %s.\n", fs.Arg(0), fs.Arg(1))
+			if !*undirected {
+				fmt.Println("Try --undirected to follow links in either direction.")
+			}
+			return nil
+		}
+		fmt.Printf("%d shortest path(s) from %s to %s (%d hops):\n", len(paths), fs.Arg(0), fs.Arg(1), len(paths[0])-1)
+	}
+
+	for i, path := range paths {
+		if out.Table() {
+			var names []string
+			for _, id := range path {
+				names = append(names, feedLabel(network.Nodes[id]))
+			}
+			fmt.Printf("\nPath %d: %s\n", i+1, strings.Join(names, " → "))
+		}
+
+		for hop := 1; hop < len(path); hop++ {
+			source, target := path[hop-1], path[hop]
+			// With --undirected a hop may follow a link backwards
+			links, err := g.GetLinksBetween(network.Members[source], network.Members[target])
+			if err != nil {
+				return err
+			}
+			arrow := "→"
+			if len(links) == 0 {
+				if links, err = g.GetLinksBetween(network.Members[target], network.Members[source]); err != nil {
+					return err
+				}
+				arrow = "←"
+			}
+
+			if out.Table() {
+				fmt.Printf("  %s %s %s (%d links)\n", feedLabel(network.Nodes[source]), arrow, feedLabel(network.Nodes[target]), len(links))
+			}
+			for j, l := range links {
+				if *posts > 0 && j >= *posts {
+					if out.Table() {
+						fmt.Printf("      ... and %d more posts\n", len(links)-*posts)
+					}
+					break
+				}
+				if !out.Table() {
+					linkSource, linkTarget := network.Resolve(l.SourceID), network.Resolve(l.TargetID)
+					record := output.PathPost{
+						Path:      i + 1,
+						Hop:       hop,
+						SourceID:  linkSource,
+						SourceURL: network.Nodes[linkSource].URL,
+						TargetID:  linkTarget,
+						TargetURL: network.Nodes[linkTarget].URL,
+						Links:     len(links),
+						PostURL:   l.PostURL,
+						PostTitle: l.PostTitle,
+					}
+					if !l.PublishedAt.IsZero() {
+						published := l.PublishedAt
+						record.PublishedAt = &published
+					}
+					records = append(records, record)
+					continue
+				}
+				postTitle := l.PostTitle
+				if postTitle == "" {
+					postTitle = "(untitled post)"
+				}
+				fmt.Printf("      %s  %s\n", linkDate(l).Format("2006-01-02"), postTitle)
+				if l.PostURL != "" {
+					fmt.Printf("                  %s\n", l.PostURL)
+				}
+			}
+		}
+	}
+
+	if !out.Table() {
+		return out.Render(records)
+	}
+	return nil
+}
+
+func cmdNeighborhood(fs *flag.FlagSet, args []string, dbPath *string) error {
+	depth := fs.Int("depth", 2, "Hops to include")
+	limit := fs.Int("limit", 50, "Max edges to show (0 for all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: rss-graph neighborhood <url or host>")
+	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
+	}
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	network, err := loadSiteNetwork(g)
+	if err != nil {
+		return err
+	}
+	centers, err := resolveSite(g, network, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	dist := network.Distances(centers, *depth)
+	ego := network.Subgraph(func(f *graph.FeedNode) bool {
+		_, ok := dist[f.ID]
+		return ok
+	})
+
+	// Heaviest edges first
+	edges := append([]graph.NetworkEdge(nil), ego.Edges...)
+	sort.SliceStable(edges, func(i, j int) bool { return edges[i].Weight > edges[j].Weight })
+	if *limit > 0 && len(edges) > *limit {
+		edges = edges[:*limit]
+	}
+
+	if !out.Table() {
+		records := make([]output.NeighborhoodEdge, 0, len(edges))
+		for _, e := range edges {
+			records = append(records, output.NeighborhoodEdge{
+				SourceID:    e.SourceID,
+				SourceURL:   ego.Nodes[e.SourceID].URL,
+				SourceDepth: dist[e.SourceID],
+				TargetID:    e.TargetID,
+				TargetURL:   ego.Nodes[e.TargetID].URL,
+				TargetDepth: dist[e.TargetID],
+				Weight:      e.Weight,
+			})
+		}
+		return out.Render(records)
+	}
+
+	fmt.Printf("Neighborhood of %s: %d sites, %d edges within %d hops\n", fs.Arg(0), len(ego.Nodes), len(ego.Edges), *depth)
+
+	neighbors := ego.Neighbors()
+	for d := 1; d <= *depth; d++ {
+		var ids []int64
+		for _, id := range ego.NodeIDs() {
+			if dist[id] == d {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			break
+		}
+		sort.SliceStable(ids, func(i, j int) bool { return len(neighbors[ids[i]]) > len(neighbors[ids[j]]) })
+		fmt.Printf("\nHop %d (%d sites):\n", d, len(ids))
+		for _, id := range ids {
+			fmt.Printf("  [%d neighbors] %s\n      %s\n", len(neighbors[id]), feedLabel(ego.Nodes[id]), ego.Nodes[id].URL)
+		}
+	}
+
+	if len(edges) > 0 {
+		fmt.Println("\nEdges by link count:")
+		for _, e := range edges {
+			fmt.Printf("  %4d  %s → %s\n", e.Weight, feedLabel(ego.Nodes[e.SourceID]), feedLabel(ego.Nodes[e.TargetID]))
+		}
+		if len(ego.Edges) > len(edges) {
+			fmt.Printf("  ... and %d more edges\n", len(ego.Edges)-len(edges))
+		}
+	}
+	return nil
+}
+
+func cmdSimilar(fs *flag.FlagSet, args []string, dbPath *string) error {
+	limit := fs.Int("n", 10, "Number of results")
+	metric := fs.String("metric", string(graph.Cosine), "Similarity metric: cosine, jaccard")
+	evidence := fs.Int("evidence", 3, "Max shared sites to list per result")
+	filterCommon := fs.Bool("filter", false, "Leave out sites the domain filters deny (see filter)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: rss-graph similar <url or host>")
+	}
+	*evidence = max(*evidence, 0)
+	if m := graph.SimilarityMetric(*metric); m != graph.Cosine && m != graph.Jaccard {
+		return fmt.Errorf("unknown metric: %s (want cosine or jaccard)", *metric)
+	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
+	}
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	network, err := loadSiteNetwork(g)
+	if err != nil {
+		return err
+	}
+	// Nearly everyone cites the common domains, so they say little about
+	// what two sites have in common
+	if *filterCommon {
+		if network, err = filterNetwork(g, network); err != nil {
+			return err
+		}
+	}
+	centers, err := resolveSite(g, network, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	center := centers[0]
+
+	similar := network.Similar(center, graph.SimilarityMetric(*metric), *limit)
+
+	urls := func(ids []int64) []string {
+		out := []string{}
+		for _, id := range ids[:min(len(ids), *evidence)] {
+			out = append(out, network.Nodes[id].URL)
+		}
+		return out
+	}
+	if !out.Table() {
+		records := make([]output.SimilarFeed, 0, len(similar))
+		for i, s := range similar {
+			f := network.Nodes[s.ID]
+			records = append(records, output.SimilarFeed{
+				Rank:         i + 1,
+				FeedID:       f.ID,
+				URL:          f.URL,
+				Title:        f.Title,
+				Subscribed:   f.Subscribed,
+				Score:        s.Score,
+				CoCitation:   s.CoCitation,
+				Coupling:     s.Coupling,
+				SharedCiters: len(s.SharedCiters),
+				SharedCited:  len(s.SharedCited),
+				CiterURLs:    urls(s.SharedCiters),
+				CitedURLs:    urls(s.SharedCited),
+			})
+		}
+		return out.Render(records)
+	}
+
+	if len(similar) == 0 {
+		fmt.Printf("No sites share citers or cited sites with %s yet.\n", feedLabel(network.Nodes[center]))
+		return nil
+	}
+
+	labels := func(ids []int64) string {
+		var names []string
+		for _, id := range ids[:min(len(ids), *evidence)] {
+			names = append(names, feedLabel(network.Nodes[id]))
+		}
+		if len(ids) > *evidence {
+			names = append(names, fmt.Sprintf("+%d more", len(ids)-*evidence))
+		}
+		return strings.Join(names, ", ")
+	}
+	fmt.Printf("Sites similar to %s:\n", feedLabel(network.Nodes[center]))
+	for i, s := range similar {
+		f := network.Nodes[s.ID]
+		mark := ""
+		if f.Subscribed {
+			mark = " ✓"
+		}
+		fmt.Printf("%2d. [%.2f] %s%s\n    %s\n", i+1, s.Score, feedLabel(f), mark, f.URL)
+		if len(s.SharedCiters) > 0 && *evidence > 0 {
+			fmt.Printf("    Both cited by: %s\n", labels(s.SharedCiters))
+		}
+		if len(s.SharedCited) > 0 && *evidence > 0 {
+			fmt.Printf("    Both cite: %s\n", labels(s.SharedCited))
+		}
+	}
+	return nil
+}
+
+func cmdConversations(fs *flag.FlagSet, args []string, dbPath *string) error {
+	limit := fs.Int("n", 20, "Number of site pairs")
+	days := fs.Int("days", 30, "Max days between replies in a thread")
+	maxThreads := fs.Int("max", 3, "Max threads per pair when a site is given")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
+	}
+	window := time.Duration(*days) * 24 * time.Hour
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	network, err := loadSiteNetwork(g)
+	if err != nil {
+		return err
+	}
+	pairs := network.Reciprocal()
+
+	// With a site, keep its pairs and put it on the A side
+	var center int64
+	if fs.NArg() > 0 {
+		centers, err := resolveSite(g, network, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		center = centers[0]
+		var mine []graph.ReciprocalPair
+		for _, p := range pairs {
+			switch center {
+			case p.AToB.SourceID:
+				mine = append(mine, p)
+			case p.BToA.SourceID:
+				mine = append(mine, p.Swap())
+			}
+		}
+		pairs = mine
+	}
+	if len(pairs) > *limit {
+		pairs = pairs[:*limit]
+	}
+
+	conversations := make([][]graph.Conversation, len(pairs))
+	for i, p := range pairs {
+		a, b := p.AToB.SourceID, p.AToB.TargetID
+		conversations[i], err = g.GetConversations(network.Members[a], network.Members[b], window)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !out.Table() {
+		if center == 0 {
+			records := make([]output.ReciprocalPair, 0, len(pairs))
+			for i, p := range pairs {
+				records = append(records, output.NewReciprocalPair(p, network.Nodes, len(conversations[i])))
+			}
+			return out.Render(records)
+		}
+		records := []output.ConversationLink{}
+		thread := 0
+		for i := range pairs {
+			for _, c := range conversations[i][:min(len(conversations[i]), *maxThreads)] {
+				thread++
+				for _, l := range c.Links {
+					records = append(records, output.NewConversationLink(thread, c, l, network))
+				}
+			}
+		}
+		return out.Render(records)
+	}
+
+	if len(pairs) == 0 {
+		fmt.Println("No sites link to each other yet.")
+		return nil
+	}
+
+	if center == 0 {
+		fmt.Println("Sites that link to each other:")
+	} else {
+		fmt.Printf("Sites in conversation with %s:\n", feedLabel(network.Nodes[center]))
+	}
+	for i, p := range pairs {
+		a, b := network.Nodes[p.AToB.SourceID], network.Nodes[p.AToB.TargetID]
+		fmt.Printf("\n%2d. [%d threads] %s ⇄ %s\n", i+1, len(conversations[i]), feedLabel(a), feedLabel(b))
+		fmt.Printf("    → %d links, last %s\n", p.AToB.Weight, p.AToB.LastSeen.Format("2006-01-02"))
+		fmt.Printf("    ← %d links, last %s\n", p.BToA.Weight, p.BToA.LastSeen.Format("2006-01-02"))
+		if center == 0 {
+			continue
+		}
+		for _, c := range conversations[i][:min(len(conversations[i]), *maxThreads)] {
+			fmt.Printf("    Thread %s – %s:\n", c.Start.Format("2006-01-02"), c.End.Format("2006-01-02"))
+			for _, l := range c.Links {
+				arrow := "→"
+				if network.Resolve(l.SourceID) != center {
+					arrow = "←"
+				}
+				title := l.PostTitle
+				if title == "" {
+					title = l.PostURL
+				}
+				fmt.Printf("      %s %s  %s\n        %s\n", arrow, linkDate(l).Format("2006-01-02"), title, l.PostURL)
+			}
+		}
+	}
+	return nil
+}
+
+func cmdSearch(fs *flag.FlagSet, args []string, dbPath *string) error {
+	limit := fs.Int("n", 20, "Number of results")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: rss-graph search <query>")
+	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
+	}
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	opts := graph.SearchOptions{Query: strings.Join(fs.Args(), " "), Limit: *limit}
+	if out.Table() && isTerminal(os.Stdout) {
+		opts.HighlightStart, opts.HighlightEnd = "\033[1m", "\033[0m"
+	}
+	matches, err := g.SearchPosts(opts)
+	if err != nil {
+		return err
+	}
+
+	if !out.Table() {
+		records := make([]output.SearchResult, 0, len(matches))
+		for i, m := range matches {
+			records = append(records, output.NewSearchResult(i+1, m))
+		}
+		return out.Render(records)
+	}
+
+	if len(matches) == 0 {
+		fmt.Printf("No posts match %q.\n", opts.Query)
+		return nil
+	}
+
+	for i, m := range matches {
+		title := m.Post.Title
+		if title == "" {
+			title = "(untitled)"
+		}
+		fmt.Printf("%2d. %s\n    %s\n", i+1, title, m.Post.URL)
+		date := ""
+		if !m.Post.PublishedAt.IsZero() {
+			date = ", " + m.Post.PublishedAt.Format("2006-01-02")
+		}
+		fmt.Printf("    %s%s\n", feedLabel(m.Feed), date)
+		if m.Snippet != "" {
+			fmt.Printf("    %s\n", m.Snippet)
+		}
+		if len(m.Targets) > 0 {
+			var hosts []string
+			for _, f := range m.Targets {
+				hosts = append(hosts, feedLabel(f))
+			}
+			fmt.Printf("    Links to: %s\n", strings.Join(hosts, ", "))
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
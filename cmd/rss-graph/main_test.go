@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+)
+
+func TestCmdSubscribe_DryRun(t *testing.T) {
+	// One server stands in for both Miniflux and the site, and any POST to
+	// it would change something
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			t.Errorf("Expected no POST on a dry run, got %s", r.URL.Path)
+			http.Error(w, "dry run", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/categories":
+			json.NewEncoder(w).Encode([]map[string]any{{"id": 1, "title": "All"}})
+		case "/blog/":
+			w.Write([]byte(`<html><head><link rel="alternate" type="application/rss+xml" href="/blog/feed.xml"></head></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dbPath := filepath.Join(t.TempDir(), "graph.db")
+	fs := flag.NewFlagSet("subscribe", flag.ContinueOnError)
+	args := []string{"-dry-run", "-url", server.URL, "-api-key", "key", "-category", "Discovered", server.URL + "/blog/"}
+	if err := cmdSubscribe(fs, args, &dbPath); err != nil {
+		t.Fatalf("cmdSubscribe error: %v", err)
+	}
+
+	g, err := graph.NewGraph(dbPath)
+	if err != nil {
+		t.Fatalf("NewGraph error: %v", err)
+	}
+	defer g.Close()
+	if feed, _ := g.GetFeedByURL(server.URL + "/blog/feed.xml"); feed != nil {
+		t.Errorf("Expected nothing recorded on a dry run, got %+v", feed)
+	}
+}
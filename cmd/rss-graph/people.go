@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/daniel-butler/rss-graph/pkg/discover"
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+	"github.com/daniel-butler/rss-graph/pkg/output"
+)
+
+func cmdPeople(fs *flag.FlagSet, args []string, dbPath *string) error {
+	limit := fs.Int("n", 30, "Number of results (0 for all)")
+	fetch := fs.Bool("fetch", false, "Fetch homepages for their author and rel=\"me\" links first")
+	days := fs.Int("days", 30, "Days before fetching a homepage again (with --fetch)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	out, err := renderer(fs)
+	if err != nil {
+		return err
+	}
+
+	g, err := ensureDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	progress := os.Stdout
+	if !out.Table() {
+		progress = os.Stderr
+	}
+	if *fetch {
+		if err := fetchHomepages(g, progress, time.Duration(*days)*24*time.Hour); err != nil {
+			return err
+		}
+	}
+
+	sites, err := g.PersonSites()
+	if err != nil {
+		return err
+	}
+	if *limit > 0 && len(sites) > *limit {
+		sites = sites[:*limit]
+	}
+
+	if !out.Table() {
+		records := make([]output.PersonSite, 0, len(sites))
+		for _, s := range sites {
+			records = append(records, output.NewPersonSite(s))
+		}
+		return out.Render(records)
+	}
+
+	if len(sites) == 0 {
+		fmt.Println("No people linked to sites yet. Run 'crawl' first, or 'people --fetch' to read homepages.")
+		return nil
+	}
+	fmt.Println("People and the sites they likely run:")
+	for i, s := range sites {
+		fmt.Printf("%2d. [%.0f score] %s\n    %s (%s)\n", i+1, s.Score, s.Name, s.Host, personEvidence(s))
+	}
+	return nil
+}
+
+// personEvidence describes why a person is linked to a site.
+func personEvidence(s graph.PersonSite) string {
+	var evidence []string
+	if s.Anchors > 0 {
+		evidence = append(evidence, fmt.Sprintf("named in links from %d feeds", s.Anchors))
+	}
+	if s.Posts > 0 {
+		evidence = append(evidence, fmt.Sprintf("author of %d posts", s.Posts))
+	}
+	if s.Homepage {
+		evidence = append(evidence, "homepage author")
+	}
+	if s.RelMe {
+		evidence = append(evidence, `rel="me"`)
+	}
+	return strings.Join(evidence, ", ")
+}
+
+// fetchHomepages fetches the homepages of subscribed sites and of sites
+// already linked to someone, unless fetched within maxAge, and stores
+// their author and rel="me" links.
+func fetchHomepages(g *graph.Graph, progress io.Writer, maxAge time.Duration) error {
+	feeds, err := g.GetSubscribedFeeds()
+	if err != nil {
+		return err
+	}
+	sites, err := g.PersonSites()
+	if err != nil {
+		return err
+	}
+	homepages, err := g.GetHomepages()
+	if err != nil {
+		return err
+	}
+
+	candidates := make(map[string]bool)
+	for _, feed := range feeds {
+		candidates[urlHost(feed.URL)] = true
+	}
+	for _, s := range sites {
+		candidates[s.Host] = true
+	}
+	var hosts []string
+	for host := range candidates {
+		if h := homepages[host]; host == "" || (h != nil && time.Since(h.FetchedAt) < maxAge) {
+			continue
+		}
+		filtered, err := g.HostFiltered("https://" + host + "/")
+		if err != nil {
+			return err
+		}
+		if !filtered {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+
+	f := newFetcher()
+	for i, host := range hosts {
+		homepageURL := "https://" + host + "/"
+		h := &graph.Homepage{Host: host}
+		line := fmt.Sprintf("[%d/%d] %s", i+1, len(hosts), homepageURL)
+		if body, err := f.Fetch(homepageURL); err != nil {
+			h.Error = err.Error()
+			line += fmt.Sprintf(": %v", err)
+		} else {
+			id := discover.ParseIdentity(body, homepageURL)
+			h.Author = id.Author
+			for _, me := range id.Me {
+				if meHost := urlHost(me); meHost != "" {
+					h.Me = append(h.Me, meHost)
+				}
+			}
+			if h.Author != "" {
+				line += fmt.Sprintf(": by %s", h.Author)
+			}
+			if len(h.Me) > 0 {
+				line += fmt.Sprintf(", %d rel=\"me\" links", len(h.Me))
+			}
+		}
+		if err := g.SetHomepage(h); err != nil {
+			return err
+		}
+		fmt.Fprintln(progress, line)
+	}
+	fmt.Fprintf(progress, "Fetched %d homepages\n\n", len(hosts))
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/daniel-butler/rss-graph/pkg/extractor"
+	"github.com/daniel-butler/rss-graph/pkg/feed"
+	"github.com/daniel-butler/rss-graph/pkg/fetcher"
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+	"github.com/daniel-butler/rss-graph/pkg/ner"
+)
+
+// fetchedFeed is the outcome of fetching a feed.
+type fetchedFeed struct {
+	feed    *feed.Feed          // Nil if the fetch failed
+	attempt *graph.FetchAttempt // Always set; the caller fills in the feed ID and records it
+	maxAge  time.Duration       // Cache-Control max-age
+}
+
+// fetchFeed fetches and parses a feed, describing how it went as a fetch
+// attempt. The result is returned even when the fetch fails.
+func fetchFeed(f *fetcher.Fetcher, feedURL string) (*fetchedFeed, error) {
+	resp, err := f.Get(feedURL)
+	attempt := &graph.FetchAttempt{AttemptedAt: time.Now(), ErrorClass: fetcher.Classify(err)}
+	fetched := &fetchedFeed{attempt: attempt}
+	if resp != nil {
+		attempt.Status = resp.StatusCode
+		attempt.Latency = resp.Latency
+		attempt.Bytes = len(resp.Body)
+		attempt.MovedTo = resp.MovedTo
+		fetched.maxAge = resp.MaxAge
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+		return fetched, fmt.Errorf("fetching feed: %w", err)
+	}
+
+	parsed, err := feed.ParseFeed(resp.Body)
+	if err != nil {
+		attempt.ErrorClass = graph.ErrorClassParse
+		attempt.Error = err.Error()
+		return fetched, fmt.Errorf("parsing feed: %w", err)
+	}
+	attempt.ItemCount = len(parsed.Items)
+	for _, item := range parsed.Items {
+		if item.PublishedAt.After(attempt.NewestItemAt) {
+			attempt.NewestItemAt = item.PublishedAt
+		}
+	}
+	fetched.feed = parsed
+	return fetched, nil
+}
+
+// scanStats counts what a postScanner stored.
+type scanStats struct {
+	links     int
+	mentions  int
+	unchanged int // Posts skipped as already stored and unchanged
+	retracted int // Links and mentions edited out of changed posts
+	removed   int // Posts no longer in their feed
+}
+
+// postScanner stores one feed's posts along with the links and people
+// mentioned in them. scan, crawl and daemon all go through it.
+type postScanner struct {
+	g       *graph.Graph
+	feedID  int64
+	siteURL string    // Links to this URL's host are internal and skipped
+	full    bool      // Reprocess posts we already have unchanged
+	partial bool      // The feed holds only some entries, as pushed by a hub
+	warn    io.Writer // Where to report posts that couldn't be stored
+	stats   scanStats
+}
+
+// scanFeed stores every item of a fetched feed and, unless it is partial,
+// marks posts that have dropped out of it as removed.
+func (s *postScanner) scanFeed(parsed *feed.Feed) error {
+	var present []*graph.Post
+	var oldest time.Time
+	for _, item := range parsed.Items {
+		content := item.Content
+		if content == "" {
+			content = item.Description
+		}
+		post := &graph.Post{
+			FeedID:      s.feedID,
+			URL:         item.URL,
+			GUID:        item.GUID,
+			Title:       item.Title,
+			Author:      item.Author,
+			PublishedAt: item.PublishedAt,
+			UpdatedAt:   item.UpdatedAt,
+		}
+		present = append(present, post)
+		if !item.PublishedAt.IsZero() && (oldest.IsZero() || item.PublishedAt.Before(oldest)) {
+			oldest = item.PublishedAt
+		}
+		if err := s.scan(post, content, item.ExtractedLinks, false); err != nil {
+			return err
+		}
+	}
+
+	if s.partial {
+		return nil
+	}
+
+	// Posts that dropped out of the feed take their links with them
+	removed, err := s.g.MarkRemovedPosts(s.feedID, present, oldest)
+	if err != nil {
+		return err
+	}
+	s.stats.removed += removed
+	return nil
+}
+
+// scan stores a post, given its HTML content and the links in it, with its
+// links and the people it mentions, and retracts the ones an edited post no
+// longer has. Links from posts starred in our reader are marked starred.
+func (s *postScanner) scan(post *graph.Post, content string, links []extractor.Link, starred bool) error {
+	g := s.g
+	post.Content = extractor.Text(content)
+	if post.URL != "" || post.GUID != "" {
+		changed := s.full
+		if !changed {
+			var err error
+			if changed, err = g.PostChanged(post); err != nil {
+				return err
+			}
+		}
+		if !changed {
+			s.stats.unchanged++
+			return nil
+		}
+		if _, err := g.AddPost(post); err != nil {
+			fmt.Fprintf(s.warn, "Warning: failed to store post %s: %v\n", post.URL, err)
+		}
+	}
+
+	var targets []int64
+	for _, link := range links {
+		// Skip links to same domain (internal links)
+		if isSameDomain(s.siteURL, link.URL) {
+			continue
+		}
+
+		// Add the target as a potential feed
+		targetID, err := g.AddFeed(&graph.FeedNode{
+			URL:   normalizeToFeedURL(link.URL),
+			Title: link.Text,
+		})
+		if err != nil {
+			continue
+		}
+
+		err = g.AddLink(&graph.LinkEdge{
+			SourceID:    s.feedID,
+			TargetID:    targetID,
+			PostID:      post.ID,
+			Context:     link.Text,
+			PostURL:     post.URL,
+			PostTitle:   post.Title,
+			PublishedAt: post.PublishedAt,
+			Starred:     starred,
+		})
+		if err == nil {
+			s.stats.links++
+			targets = append(targets, targetID)
+		}
+	}
+
+	// Extract people mentions using NER
+	people := ner.ExtractPeople(content)
+	for _, name := range people {
+		err := g.AddMention(&graph.Mention{
+			SourceID:    s.feedID,
+			PostID:      post.ID,
+			Name:        name,
+			EntityType:  "PERSON",
+			PostURL:     post.URL,
+			PostTitle:   post.Title,
+			PublishedAt: post.PublishedAt,
+		})
+		if err == nil {
+			s.stats.mentions++
+		}
+	}
+
+	// Retract links and mentions an edited post no longer has
+	if post.ID != 0 {
+		n, err := g.RetractLinks(post.ID, targets)
+		if err != nil {
+			return err
+		}
+		s.stats.retracted += n
+		if n, err = g.RetractMentions(post.ID, people); err != nil {
+			return err
+		}
+		s.stats.retracted += n
+	}
+	return nil
+}
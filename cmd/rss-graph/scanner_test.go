@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/daniel-butler/rss-graph/pkg/extractor"
+	"github.com/daniel-butler/rss-graph/pkg/feed"
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+)
+
+func TestPostScanner_RestoresReturningPost(t *testing.T) {
+	g, err := graph.NewGraph(":memory:")
+	if err != nil {
+		t.Fatalf("NewGraph error: %v", err)
+	}
+	defer g.Close()
+	feedID, _ := g.AddFeed(&graph.FeedNode{URL: "https://a.com/feed", Subscribed: true})
+
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	current := &feed.Item{URL: "https://a.com/current", Title: "Current", Content: "<p>Hi</p>", PublishedAt: day(5)}
+	dropped := &feed.Item{
+		URL: "https://a.com/dropped", Title: "Dropped", Content: `<p>See <a href="https://b.com/post">b</a></p>`,
+		PublishedAt:    day(10),
+		ExtractedLinks: []extractor.Link{{URL: "https://b.com/post", Text: "b"}},
+	}
+	scan := func(items ...*feed.Item) scanStats {
+		t.Helper()
+		parsed := &feed.Feed{}
+		for _, item := range items {
+			parsed.Items = append(parsed.Items, *item)
+		}
+		s := &postScanner{g: g, feedID: feedID, siteURL: "https://a.com/", warn: io.Discard}
+		if err := s.scanFeed(parsed); err != nil {
+			t.Fatalf("scanFeed error: %v", err)
+		}
+		return s.stats
+	}
+	inbound := func() int {
+		target, _ := g.GetFeedByURL(normalizeToFeedURL("https://b.com/post"))
+		if target == nil {
+			t.Fatal("Expected the link target in the graph")
+		}
+		links, _ := g.GetInboundLinks(target.ID)
+		return len(links)
+	}
+
+	scan(current, dropped)
+	if inbound() != 1 {
+		t.Fatalf("Expected the link stored, got %d", inbound())
+	}
+
+	if stats := scan(current); stats.removed != 1 {
+		t.Errorf("Expected the dropped post removed, got %+v", stats)
+	}
+	if inbound() != 0 {
+		t.Errorf("Expected the removed post's link retracted, got %d", inbound())
+	}
+
+	// Back in the feed with the same content, it is stored again
+	if stats := scan(current, dropped); stats.unchanged != 1 {
+		t.Errorf("Expected only the current post unchanged, got %+v", stats)
+	}
+	if p, _ := g.GetPost(feedID, dropped.URL); p == nil || !p.RemovedAt.IsZero() {
+		t.Errorf("Expected the post restored, got %+v", p)
+	}
+	if inbound() != 1 {
+		t.Errorf("Expected the restored post's link back, got %d", inbound())
+	}
+}
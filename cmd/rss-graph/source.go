@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+	"github.com/daniel-butler/rss-graph/pkg/greader"
+	"github.com/daniel-butler/rss-graph/pkg/miniflux"
+	"github.com/daniel-butler/rss-graph/pkg/source"
+)
+
+// sourceFlags are the flags selecting and authenticating a reader backend.
+type sourceFlags struct {
+	kind     *string
+	url      *string
+	apiKey   *string
+	user     *string
+	password *string
+}
+
+func addSourceFlags(fs *flag.FlagSet) *sourceFlags {
+	return &sourceFlags{
+		kind:     fs.String("source", conf.Source, "Reader backend: miniflux, greader"),
+		url:      fs.String("url", "", "Reader server URL (default: $MINIFLUX_URL or $GREADER_URL)"),
+		apiKey:   fs.String("api-key", conf.Miniflux.APIKey, "Miniflux API key"),
+		user:     fs.String("user", conf.GReader.User, "Google Reader API username"),
+		password: fs.String("password", conf.GReader.Password, "Google Reader API password"),
+	}
+}
+
+// open returns the selected backend. Call after the flags are parsed.
+func (sf *sourceFlags) open() (source.Source, error) {
+	switch *sf.kind {
+	case "miniflux":
+		url := firstNonEmpty(*sf.url, conf.Miniflux.URL)
+		if url == "" || *sf.apiKey == "" {
+			return nil, fmt.Errorf("MINIFLUX_URL and MINIFLUX_API_KEY required (flags, env or config file)")
+		}
+		return miniflux.NewClient(url, *sf.apiKey), nil
+	case "greader":
+		url := firstNonEmpty(*sf.url, conf.GReader.URL)
+		if url == "" || *sf.user == "" || *sf.password == "" {
+			return nil, fmt.Errorf("GREADER_URL, GREADER_USER and GREADER_PASSWORD required (flags, env or config file)")
+		}
+		return greader.NewClient(url, *sf.user, *sf.password), nil
+	default:
+		return nil, fmt.Errorf("unknown source %q (want miniflux or greader)", *sf.kind)
+	}
+}
+
+// addSubscription records a reader subscription and its categories in the graph.
+func addSubscription(g *graph.Graph, src source.Source, f source.Feed) (int64, error) {
+	id, err := g.AddFeed(&graph.FeedNode{
+		URL:        f.FeedURL,
+		Title:      f.Title,
+		SiteURL:    f.SiteURL,
+		Subscribed: true,
+		Source:     src.Name(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, category := range f.Categories {
+		if err := g.AddFeedTag(id, category); err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
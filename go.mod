@@ -2,14 +2,17 @@ module github.com/daniel-butler/rss-graph
 
 go 1.22
 
-require modernc.org/sqlite v1.29.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/jdkato/prose/v2 v2.0.0
+	modernc.org/sqlite v1.29.0
+)
 
 require (
 	github.com/deckarep/golang-set v1.7.1 // indirect
 	github.com/dustin/go-humanize v1.0.1 // indirect
 	github.com/google/uuid v1.3.0 // indirect
 	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
-	github.com/jdkato/prose/v2 v2.0.0 // indirect
 	github.com/mattn/go-isatty v0.0.16 // indirect
 	github.com/mingrammer/commonregex v1.0.1 // indirect
 	github.com/ncruces/go-strftime v0.1.9 // indirect
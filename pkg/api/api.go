@@ -0,0 +1,424 @@
+// Package api serves a read-only JSON API over the feed graph.
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+// Server answers API requests from graph queries.
+type Server struct {
+	g *graph.Graph
+}
+
+// New creates a Server for the given graph.
+func New(g *graph.Graph) *Server {
+	return &Server{g: g}
+}
+
+// Handler returns the HTTP handler for the API, rooted at /.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /feeds", s.handleFeeds)
+	mux.HandleFunc("GET /feeds/{id}", s.handleFeed)
+	mux.HandleFunc("GET /feeds/{id}/inbound", s.handleLinks(s.g.GetInboundLinks))
+	mux.HandleFunc("GET /feeds/{id}/outbound", s.handleLinks(s.g.GetOutboundLinks))
+	mux.HandleFunc("GET /rank", s.handleRank)
+	mux.HandleFunc("GET /mentions", s.handleMentions)
+	mux.HandleFunc("GET /rising", s.handleRising)
+	mux.HandleFunc("GET /snapshots", s.handleSnapshots)
+	return mux
+}
+
+// Feed is the JSON form of a graph.FeedNode.
+type Feed struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	Title      string    `json:"title"`
+	SiteURL    string    `json:"site_url,omitempty"`
+	Subscribed bool      `json:"subscribed"`
+	Source     string    `json:"source,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Link is the JSON form of a graph.LinkEdge.
+type Link struct {
+	ID           int64      `json:"id"`
+	SourceID     int64      `json:"source_id"`
+	TargetID     int64      `json:"target_id"`
+	PostURL      string     `json:"post_url,omitempty"`
+	PostTitle    string     `json:"post_title,omitempty"`
+	Context      string     `json:"context,omitempty"`
+	PublishedAt  *time.Time `json:"published_at,omitempty"`
+	Starred      bool       `json:"starred"`
+	DiscoveredAt time.Time  `json:"discovered_at"`
+}
+
+// RankedFeed is one row of a ranking.
+type RankedFeed struct {
+	Feed         Feed    `json:"feed"`
+	InboundCount int     `json:"inbound_count"`
+	Score        float64 `json:"score,omitempty"`
+	CitingFeeds  int     `json:"citing_feeds,omitempty"`
+}
+
+// Mention is a ranked or rising entity.
+type Mention struct {
+	Name          string   `json:"name"`
+	EntityType    string   `json:"entity_type"`
+	Count         int      `json:"count"`
+	PreviousCount *int     `json:"previous_count,omitempty"`
+	Velocity      *float64 `json:"velocity,omitempty"`
+	Status        string   `json:"status,omitempty"`
+}
+
+func toFeed(f *graph.FeedNode) Feed {
+	return Feed{
+		ID:         f.ID,
+		URL:        f.URL,
+		Title:      f.Title,
+		SiteURL:    f.SiteURL,
+		Subscribed: f.Subscribed,
+		Source:     f.Source,
+		CreatedAt:  f.CreatedAt,
+	}
+}
+
+func toLink(l graph.LinkEdge) Link {
+	link := Link{
+		ID:           l.ID,
+		SourceID:     l.SourceID,
+		TargetID:     l.TargetID,
+		PostURL:      l.PostURL,
+		PostTitle:    l.PostTitle,
+		Context:      l.Context,
+		Starred:      l.Starred,
+		DiscoveredAt: l.DiscoveredAt,
+	}
+	if !l.PublishedAt.IsZero() {
+		link.PublishedAt = &l.PublishedAt
+	}
+	return link
+}
+
+func (s *Server) handleFeeds(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := graph.FeedQuery{
+		Search:     q.Get("q"),
+		Subscribed: q.Get("subscribed") == "true",
+		Limit:      limitParam(r),
+		Offset:     intParam(r, "offset", 0),
+	}
+	feeds, total, err := s.g.ListFeeds(query)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	out := make([]Feed, 0, len(feeds))
+	for _, f := range feeds {
+		out = append(out, toFeed(f))
+	}
+	writeJSON(w, r, map[string]any{
+		"feeds":  out,
+		"total":  total,
+		"limit":  query.Limit,
+		"offset": query.Offset,
+	})
+}
+
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	feed, ok := s.feedParam(w, r)
+	if !ok {
+		return
+	}
+	tags, err := s.g.GetFeedTags(feed.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	out := toFeed(feed)
+	out.Tags = tags
+	writeJSON(w, r, out)
+}
+
+func (s *Server) handleLinks(get func(int64) ([]graph.LinkEdge, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		feed, ok := s.feedParam(w, r)
+		if !ok {
+			return
+		}
+		links, err := get(feed.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		out := make([]Link, 0, len(links))
+		for _, l := range links {
+			out = append(out, toLink(l))
+		}
+		writeJSON(w, r, map[string]any{"links": out})
+	}
+}
+
+// handleRank serves the rankings behind the CLI's rank and recommend commands,
+// chosen by the algorithm parameter: linked (default), new or recommended.
+func (s *Server) handleRank(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := limitParam(r)
+	filter, err := boolParam(r, "filter")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	out := []RankedFeed{}
+	switch algorithm := q.Get("algorithm"); algorithm {
+	case "", "linked":
+		starWeight, err := floatParam(r, "star_weight", 1)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		ranked, err := s.g.RankFeeds(graph.RankOptions{
+			Limit:      limit,
+			Category:   q.Get("category"),
+			StarWeight: &starWeight,
+			Filter:     filter,
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, rf := range ranked {
+			out = append(out, RankedFeed{Feed: toFeed(rf.Feed), InboundCount: rf.InboundCount, Score: rf.Score})
+		}
+	case "new":
+		ranked, err := s.g.GetNewFeeds(intParam(r, "days", 30), limit)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, rf := range ranked {
+			out = append(out, RankedFeed{Feed: toFeed(rf.Feed), InboundCount: rf.InboundCount})
+		}
+	case "recommended":
+		recs, err := s.g.GetRecommendations(filter)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		min := intParam(r, "min", 1)
+		for _, rec := range recs {
+			if rec.CitingFeeds < min {
+				continue
+			}
+			out = append(out, RankedFeed{Feed: toFeed(rec.Feed), InboundCount: rec.LinkCount, CitingFeeds: rec.CitingFeeds})
+			if len(out) == limit {
+				break
+			}
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "unknown algorithm: "+algorithm)
+		return
+	}
+
+	writeJSON(w, r, map[string]any{"feeds": out})
+}
+
+func (s *Server) handleMentions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	ranked, err := s.g.RankMentions(graph.MentionOptions{
+		EntityType: entityType(r),
+		Category:   q.Get("category"),
+		Limit:      limitParam(r),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	out := make([]Mention, 0, len(ranked))
+	for _, m := range ranked {
+		out = append(out, Mention{Name: m.Name, EntityType: m.EntityType, Count: m.MentionCount})
+	}
+	writeJSON(w, r, map[string]any{"mentions": out})
+}
+
+// handleRising compares two snapshots, defaulting to the latest two.
+func (s *Server) handleRising(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	current, previous := q.Get("current"), q.Get("previous")
+	if current == "" || previous == "" {
+		dates, err := s.g.GetSnapshotDates()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if len(dates) < 2 {
+			writeError(w, http.StatusNotFound, "need at least 2 snapshots")
+			return
+		}
+		current, previous = dates[0], dates[1]
+	}
+
+	rising, err := s.g.GetRisingMentions(entityType(r), current, previous, limitParam(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	out := make([]Mention, 0, len(rising))
+	for _, m := range rising {
+		previousCount, velocity := m.PreviousCount, m.Velocity
+		out = append(out, Mention{
+			Name:          m.Name,
+			EntityType:    m.EntityType,
+			Count:         m.CurrentCount,
+			PreviousCount: &previousCount,
+			Velocity:      &velocity,
+			Status:        m.Status,
+		})
+	}
+	writeJSON(w, r, map[string]any{
+		"current":  current,
+		"previous": previous,
+		"mentions": out,
+	})
+}
+
+func (s *Server) handleSnapshots(w http.ResponseWriter, r *http.Request) {
+	dates, err := s.g.GetSnapshotDates()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if dates == nil {
+		dates = []string{}
+	}
+	writeJSON(w, r, map[string]any{"dates": dates})
+}
+
+// feedParam loads the feed named by the {id} path parameter, writing a 404 if it doesn't exist.
+func (s *Server) feedParam(w http.ResponseWriter, r *http.Request) (*graph.FeedNode, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "feed not found")
+		return nil, false
+	}
+	feed, err := s.g.GetFeedByID(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if feed == nil {
+		writeError(w, http.StatusNotFound, "feed not found")
+		return nil, false
+	}
+	return feed, true
+}
+
+// writeJSON writes v with an ETag derived from its encoding, answering 304
+// when the client already has it.
+func writeJSON(w http.ResponseWriter, r *http.Request, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+	w.Write([]byte("\n"))
+}
+
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func entityType(r *http.Request) string {
+	if t := r.URL.Query().Get("type"); t != "" {
+		return strings.ToUpper(t)
+	}
+	return "PERSON"
+}
+
+func limitParam(r *http.Request) int {
+	limit := intParam(r, "limit", defaultLimit)
+	if limit <= 0 {
+		return defaultLimit
+	}
+	if limit > maxLimit {
+		return maxLimit
+	}
+	return limit
+}
+
+func intParam(r *http.Request, name string, def int) int {
+	v, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil || v < 0 {
+		return def
+	}
+	return v
+}
+
+func boolParam(r *http.Request, name string) (bool, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %q", name, v)
+	}
+	return b, nil
+}
+
+func floatParam(r *http.Request, name string, def float64) (float64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", name, v)
+	}
+	return f, nil
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *graph.Graph, map[string]int64) {
+	t.Helper()
+	g, err := graph.NewGraph(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewGraph error: %v", err)
+	}
+	t.Cleanup(func() { g.Close() })
+
+	ids := make(map[string]int64)
+	for _, f := range []*graph.FeedNode{
+		{URL: "https://a.com/feed", Title: "Alpha", Subscribed: true, Source: "manual"},
+		{URL: "https://b.com/feed", Title: "Beta", Subscribed: true, Source: "manual"},
+		{URL: "https://c.com/", Title: "Gamma"},
+	} {
+		id, err := g.AddFeed(f)
+		if err != nil {
+			t.Fatalf("AddFeed error: %v", err)
+		}
+		ids[f.Title] = id
+	}
+	g.AddFeedTag(ids["Alpha"], "Tech")
+
+	for _, l := range []*graph.LinkEdge{
+		{SourceID: ids["Alpha"], TargetID: ids["Gamma"], PostURL: "https://a.com/1", Starred: true},
+		{SourceID: ids["Beta"], TargetID: ids["Gamma"], PostURL: "https://b.com/1"},
+	} {
+		if err := g.AddLink(l); err != nil {
+			t.Fatalf("AddLink error: %v", err)
+		}
+	}
+	g.AddMention(&graph.Mention{SourceID: ids["Alpha"], Name: "Ada Lovelace", EntityType: "PERSON"})
+
+	srv := httptest.NewServer(New(g).Handler())
+	t.Cleanup(srv.Close)
+	return srv, g, ids
+}
+
+// getJSON fetches path and decodes the body into out, returning the response.
+func getJSON(t *testing.T, srv *httptest.Server, path string, out any) *http.Response {
+	t.Helper()
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s error: %v", path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("GET %s: invalid JSON: %v", path, err)
+		}
+	}
+	return resp
+}
+
+func TestAPI_Feeds(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	var page struct {
+		Feeds []Feed `json:"feeds"`
+		Total int    `json:"total"`
+	}
+	getJSON(t, srv, "/feeds?limit=2", &page)
+	if page.Total != 3 || len(page.Feeds) != 2 {
+		t.Errorf("Expected 2 of 3 feeds, got %d of %d", len(page.Feeds), page.Total)
+	}
+
+	getJSON(t, srv, "/feeds?limit=2&offset=2", &page)
+	if len(page.Feeds) != 1 || page.Feeds[0].Title != "Gamma" {
+		t.Errorf("Expected Gamma on the second page, got %+v", page.Feeds)
+	}
+
+	getJSON(t, srv, "/feeds?q=beta", &page)
+	if page.Total != 1 || page.Feeds[0].URL != "https://b.com/feed" {
+		t.Errorf("Expected search to find Beta, got %+v", page.Feeds)
+	}
+
+	getJSON(t, srv, "/feeds?subscribed=true", &page)
+	if page.Total != 2 {
+		t.Errorf("Expected 2 subscriptions, got %d", page.Total)
+	}
+}
+
+func TestAPI_Feed(t *testing.T) {
+	srv, _, ids := newTestServer(t)
+
+	var feed Feed
+	getJSON(t, srv, "/feeds/"+strconv.FormatInt(ids["Alpha"], 10), &feed)
+	if feed.Title != "Alpha" || !feed.Subscribed {
+		t.Errorf("Expected subscribed Alpha, got %+v", feed)
+	}
+	if len(feed.Tags) != 1 || feed.Tags[0] != "Tech" {
+		t.Errorf("Expected tag Tech, got %v", feed.Tags)
+	}
+
+	resp := getJSON(t, srv, "/feeds/999", nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown feed, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_Links(t *testing.T) {
+	srv, _, ids := newTestServer(t)
+
+	var body struct {
+		Links []Link `json:"links"`
+	}
+	getJSON(t, srv, "/feeds/"+strconv.FormatInt(ids["Gamma"], 10)+"/inbound", &body)
+	if len(body.Links) != 2 {
+		t.Errorf("Expected 2 inbound links, got %d", len(body.Links))
+	}
+
+	getJSON(t, srv, "/feeds/"+strconv.FormatInt(ids["Alpha"], 10)+"/outbound", &body)
+	if len(body.Links) != 1 || body.Links[0].TargetID != ids["Gamma"] || !body.Links[0].Starred {
+		t.Errorf("Expected starred link to Gamma, got %+v", body.Links)
+	}
+}
+
+func TestAPI_Rank(t *testing.T) {
+	srv, _, ids := newTestServer(t)
+
+	var body struct {
+		Feeds []RankedFeed `json:"feeds"`
+	}
+	getJSON(t, srv, "/rank?star_weight=3", &body)
+	if len(body.Feeds) != 1 || body.Feeds[0].Feed.ID != ids["Gamma"] {
+		t.Fatalf("Expected Gamma ranked, got %+v", body.Feeds)
+	}
+	if body.Feeds[0].InboundCount != 2 || body.Feeds[0].Score != 4 {
+		t.Errorf("Expected 2 links scoring 4, got %+v", body.Feeds[0])
+	}
+
+	getJSON(t, srv, "/rank?category=tech", &body)
+	if len(body.Feeds) != 1 || body.Feeds[0].InboundCount != 1 {
+		t.Errorf("Expected 1 link from Tech, got %+v", body.Feeds)
+	}
+
+	getJSON(t, srv, "/rank?algorithm=recommended&min=2", &body)
+	if len(body.Feeds) != 1 || body.Feeds[0].CitingFeeds != 2 {
+		t.Errorf("Expected Gamma recommended by 2 feeds, got %+v", body.Feeds)
+	}
+
+	getJSON(t, srv, "/rank?algorithm=new", &body)
+	if len(body.Feeds) != 3 {
+		t.Errorf("Expected 3 new feeds, got %d", len(body.Feeds))
+	}
+
+	for _, path := range []string{"/rank?algorithm=pagerank", "/rank?star_weight=x", "/rank?filter=maybe"} {
+		if resp := getJSON(t, srv, path, nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestAPI_MentionsAndRising(t *testing.T) {
+	srv, g, ids := newTestServer(t)
+
+	var mentions struct {
+		Mentions []Mention `json:"mentions"`
+	}
+	getJSON(t, srv, "/mentions?type=person", &mentions)
+	if len(mentions.Mentions) != 1 || mentions.Mentions[0].Name != "Ada Lovelace" {
+		t.Errorf("Expected Ada Lovelace, got %+v", mentions.Mentions)
+	}
+
+	if resp := getJSON(t, srv, "/rising", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 without snapshots, got %d", resp.StatusCode)
+	}
+
+	g.TakeSnapshot("2024-01-01")
+	g.AddMention(&graph.Mention{SourceID: ids["Beta"], Name: "Ada Lovelace", EntityType: "PERSON"})
+	g.TakeSnapshot("2024-01-08")
+
+	var snapshots struct {
+		Dates []string `json:"dates"`
+	}
+	getJSON(t, srv, "/snapshots", &snapshots)
+	if len(snapshots.Dates) != 2 || snapshots.Dates[0] != "2024-01-08" {
+		t.Errorf("Expected 2 snapshots newest first, got %v", snapshots.Dates)
+	}
+
+	var rising struct {
+		Current  string    `json:"current"`
+		Previous string    `json:"previous"`
+		Mentions []Mention `json:"mentions"`
+	}
+	getJSON(t, srv, "/rising", &rising)
+	if rising.Current != "2024-01-08" || rising.Previous != "2024-01-01" {
+		t.Errorf("Expected latest two snapshots, got %s vs %s", rising.Current, rising.Previous)
+	}
+	if len(rising.Mentions) != 1 || rising.Mentions[0].Count != 2 || *rising.Mentions[0].PreviousCount != 1 {
+		t.Errorf("Expected Ada rising 1 -> 2, got %+v", rising.Mentions)
+	}
+}
+
+func TestAPI_ETag(t *testing.T) {
+	srv, g, _ := newTestServer(t)
+
+	resp := getJSON(t, srv, "/feeds", nil)
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag header")
+	}
+
+	req, _ := http.NewRequest("GET", srv.URL+"/feeds", nil)
+	req.Header.Set("If-None-Match", etag)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304 for matching ETag, got %d", resp.StatusCode)
+	}
+
+	// A change to the graph changes the ETag
+	g.AddFeed(&graph.FeedNode{URL: "https://d.com/", Title: "Delta"})
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 after the graph changed, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_ReadOnly(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	resp, err := http.Post(srv.URL+"/feeds", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", resp.StatusCode)
+	}
+}
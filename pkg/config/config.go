@@ -0,0 +1,279 @@
+// Package config loads settings from a TOML config file, by default
+// ~/.rss-graph/config.toml.
+//
+// Top-level settings apply to every run. A [profiles.<name>] table
+// overrides any of them when its profile is selected, with the file's
+// profile key or the -profile flag:
+//
+//	db = "~/.rss-graph/graph.db"
+//	profile = "home"
+//
+//	[miniflux]
+//	url = "https://reader.example.com"
+//
+//	[profiles.work]
+//	db = "~/work/graph.db"
+//	source = "greader"
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/daniel-butler/rss-graph/pkg/ner"
+)
+
+// Config is every setting a config file can hold.
+type Config struct {
+	DB       string   `toml:"db"`
+	Source   string   `toml:"source"` // Reader backend: miniflux or greader
+	Miniflux Miniflux `toml:"miniflux"`
+	GReader  GReader  `toml:"greader"`
+	Fetcher  Fetcher  `toml:"fetcher"`
+	Filter   Filter   `toml:"filter"`
+	NER      NER      `toml:"ner"`
+	Rank     Rank     `toml:"rank"`
+	Crawl    Crawl    `toml:"crawl"`
+}
+
+// Miniflux holds Miniflux credentials.
+type Miniflux struct {
+	URL    string `toml:"url"`
+	APIKey string `toml:"api_key"`
+}
+
+// GReader holds Google Reader API credentials.
+type GReader struct {
+	URL      string `toml:"url"`
+	User     string `toml:"user"`
+	Password string `toml:"password"`
+}
+
+// Fetcher configures how feeds and sites are fetched.
+type Fetcher struct {
+	Timeout   time.Duration `toml:"timeout"`
+	UserAgent string        `toml:"user_agent"`
+	RateLimit time.Duration `toml:"rate_limit"` // Shortest time between requests to one host
+}
+
+// Filter holds the common domains, which the domain filters deny along
+// with their subdomains. Extra domains are added to the list rather than
+// replacing it.
+type Filter struct {
+	CommonDomains      []string `toml:"common_domains"`
+	ExtraCommonDomains []string `toml:"extra_common_domains,omitempty"`
+}
+
+// NER holds the names never counted as people. Extra names are added to
+// the list rather than replacing it.
+type NER struct {
+	NotPeople      []string `toml:"not_people"`
+	ExtraNotPeople []string `toml:"extra_not_people,omitempty"`
+}
+
+// Rank holds the defaults of the rank command.
+type Rank struct {
+	Limit      int     `toml:"limit"`
+	StarWeight float64 `toml:"star_weight"`
+	Filter     bool    `toml:"filter"`
+}
+
+// Crawl holds the defaults of the crawl command.
+type Crawl struct {
+	Entries int `toml:"entries"` // Entries to scan per feed on a first or full crawl
+}
+
+// defaultCommonDomains are sites too big or too generic to tell us
+// anything about the blogs that link to them.
+var defaultCommonDomains = []string{
+	"github.com",
+	"twitter.com",
+	"x.com",
+	"youtube.com",
+	"linkedin.com",
+	"huggingface.co",
+	"news.ycombinator.com",
+	"arxiv.org",
+	"nytimes.com",
+	"openai.com",
+	"anthropic.com",
+	"google.com",
+	"medium.com",
+	"substack.com",
+	"podcasts.apple.com",
+	"scholar.google.com",
+	"en.wikipedia.org",
+	"reddit.com",
+	"facebook.com",
+}
+
+// Dir returns the directory holding the database and config file.
+func Dir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".rss-graph")
+}
+
+// DefaultPath returns the config file used unless another is given.
+func DefaultPath() string {
+	return filepath.Join(Dir(), "config.toml")
+}
+
+// Default returns the settings used when nothing else sets them.
+func Default() *Config {
+	return &Config{
+		DB:     filepath.Join(Dir(), "graph.db"),
+		Source: "miniflux",
+		Fetcher: Fetcher{
+			Timeout:   30 * time.Second,
+			UserAgent: "rss-graph/1.0",
+		},
+		Filter: Filter{CommonDomains: slices.Clone(defaultCommonDomains)},
+		NER:    NER{NotPeople: ner.NotPeople()},
+		Rank:   Rank{Limit: 20, StarWeight: 1},
+		Crawl:  Crawl{Entries: 50},
+	}
+}
+
+// file is the layout of a config file: the settings, plus profiles that
+// override them.
+type file struct {
+	Config
+	Profile  string                    `toml:"profile"`
+	Profiles map[string]toml.Primitive `toml:"profiles"`
+}
+
+// Load reads the config file at path over the defaults, then the named
+// profile, or the file's own default profile if name is empty. It returns
+// the settings and the profile applied.
+func Load(path, name string) (*Config, string, error) {
+	f := file{Config: *Default()}
+	md, err := toml.DecodeFile(path, &f)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading config %s: %w", path, err)
+	}
+
+	// Decode every profile, so that mistakes in any of them are reported
+	profiles := make(map[string]*Config, len(f.Profiles))
+	for n, prim := range f.Profiles {
+		p := f.Config.clone()
+		if err := md.PrimitiveDecode(prim, p); err != nil {
+			return nil, "", fmt.Errorf("reading config %s: profile %s: %w", path, n, err)
+		}
+		profiles[n] = p
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, k := range undecoded {
+			keys[i] = k.String()
+		}
+		return nil, "", fmt.Errorf("reading config %s: unknown settings: %s", path, strings.Join(keys, ", "))
+	}
+
+	if name == "" {
+		name = f.Profile
+	}
+	c := &f.Config
+	if name != "" {
+		p, ok := profiles[name]
+		if !ok {
+			return nil, "", fmt.Errorf("config %s has no profile %q (have: %s)", path, name, strings.Join(profileNames(profiles), ", "))
+		}
+		c = p
+	}
+	c.normalize()
+	return c, name, nil
+}
+
+// LoadDefault is Load for the default config file, which may not exist.
+// Without it, the defaults apply and no profile can be selected.
+func LoadDefault(name string) (*Config, string, error) {
+	path := DefaultPath()
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if name != "" {
+			return nil, "", fmt.Errorf("no config file at %s to read profile %q from", path, name)
+		}
+		return Default(), "", nil
+	}
+	return Load(path, name)
+}
+
+func profileNames(profiles map[string]*Config) []string {
+	names := make([]string, 0, len(profiles))
+	for n := range profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// clone returns a copy of the settings that shares no lists with them, as
+// decoding a profile into it would otherwise overwrite them.
+func (c *Config) clone() *Config {
+	d := *c
+	d.Filter.CommonDomains = slices.Clone(c.Filter.CommonDomains)
+	d.Filter.ExtraCommonDomains = slices.Clone(c.Filter.ExtraCommonDomains)
+	d.NER.NotPeople = slices.Clone(c.NER.NotPeople)
+	d.NER.ExtraNotPeople = slices.Clone(c.NER.ExtraNotPeople)
+	return &d
+}
+
+// normalize folds the extra lists into the main ones and expands a
+// leading ~ in the database path.
+func (c *Config) normalize() {
+	c.Filter.CommonDomains = append(c.Filter.CommonDomains, c.Filter.ExtraCommonDomains...)
+	c.Filter.ExtraCommonDomains = nil
+	c.NER.NotPeople = append(c.NER.NotPeople, c.NER.ExtraNotPeople...)
+	c.NER.ExtraNotPeople = nil
+	if rest, ok := strings.CutPrefix(c.DB, "~/"); ok {
+		home, _ := os.UserHomeDir()
+		c.DB = filepath.Join(home, rest)
+	}
+}
+
+// ApplyEnv overrides settings with the environment variables that set
+// them, looked up with getenv.
+func (c *Config) ApplyEnv(getenv func(string) string) {
+	for _, v := range []struct {
+		name    string
+		setting *string
+	}{
+		{"RSS_GRAPH_DB", &c.DB},
+		{"RSS_GRAPH_SOURCE", &c.Source},
+		{"MINIFLUX_URL", &c.Miniflux.URL},
+		{"MINIFLUX_API_KEY", &c.Miniflux.APIKey},
+		{"GREADER_URL", &c.GReader.URL},
+		{"GREADER_USER", &c.GReader.User},
+		{"GREADER_PASSWORD", &c.GReader.Password},
+	} {
+		if value := getenv(v.name); value != "" {
+			*v.setting = value
+		}
+	}
+}
+
+// Redacted returns a copy of the settings with credentials masked.
+func (c *Config) Redacted() *Config {
+	r := *c
+	for _, secret := range []*string{&r.Miniflux.APIKey, &r.GReader.Password} {
+		if *secret != "" {
+			*secret = "********"
+		}
+	}
+	return &r
+}
+
+// Write writes the settings as TOML, in the layout of a config file.
+func (c *Config) Write(w io.Writer) error {
+	enc := toml.NewEncoder(w)
+	enc.Indent = ""
+	return enc.Encode(c)
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	return path
+}
+
+const testConfig = `
+db = "/data/graph.db"
+profile = "home"
+
+[miniflux]
+url = "https://reader.example.com"
+api_key = "k3y"
+
+[fetcher]
+timeout = "10s"
+rate_limit = "500ms"
+
+[filter]
+extra_common_domains = ["mastodon.social"]
+
+[rank]
+limit = 50
+
+[profiles.home]
+source = "miniflux"
+
+[profiles.work]
+db = "/work/graph.db"
+source = "greader"
+
+[profiles.work.greader]
+url = "https://freshrss.example.com/api/greader.php"
+user = "me"
+
+[profiles.work.filter]
+common_domains = ["example.org"]
+`
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, testConfig)
+
+	c, profile, err := Load(path, "")
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if profile != "home" {
+		t.Errorf("Expected the file's default profile, got %q", profile)
+	}
+	if c.DB != "/data/graph.db" || c.Source != "miniflux" || c.Miniflux.APIKey != "k3y" {
+		t.Errorf("Unexpected settings %+v", c)
+	}
+	if c.Fetcher.Timeout != 10*time.Second || c.Fetcher.RateLimit != 500*time.Millisecond {
+		t.Errorf("Expected durations parsed, got %+v", c.Fetcher)
+	}
+	if c.Fetcher.UserAgent != "rss-graph/1.0" || c.Crawl.Entries != 50 || c.Rank.StarWeight != 1 {
+		t.Errorf("Expected defaults for unset settings, got %+v", c)
+	}
+	if c.Rank.Limit != 50 {
+		t.Errorf("Expected rank limit 50, got %d", c.Rank.Limit)
+	}
+	domains := c.Filter.CommonDomains
+	if !slices.Contains(domains, "github.com") || !slices.Contains(domains, "mastodon.social") {
+		t.Errorf("Expected extra domains added to the defaults, got %v", domains)
+	}
+	if len(c.NER.NotPeople) == 0 {
+		t.Error("Expected the default NER blocklist")
+	}
+}
+
+func TestLoad_Profile(t *testing.T) {
+	path := writeConfig(t, testConfig)
+
+	c, profile, err := Load(path, "work")
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if profile != "work" || c.DB != "/work/graph.db" || c.Source != "greader" || c.GReader.User != "me" {
+		t.Errorf("Expected the work profile applied, got %q %+v", profile, c)
+	}
+	if c.Miniflux.URL != "https://reader.example.com" || c.Rank.Limit != 50 {
+		t.Errorf("Expected top-level settings the profile doesn't set kept, got %+v", c)
+	}
+	if !slices.Equal(c.Filter.CommonDomains, []string{"example.org", "mastodon.social"}) {
+		t.Errorf("Expected the profile's domains plus the extras, got %v", c.Filter.CommonDomains)
+	}
+
+	// The work profile's list mustn't leak into the others
+	c, _, err = Load(path, "home")
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if len(c.Filter.CommonDomains) != len(defaultCommonDomains)+1 || c.Filter.CommonDomains[0] != defaultCommonDomains[0] {
+		t.Errorf("Expected the default domains plus the extra, got %v", c.Filter.CommonDomains)
+	}
+
+	if _, _, err := Load(path, "play"); err == nil || !strings.Contains(err.Error(), "home, work") {
+		t.Errorf("Expected an error listing the profiles, got %v", err)
+	}
+}
+
+func TestLoad_UnknownSetting(t *testing.T) {
+	path := writeConfig(t, "[fetcher]\ntimout = \"5s\"\n\n[profiles.x]\nsorce = \"greader\"\n")
+
+	_, _, err := Load(path, "")
+	if err == nil {
+		t.Fatal("Expected an error for unknown settings")
+	}
+	if !strings.Contains(err.Error(), "fetcher.timout") || !strings.Contains(err.Error(), "sorce") {
+		t.Errorf("Expected the misspelled keys named, got %v", err)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	c := Default()
+	c.Miniflux.URL = "https://from-config.example.com"
+	env := map[string]string{
+		"MINIFLUX_URL": "https://from-env.example.com",
+		"RSS_GRAPH_DB": "/env/graph.db",
+	}
+	c.ApplyEnv(func(name string) string { return env[name] })
+
+	if c.Miniflux.URL != "https://from-env.example.com" || c.DB != "/env/graph.db" {
+		t.Errorf("Expected the environment to override the config, got %+v", c)
+	}
+	if c.Source != "miniflux" {
+		t.Errorf("Expected unset variables to leave settings alone, got %q", c.Source)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	c := Default()
+	c.Miniflux.APIKey = "k3y"
+
+	var buf bytes.Buffer
+	if err := c.Redacted().Write(&buf); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if strings.Contains(buf.String(), "k3y") || !strings.Contains(buf.String(), `api_key = "********"`) {
+		t.Errorf("Expected the API key masked, got:\n%s", buf.String())
+	}
+	if c.Miniflux.APIKey != "k3y" {
+		t.Error("Expected Redacted to leave the original alone")
+	}
+
+	// What Write prints loads back as the same settings
+	path := writeConfig(t, buf.String())
+	loaded, _, err := Load(path, "")
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if loaded.Fetcher.Timeout != c.Fetcher.Timeout || !slices.Equal(loaded.Filter.CommonDomains, c.Filter.CommonDomains) {
+		t.Errorf("Expected a round trip, got %+v", loaded)
+	}
+}
@@ -0,0 +1,176 @@
+// Package discover finds the RSS and Atom feeds advertised by websites.
+package discover
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/daniel-butler/rss-graph/pkg/feed"
+	"github.com/daniel-butler/rss-graph/pkg/fetcher"
+)
+
+// Feed is a feed found on a website.
+type Feed struct {
+	URL   string
+	Title string
+	Type  string // rss, atom
+}
+
+// ErrNoFeed is returned when a site advertises no feed.
+var ErrNoFeed = errors.New("no feed found")
+
+var (
+	linkTagRegex = regexp.MustCompile(`(?is)<link\s[^>]*>`)
+	meTagRegex   = regexp.MustCompile(`(?is)<(?:a|link)\s[^>]*>`)
+	metaTagRegex = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attrRegex    = regexp.MustCompile(`(?is)([a-z-]+)\s*=\s*["']([^"']*)["']`)
+)
+
+// Identity is what a homepage says about who runs the site.
+type Identity struct {
+	Author string   // From <meta name="author">
+	Me     []string // Other profiles of the author, from rel="me" links
+}
+
+// feedTypes maps advertised MIME types to feed kinds.
+var feedTypes = map[string]string{
+	"application/rss+xml":   "rss",
+	"application/atom+xml":  "atom",
+	"application/feed+json": "json",
+}
+
+// commonPaths are tried when a page advertises no feed.
+var commonPaths = []string{
+	"/feed",
+	"/feed.xml",
+	"/rss.xml",
+	"/atom.xml",
+	"/index.xml",
+	"/rss",
+}
+
+// FeedLinks returns the feeds advertised by <link rel="alternate"> tags in
+// an HTML page, resolved against baseURL.
+func FeedLinks(html []byte, baseURL string) []Feed {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var feeds []Feed
+	for _, tag := range linkTagRegex.FindAll(html, -1) {
+		attrs := tagAttrs(tag)
+
+		if !hasToken(attrs["rel"], "alternate") {
+			continue
+		}
+		kind, ok := feedTypes[strings.ToLower(attrs["type"])]
+		if !ok || kind == "json" || attrs["href"] == "" {
+			continue
+		}
+
+		ref, err := url.Parse(attrs["href"])
+		if err != nil {
+			continue
+		}
+		feedURL := base.ResolveReference(ref).String()
+		if seen[feedURL] {
+			continue
+		}
+		seen[feedURL] = true
+
+		feeds = append(feeds, Feed{URL: feedURL, Title: attrs["title"], Type: kind})
+	}
+	return feeds
+}
+
+// ParseIdentity returns the author and rel="me" links of an HTML page,
+// with links resolved against baseURL.
+func ParseIdentity(html []byte, baseURL string) Identity {
+	var id Identity
+	for _, tag := range metaTagRegex.FindAll(html, -1) {
+		attrs := tagAttrs(tag)
+		if strings.EqualFold(attrs["name"], "author") && attrs["content"] != "" {
+			id.Author = attrs["content"]
+			break
+		}
+	}
+
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return id
+	}
+	seen := make(map[string]bool)
+	for _, tag := range meTagRegex.FindAll(html, -1) {
+		attrs := tagAttrs(tag)
+		if !hasToken(attrs["rel"], "me") || attrs["href"] == "" {
+			continue
+		}
+		ref, err := url.Parse(attrs["href"])
+		if err != nil {
+			continue
+		}
+		me := base.ResolveReference(ref).String()
+		if !seen[me] {
+			seen[me] = true
+			id.Me = append(id.Me, me)
+		}
+	}
+	return id
+}
+
+// Discover returns the feeds for a site. If the URL is itself a feed it is
+// returned as-is; otherwise the page's advertised feeds are used, falling
+// back to probing common feed paths.
+func Discover(f *fetcher.Fetcher, siteURL string) ([]Feed, error) {
+	data, err := f.Fetch(siteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed, err := feed.ParseFeed(data); err == nil {
+		return []Feed{{URL: siteURL, Title: parsed.Title}}, nil
+	}
+
+	if feeds := FeedLinks(data, siteURL); len(feeds) > 0 {
+		return feeds, nil
+	}
+
+	base, err := url.Parse(siteURL)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range commonPaths {
+		candidate := base.ResolveReference(&url.URL{Path: path}).String()
+		data, err := f.Fetch(candidate)
+		if err != nil {
+			continue
+		}
+		if parsed, err := feed.ParseFeed(data); err == nil {
+			return []Feed{{URL: candidate, Title: parsed.Title}}, nil
+		}
+	}
+
+	return nil, ErrNoFeed
+}
+
+// tagAttrs returns the quoted attributes of an HTML tag by lowercased name.
+func tagAttrs(tag []byte) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range attrRegex.FindAllSubmatch(tag, -1) {
+		attrs[strings.ToLower(string(m[1]))] = strings.TrimSpace(string(m[2]))
+	}
+	return attrs
+}
+
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(strings.ToLower(list)) {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
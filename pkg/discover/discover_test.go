@@ -0,0 +1,138 @@
+package discover
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daniel-butler/rss-graph/pkg/fetcher"
+)
+
+const atomFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Julia Evans</title>
+  <link href="https://jvns.ca/"/>
+</feed>`
+
+func TestFeedLinks(t *testing.T) {
+	html := `<html><head>
+	<link rel="stylesheet" href="/style.css">
+	<link rel="alternate" type="application/rss+xml" title="RSS" href="/feed.xml">
+	<link type="application/atom+xml" rel="alternate" href="https://example.com/atom.xml" title="Atom"/>
+	<link rel="alternate" type="application/feed+json" href="/feed.json">
+	<link rel="alternate" hreflang="de" href="/de/">
+	</head></html>`
+
+	feeds := FeedLinks([]byte(html), "https://example.com/blog/")
+
+	if len(feeds) != 2 {
+		t.Fatalf("Expected 2 feeds, got %d: %v", len(feeds), feeds)
+	}
+	if feeds[0].URL != "https://example.com/feed.xml" || feeds[0].Type != "rss" {
+		t.Errorf("Expected resolved RSS feed, got %+v", feeds[0])
+	}
+	if feeds[1].URL != "https://example.com/atom.xml" || feeds[1].Type != "atom" {
+		t.Errorf("Expected Atom feed, got %+v", feeds[1])
+	}
+}
+
+func TestParseIdentity(t *testing.T) {
+	html := `<html><head>
+	<meta charset="utf-8">
+	<meta name="Author" content="Julia Evans">
+	<link rel="me" href="https://social.example/@b0rk">
+	<link rel="stylesheet" href="/style.css">
+	</head><body>
+	<a href="/about">About</a>
+	<a class="u-url" rel="me noopener" href="https://github.com/jvns">GitHub</a>
+	<a rel="me" href="https://social.example/@b0rk">Mastodon</a>
+	<a rel="me" href="/cv">CV</a>
+	</body></html>`
+
+	id := ParseIdentity([]byte(html), "https://jvns.ca/")
+
+	if id.Author != "Julia Evans" {
+		t.Errorf("Expected the meta author, got %q", id.Author)
+	}
+	want := []string{"https://social.example/@b0rk", "https://github.com/jvns", "https://jvns.ca/cv"}
+	if len(id.Me) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, id.Me)
+	}
+	for i := range want {
+		if id.Me[i] != want[i] {
+			t.Errorf("Expected rel=me link %q, got %q", want[i], id.Me[i])
+		}
+	}
+}
+
+func TestDiscover_AdvertisedFeed(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><link rel="alternate" type="application/atom+xml" href="/atom.xml"></head></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	feeds, err := Discover(fetcher.New(), server.URL+"/")
+	if err != nil {
+		t.Fatalf("Discover error: %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].URL != server.URL+"/atom.xml" {
+		t.Errorf("Expected advertised feed, got %v", feeds)
+	}
+}
+
+func TestDiscover_FeedURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(atomFeed))
+	}))
+	defer server.Close()
+
+	feeds, err := Discover(fetcher.New(), server.URL+"/atom.xml")
+	if err != nil {
+		t.Fatalf("Discover error: %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].Title != "Julia Evans" {
+		t.Errorf("Expected the URL itself as the feed, got %v", feeds)
+	}
+}
+
+func TestDiscover_CommonPath(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`<html><body>No feed links here</body></html>`))
+	})
+	mux.HandleFunc("/index.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(atomFeed))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	feeds, err := Discover(fetcher.New(), server.URL+"/")
+	if err != nil {
+		t.Fatalf("Discover error: %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].URL != server.URL+"/index.xml" {
+		t.Errorf("Expected probed feed, got %v", feeds)
+	}
+}
+
+func TestDiscover_NoFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`<html><body>Nothing</body></html>`))
+	}))
+	defer server.Close()
+
+	_, err := Discover(fetcher.New(), server.URL+"/")
+	if err != ErrNoFeed {
+		t.Errorf("Expected ErrNoFeed, got %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+// Package export writes the feed graph in formats read by graph tools:
+// GraphML and GEXF (Gephi, Cytoscape), DOT (Graphviz) and Cytoscape.js JSON.
+//
+// Nodes carry the feed's title, URL, inbound link count and subscription
+// status; edge weights are the number of posts linking one feed to another.
+package export
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+)
+
+// Formats lists the supported format names.
+var Formats = []string{"graphml", "gexf", "dot", "cytoscape-json"}
+
+// Write exports n in the named format.
+func Write(w io.Writer, format string, n *graph.Network) error {
+	switch format {
+	case "graphml":
+		return GraphML(w, n)
+	case "gexf":
+		return GEXF(w, n)
+	case "dot":
+		return DOT(w, n)
+	case "cytoscape-json":
+		return CytoscapeJSON(w, n)
+	}
+	return fmt.Errorf("unknown export format: %s (want %s)", format, strings.Join(Formats, ", "))
+}
+
+func nodeID(id int64) string {
+	return "n" + strconv.FormatInt(id, 10)
+}
+
+type xmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLNode struct {
+	ID   string    `xml:"id,attr"`
+	Data []xmlData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string    `xml:"id,attr"`
+	Source string    `xml:"source,attr"`
+	Target string    `xml:"target,attr"`
+	Data   []xmlData `xml:"data"`
+}
+
+type graphMLDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+// GraphML writes n as a directed GraphML graph.
+func GraphML(w io.Writer, n *graph.Network) error {
+	doc := graphMLDoc{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "title", For: "node", Name: "title", Type: "string"},
+			{ID: "url", For: "node", Name: "url", Type: "string"},
+			{ID: "inbound", For: "node", Name: "inbound", Type: "int"},
+			{ID: "subscribed", For: "node", Name: "subscribed", Type: "boolean"},
+			{ID: "weight", For: "edge", Name: "weight", Type: "int"},
+		},
+	}
+	doc.Graph.ID = "rss-graph"
+	doc.Graph.EdgeDefault = "directed"
+
+	for _, id := range n.NodeIDs() {
+		f := n.Nodes[id]
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: nodeID(id),
+			Data: []xmlData{
+				{Key: "title", Value: f.Title},
+				{Key: "url", Value: f.URL},
+				{Key: "inbound", Value: strconv.Itoa(n.Inbound[id])},
+				{Key: "subscribed", Value: strconv.FormatBool(f.Subscribed)},
+			},
+		})
+	}
+	for i, e := range n.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     "e" + strconv.Itoa(i),
+			Source: nodeID(e.SourceID),
+			Target: nodeID(e.TargetID),
+			Data:   []xmlData{{Key: "weight", Value: strconv.Itoa(e.Weight)}},
+		})
+	}
+	return writeXML(w, doc)
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	Start     string         `xml:"start,attr,omitempty"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID     string  `xml:"id,attr"`
+	Source string  `xml:"source,attr"`
+	Target string  `xml:"target,attr"`
+	Weight float64 `xml:"weight,attr"`
+	Start  string  `xml:"start,attr,omitempty"`
+}
+
+type gexfDoc struct {
+	XMLName xml.Name `xml:"gexf"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Version string   `xml:"version,attr"`
+	Meta    struct {
+		Creator string `xml:"creator"`
+	} `xml:"meta"`
+	Graph struct {
+		Mode            string `xml:"mode,attr"`
+		DefaultEdgeType string `xml:"defaultedgetype,attr"`
+		TimeFormat      string `xml:"timeformat,attr"`
+		Attributes      struct {
+			Class      string          `xml:"class,attr"`
+			Attributes []gexfAttribute `xml:"attribute"`
+		} `xml:"attributes"`
+		Nodes []gexfNode `xml:"nodes>node"`
+		Edges []gexfEdge `xml:"edges>edge"`
+	} `xml:"graph"`
+}
+
+// GEXF writes n as a dynamic GEXF 1.3 graph. Each edge starts at its
+// earliest link, and each node at its creation or its earliest link,
+// whichever came first, so Gephi's timeline replays how the graph grew.
+func GEXF(w io.Writer, n *graph.Network) error {
+	doc := gexfDoc{Xmlns: "http://gexf.net/1.3", Version: "1.3"}
+	doc.Meta.Creator = "rss-graph"
+	doc.Graph.Mode = "dynamic"
+	doc.Graph.DefaultEdgeType = "directed"
+	doc.Graph.TimeFormat = "datetime"
+	doc.Graph.Attributes.Class = "node"
+	doc.Graph.Attributes.Attributes = []gexfAttribute{
+		{ID: "url", Title: "url", Type: "string"},
+		{ID: "inbound", Title: "inbound", Type: "integer"},
+		{ID: "subscribed", Title: "subscribed", Type: "boolean"},
+	}
+
+	starts := make(map[int64]time.Time)
+	for id, f := range n.Nodes {
+		starts[id] = f.CreatedAt
+	}
+	for _, e := range n.Edges {
+		for _, id := range []int64{e.SourceID, e.TargetID} {
+			if s := starts[id]; s.IsZero() || e.FirstSeen.Before(s) {
+				starts[id] = e.FirstSeen
+			}
+		}
+	}
+
+	for _, id := range n.NodeIDs() {
+		f := n.Nodes[id]
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gexfNode{
+			ID:    nodeID(id),
+			Label: label(f),
+			Start: gexfTime(starts[id]),
+			AttValues: []gexfAttValue{
+				{For: "url", Value: f.URL},
+				{For: "inbound", Value: strconv.Itoa(n.Inbound[id])},
+				{For: "subscribed", Value: strconv.FormatBool(f.Subscribed)},
+			},
+		})
+	}
+	for i, e := range n.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, gexfEdge{
+			ID:     "e" + strconv.Itoa(i),
+			Source: nodeID(e.SourceID),
+			Target: nodeID(e.TargetID),
+			Weight: float64(e.Weight),
+			Start:  gexfTime(e.FirstSeen),
+		})
+	}
+	return writeXML(w, doc)
+}
+
+func gexfTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func writeXML(w io.Writer, doc any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// DOT writes n as a Graphviz digraph. Subscriptions are filled, and edge
+// pen width grows with weight.
+func DOT(w io.Writer, n *graph.Network) error {
+	var b strings.Builder
+	b.WriteString("digraph \"rss-graph\" {\n")
+	b.WriteString("  node [shape=box, style=rounded];\n")
+	for _, id := range n.NodeIDs() {
+		f := n.Nodes[id]
+		fmt.Fprintf(&b, "  %s [label=%s, URL=%s, inbound=%d, subscribed=%t",
+			nodeID(id), dotQuote(label(f)), dotQuote(f.URL), n.Inbound[id], f.Subscribed)
+		if f.Subscribed {
+			b.WriteString(`, style="rounded,filled", fillcolor=lightblue`)
+		}
+		b.WriteString("];\n")
+	}
+	for _, e := range n.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [weight=%d, penwidth=%d];\n",
+			nodeID(e.SourceID), nodeID(e.TargetID), e.Weight, min(e.Weight, 8))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+type cytoscapeElement struct {
+	Data map[string]any `json:"data"`
+}
+
+// CytoscapeJSON writes n in the Cytoscape.js elements format, which
+// Cytoscape desktop also imports.
+func CytoscapeJSON(w io.Writer, n *graph.Network) error {
+	var doc struct {
+		Elements struct {
+			Nodes []cytoscapeElement `json:"nodes"`
+			Edges []cytoscapeElement `json:"edges"`
+		} `json:"elements"`
+	}
+	doc.Elements.Nodes = []cytoscapeElement{}
+	doc.Elements.Edges = []cytoscapeElement{}
+
+	for _, id := range n.NodeIDs() {
+		f := n.Nodes[id]
+		doc.Elements.Nodes = append(doc.Elements.Nodes, cytoscapeElement{Data: map[string]any{
+			"id":         nodeID(id),
+			"label":      label(f),
+			"title":      f.Title,
+			"url":        f.URL,
+			"inbound":    n.Inbound[id],
+			"subscribed": f.Subscribed,
+		}})
+	}
+	for i, e := range n.Edges {
+		doc.Elements.Edges = append(doc.Elements.Edges, cytoscapeElement{Data: map[string]any{
+			"id":     "e" + strconv.Itoa(i),
+			"source": nodeID(e.SourceID),
+			"target": nodeID(e.TargetID),
+			"weight": e.Weight,
+		}})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// label is the display name for a node, falling back to its URL.
+func label(f *graph.FeedNode) string {
+	if f.Title != "" {
+		return f.Title
+	}
+	return f.URL
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+)
+
+func testNetwork() *graph.Network {
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	return &graph.Network{
+		Nodes: map[int64]*graph.FeedNode{
+			1: {ID: 1, URL: "https://a.com/feed", Title: `Alpha "A" & co`, Subscribed: true, CreatedAt: created},
+			2: {ID: 2, URL: "https://b.com/", CreatedAt: created},
+		},
+		Edges: []graph.NetworkEdge{{
+			SourceID:  1,
+			TargetID:  2,
+			Weight:    3,
+			FirstSeen: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+			LastSeen:  time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		Inbound: map[int64]int{2: 3},
+	}
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, "svg", testNetwork()); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestGraphML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "graphml", testNetwork()); err != nil {
+		t.Fatalf("GraphML error: %v", err)
+	}
+
+	var doc graphMLDoc
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid XML: %v\n%s", err, buf.String())
+	}
+	if len(doc.Graph.Nodes) != 2 || len(doc.Graph.Edges) != 1 {
+		t.Fatalf("Expected 2 nodes and 1 edge, got %d and %d", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+	if doc.Graph.Nodes[0].Data[0].Value != `Alpha "A" & co` {
+		t.Errorf("Expected title to round-trip, got %q", doc.Graph.Nodes[0].Data[0].Value)
+	}
+	if doc.Graph.Nodes[1].Data[2].Value != "3" {
+		t.Errorf("Expected inbound 3 on b, got %q", doc.Graph.Nodes[1].Data[2].Value)
+	}
+	e := doc.Graph.Edges[0]
+	if e.Source != "n1" || e.Target != "n2" || e.Data[0].Value != "3" {
+		t.Errorf("Expected n1 -> n2 with weight 3, got %+v", e)
+	}
+}
+
+func TestGEXF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "gexf", testNetwork()); err != nil {
+		t.Fatalf("GEXF error: %v", err)
+	}
+
+	var doc gexfDoc
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid XML: %v\n%s", err, buf.String())
+	}
+	if doc.Graph.Mode != "dynamic" {
+		t.Errorf("Expected dynamic graph, got %q", doc.Graph.Mode)
+	}
+	if len(doc.Graph.Edges) != 1 || doc.Graph.Edges[0].Weight != 3 {
+		t.Fatalf("Expected 1 edge with weight 3, got %+v", doc.Graph.Edges)
+	}
+	if doc.Graph.Edges[0].Start != "2023-02-01T00:00:00Z" {
+		t.Errorf("Expected edge to start at its first link, got %q", doc.Graph.Edges[0].Start)
+	}
+	// Nodes start no later than their first edge, even if added to the graph afterwards
+	for _, n := range doc.Graph.Nodes {
+		if n.Start != "2023-02-01T00:00:00Z" {
+			t.Errorf("Expected %s to start at 2023-02-01, got %q", n.ID, n.Start)
+		}
+	}
+	if doc.Graph.Nodes[1].Label != "https://b.com/" {
+		t.Errorf("Expected untitled node labeled by URL, got %q", doc.Graph.Nodes[1].Label)
+	}
+}
+
+func TestDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "dot", testNetwork()); err != nil {
+		t.Fatalf("DOT error: %v", err)
+	}
+	out := buf.String()
+
+	if !strings.HasPrefix(out, `digraph "rss-graph" {`) || !strings.HasSuffix(out, "}\n") {
+		t.Errorf("Expected a digraph, got:\n%s", out)
+	}
+	for _, want := range []string{
+		`n1 [label="Alpha \"A\" & co", URL="https://a.com/feed", inbound=0, subscribed=true`,
+		`n2 [label="https://b.com/", URL="https://b.com/", inbound=3, subscribed=false];`,
+		`n1 -> n2 [weight=3, penwidth=3];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected DOT to contain %s\ngot:\n%s", want, out)
+		}
+	}
+}
+
+func TestCytoscapeJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "cytoscape-json", testNetwork()); err != nil {
+		t.Fatalf("CytoscapeJSON error: %v", err)
+	}
+
+	var doc struct {
+		Elements struct {
+			Nodes []struct {
+				Data map[string]any `json:"data"`
+			} `json:"nodes"`
+			Edges []struct {
+				Data map[string]any `json:"data"`
+			} `json:"edges"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(doc.Elements.Nodes) != 2 || len(doc.Elements.Edges) != 1 {
+		t.Fatalf("Expected 2 nodes and 1 edge, got %d and %d", len(doc.Elements.Nodes), len(doc.Elements.Edges))
+	}
+	if doc.Elements.Nodes[0].Data["subscribed"] != true || doc.Elements.Nodes[1].Data["inbound"] != float64(3) {
+		t.Errorf("Unexpected node data: %v", doc.Elements.Nodes)
+	}
+	if doc.Elements.Edges[0].Data["source"] != "n1" || doc.Elements.Edges[0].Data["weight"] != float64(3) {
+		t.Errorf("Unexpected edge data: %v", doc.Elements.Edges[0].Data)
+	}
+}
@@ -2,6 +2,7 @@
 package extractor
 
 import (
+	"html"
 	"regexp"
 	"strings"
 )
@@ -60,3 +61,17 @@ func ExtractLinks(html string) []Link {
 
 	return links
 }
+
+// blockRegex matches elements whose content isn't readable text.
+var blockRegex = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+
+// tagRegex matches any HTML tag.
+var tagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// Text returns the readable text of HTML content, with tags removed,
+// entities decoded and whitespace collapsed.
+func Text(content string) string {
+	content = blockRegex.ReplaceAllString(content, " ")
+	content = tagRegex.ReplaceAllString(content, " ")
+	return strings.Join(strings.Fields(html.UnescapeString(content)), " ")
+}
@@ -128,3 +128,16 @@ func TestExtractLinks_ExtractsFromNestedHTML(t *testing.T) {
 		t.Errorf("Wrong URL extracted")
 	}
 }
+
+func TestText(t *testing.T) {
+	html := `<style>p { color: red }</style>
+		<p>Read <a href="https://jvns.ca/">Julia&#39;s   post</a>
+		on <em>DNS</em> &amp; TLS.</p><script>alert("hi")</script>`
+
+	if text := Text(html); text != "Read Julia's post on DNS & TLS." {
+		t.Errorf("Unexpected text: %q", text)
+	}
+	if text := Text(""); text != "" {
+		t.Errorf("Expected empty text, got %q", text)
+	}
+}
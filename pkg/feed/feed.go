@@ -5,27 +5,60 @@ import (
 	"encoding/xml"
 	"errors"
 	"html"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/daniel-butler/rss-graph/pkg/extractor"
 )
 
 // Feed represents a parsed RSS or Atom feed.
 type Feed struct {
-	Title string
-	URL   string
-	Items []Item
+	Title        string
+	URL          string
+	Items        []Item
+	TTL          time.Duration // RSS <ttl>; zero if absent
+	UpdatePeriod time.Duration // sy:updatePeriod over sy:updateFrequency; zero if absent
+	Hubs         []string      // WebSub hubs from <link rel="hub">
+	Self         string        // The feed's canonical URL from <link rel="self">
 }
 
 // Item represents a single entry in a feed.
 type Item struct {
 	Title          string
 	URL            string
+	GUID           string // RSS guid or Atom id
+	Author         string // RSS author or dc:creator, or Atom author name
 	Description    string
 	Content        string
+	PublishedAt    time.Time // Zero if missing or unparseable
+	UpdatedAt      time.Time // Atom only
 	ExtractedLinks []extractor.Link
 }
 
+// updatePeriods are the sy:updatePeriod values of the RSS 1.0 Syndication
+// module, which RSS 2.0 and Atom feeds also use to say how often they update.
+var updatePeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// parseUpdatePeriod converts sy:updatePeriod and sy:updateFrequency, the
+// number of updates per period, into the time between updates.
+func parseUpdatePeriod(period, frequency string) time.Duration {
+	d, ok := updatePeriods[strings.ToLower(strings.TrimSpace(period))]
+	if !ok {
+		return 0
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(frequency)); err == nil && n > 1 {
+		d /= time.Duration(n)
+	}
+	return d
+}
+
 // RSS 2.0 structures
 type rss2Feed struct {
 	XMLName xml.Name    `xml:"rss"`
@@ -33,25 +66,37 @@ type rss2Feed struct {
 }
 
 type rss2Channel struct {
-	Title       string     `xml:"title"`
-	Link        string     `xml:"link"`
-	Description string     `xml:"description"`
-	Items       []rss2Item `xml:"item"`
+	// Before Link, which would otherwise also match atom:link
+	AtomLinks       []atomLink `xml:"http://www.w3.org/2005/Atom link"`
+	Title           string     `xml:"title"`
+	Link            string     `xml:"link"`
+	Description     string     `xml:"description"`
+	TTL             string     `xml:"ttl"`
+	UpdatePeriod    string     `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string     `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	Items           []rss2Item `xml:"item"`
 }
 
 type rss2Item struct {
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	Author      string `xml:"author"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	PubDate     string `xml:"pubDate"`
 	Description string `xml:"description"`
 	Content     string `xml:"encoded"`
 }
 
 // Atom structures
 type atomFeed struct {
-	XMLName xml.Name    `xml:"feed"`
-	Title   string      `xml:"title"`
-	Links   []atomLink  `xml:"link"`
-	Entries []atomEntry `xml:"entry"`
+	XMLName         xml.Name    `xml:"feed"`
+	Title           string      `xml:"title"`
+	Links           []atomLink  `xml:"link"`
+	Author          atomPerson  `xml:"author"`
+	UpdatePeriod    string      `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string      `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	Entries         []atomEntry `xml:"entry"`
 }
 
 type atomLink struct {
@@ -59,11 +104,20 @@ type atomLink struct {
 	Rel  string `xml:"rel,attr"`
 }
 
+// atomPerson is an Atom author.
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
 type atomEntry struct {
-	Title   string     `xml:"title"`
-	Links   []atomLink `xml:"link"`
-	Content string     `xml:"content"`
-	Summary string     `xml:"summary"`
+	Title     string     `xml:"title"`
+	ID        string     `xml:"id"`
+	Author    atomPerson `xml:"author"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Links     []atomLink `xml:"link"`
+	Content   string     `xml:"content"`
+	Summary   string     `xml:"summary"`
 }
 
 // ParseFeed parses RSS 2.0 or Atom feed data.
@@ -89,10 +143,15 @@ func ParseFeed(data []byte) (*Feed, error) {
 
 func parseRSS2(rss *rss2Feed) *Feed {
 	feed := &Feed{
-		Title: rss.Channel.Title,
-		URL:   rss.Channel.Link,
-		Items: make([]Item, 0, len(rss.Channel.Items)),
+		Title:        rss.Channel.Title,
+		URL:          rss.Channel.Link,
+		Items:        make([]Item, 0, len(rss.Channel.Items)),
+		UpdatePeriod: parseUpdatePeriod(rss.Channel.UpdatePeriod, rss.Channel.UpdateFrequency),
+	}
+	if minutes, err := strconv.Atoi(strings.TrimSpace(rss.Channel.TTL)); err == nil && minutes > 0 {
+		feed.TTL = time.Duration(minutes) * time.Minute
 	}
+	feed.Hubs, feed.Self = webSubLinks(rss.Channel.AtomLinks)
 
 	for _, item := range rss.Channel.Items {
 		content := item.Content
@@ -106,10 +165,16 @@ func parseRSS2(rss *rss2Feed) *Feed {
 		feedItem := Item{
 			Title:          item.Title,
 			URL:            item.Link,
+			GUID:           strings.TrimSpace(item.GUID),
+			Author:         strings.TrimSpace(item.Creator),
 			Description:    item.Description,
 			Content:        content,
+			PublishedAt:    parseDate(item.PubDate),
 			ExtractedLinks: extractor.ExtractLinks(decodedContent),
 		}
+		if feedItem.Author == "" {
+			feedItem.Author = strings.TrimSpace(item.Author)
+		}
 		feed.Items = append(feed.Items, feedItem)
 	}
 
@@ -130,10 +195,12 @@ func parseAtom(atom *atomFeed) *Feed {
 	}
 
 	feed := &Feed{
-		Title: atom.Title,
-		URL:   strings.TrimSuffix(feedURL, "/"),
-		Items: make([]Item, 0, len(atom.Entries)),
+		Title:        atom.Title,
+		URL:          strings.TrimSuffix(feedURL, "/"),
+		Items:        make([]Item, 0, len(atom.Entries)),
+		UpdatePeriod: parseUpdatePeriod(atom.UpdatePeriod, atom.UpdateFrequency),
 	}
+	feed.Hubs, feed.Self = webSubLinks(atom.Links)
 
 	for _, entry := range atom.Entries {
 		// Find entry link
@@ -159,12 +226,57 @@ func parseAtom(atom *atomFeed) *Feed {
 		feedItem := Item{
 			Title:          entry.Title,
 			URL:            entryURL,
+			GUID:           strings.TrimSpace(entry.ID),
+			Author:         strings.TrimSpace(entry.Author.Name),
 			Description:    entry.Summary,
 			Content:        content,
+			PublishedAt:    parseDate(entry.Published),
+			UpdatedAt:      parseDate(entry.Updated),
 			ExtractedLinks: extractor.ExtractLinks(decodedContent),
 		}
+		// Entries without an author are by the feed's
+		if feedItem.Author == "" {
+			feedItem.Author = strings.TrimSpace(atom.Author.Name)
+		}
 		feed.Items = append(feed.Items, feedItem)
 	}
 
 	return feed
 }
+
+// webSubLinks returns the hub and self links a feed advertises for WebSub.
+func webSubLinks(links []atomLink) (hubs []string, self string) {
+	for _, link := range links {
+		href := strings.TrimSpace(link.Href)
+		switch {
+		case href == "":
+		case link.Rel == "hub":
+			hubs = append(hubs, href)
+		case link.Rel == "self" && self == "":
+			self = href
+		}
+	}
+	return hubs, self
+}
+
+// dateLayouts are the date formats seen in feeds: RFC 822 variants for RSS
+// and RFC 3339 for Atom.
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+}
+
+// parseDate parses a feed date, returning the zero time if it can't.
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
@@ -2,6 +2,7 @@ package feed
 
 import (
 	"testing"
+	"time"
 )
 
 func TestParseFeed_RSS2(t *testing.T) {
@@ -137,3 +138,152 @@ func TestFeedItem_ContentOrDescription(t *testing.T) {
 		t.Error("Expected content to be extracted")
 	}
 }
+
+func TestParseFeed_IdentityAndDates(t *testing.T) {
+	rss := `<rss version="2.0"><channel><title>T</title>
+    <item>
+      <title>Dated</title>
+      <link>https://example.com/dated</link>
+      <guid isPermaLink="false"> post-42 </guid>
+      <pubDate>Tue, 02 Jan 2024 15:04:05 +0000</pubDate>
+    </item>
+    <item><title>Undated</title><pubDate>someday</pubDate></item>
+  </channel></rss>`
+
+	feed, err := ParseFeed([]byte(rss))
+	if err != nil {
+		t.Fatalf("ParseFeed error: %v", err)
+	}
+	item := feed.Items[0]
+	if item.GUID != "post-42" {
+		t.Errorf("Expected GUID post-42, got %q", item.GUID)
+	}
+	if !item.PublishedAt.Equal(time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("Unexpected pubDate: %v", item.PublishedAt)
+	}
+	if !feed.Items[1].PublishedAt.IsZero() {
+		t.Errorf("Expected zero time for an unparseable date, got %v", feed.Items[1].PublishedAt)
+	}
+
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom"><title>T</title>
+  <entry>
+    <title>E</title>
+    <id>urn:uuid:1234</id>
+    <published>2024-01-02T10:00:00+01:00</published>
+    <updated>2024-02-01T00:00:00Z</updated>
+  </entry>
+</feed>`
+
+	feed, err = ParseFeed([]byte(atom))
+	if err != nil {
+		t.Fatalf("ParseFeed error: %v", err)
+	}
+	entry := feed.Items[0]
+	if entry.GUID != "urn:uuid:1234" {
+		t.Errorf("Expected GUID urn:uuid:1234, got %q", entry.GUID)
+	}
+	if !entry.PublishedAt.Equal(time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)) || !entry.UpdatedAt.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected dates: published %v, updated %v", entry.PublishedAt, entry.UpdatedAt)
+	}
+}
+
+func TestParseFeed_UpdateHints(t *testing.T) {
+	rss := `<rss version="2.0" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><channel>
+    <title>T</title>
+    <ttl>60</ttl>
+    <sy:updatePeriod>daily</sy:updatePeriod>
+    <sy:updateFrequency>2</sy:updateFrequency>
+  </channel></rss>`
+
+	feed, err := ParseFeed([]byte(rss))
+	if err != nil {
+		t.Fatalf("ParseFeed error: %v", err)
+	}
+	if feed.TTL != time.Hour {
+		t.Errorf("Expected 1h TTL, got %v", feed.TTL)
+	}
+	if feed.UpdatePeriod != 12*time.Hour {
+		t.Errorf("Expected 12h update period, got %v", feed.UpdatePeriod)
+	}
+
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/">
+  <title>T</title>
+  <sy:updatePeriod>weekly</sy:updatePeriod>
+</feed>`
+
+	feed, err = ParseFeed([]byte(atom))
+	if err != nil {
+		t.Fatalf("ParseFeed error: %v", err)
+	}
+	if feed.TTL != 0 || feed.UpdatePeriod != 7*24*time.Hour {
+		t.Errorf("Expected weekly updates and no TTL, got %v, %v", feed.UpdatePeriod, feed.TTL)
+	}
+}
+
+func TestParseFeed_WebSubLinks(t *testing.T) {
+	rss := `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+    <title>T</title>
+    <link>https://example.com/</link>
+    <atom:link rel="self" href="https://example.com/feed.xml"/>
+    <atom:link rel="hub" href="https://hub.example/"/>
+  </channel></rss>`
+
+	feed, err := ParseFeed([]byte(rss))
+	if err != nil {
+		t.Fatalf("ParseFeed error: %v", err)
+	}
+	if feed.URL != "https://example.com/" {
+		t.Errorf("Expected the channel link to survive atom:link, got %q", feed.URL)
+	}
+	if feed.Self != "https://example.com/feed.xml" || len(feed.Hubs) != 1 || feed.Hubs[0] != "https://hub.example/" {
+		t.Errorf("Expected self and hub links, got %q, %v", feed.Self, feed.Hubs)
+	}
+
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>T</title>
+  <link rel="alternate" href="https://example.com/"/>
+  <link rel="hub" href="https://a.hub/"/>
+  <link rel="hub" href="https://b.hub/"/>
+  <link rel="self" href="https://example.com/atom.xml"/>
+</feed>`
+
+	feed, err = ParseFeed([]byte(atom))
+	if err != nil {
+		t.Fatalf("ParseFeed error: %v", err)
+	}
+	if feed.Self != "https://example.com/atom.xml" || len(feed.Hubs) != 2 {
+		t.Errorf("Expected self and two hubs, got %q, %v", feed.Self, feed.Hubs)
+	}
+}
+
+func TestParseFeed_Authors(t *testing.T) {
+	rss := `<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel><title>T</title>
+    <item><title>A</title><author>simon@example.com (Simon Willison)</author></item>
+    <item><title>B</title><dc:creator> Julia Evans </dc:creator></item>
+    <item><title>C</title></item>
+  </channel></rss>`
+
+	feed, err := ParseFeed([]byte(rss))
+	if err != nil {
+		t.Fatalf("ParseFeed error: %v", err)
+	}
+	for i, want := range []string{"simon@example.com (Simon Willison)", "Julia Evans", ""} {
+		if got := feed.Items[i].Author; got != want {
+			t.Errorf("Item %d: expected author %q, got %q", i, want, got)
+		}
+	}
+
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom"><title>T</title>
+  <author><name>Rob Pike</name></author>
+  <entry><title>Own</title><author><name>Russ Cox</name></author></entry>
+  <entry><title>Inherited</title></entry>
+</feed>`
+
+	feed, err = ParseFeed([]byte(atom))
+	if err != nil {
+		t.Fatalf("ParseFeed error: %v", err)
+	}
+	if feed.Items[0].Author != "Russ Cox" || feed.Items[1].Author != "Rob Pike" {
+		t.Errorf("Expected the entry's author, else the feed's, got %q and %q", feed.Items[0].Author, feed.Items[1].Author)
+	}
+}
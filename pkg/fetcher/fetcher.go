@@ -2,9 +2,16 @@
 package fetcher
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -12,6 +19,10 @@ import (
 type Fetcher struct {
 	client    *http.Client
 	userAgent string
+	rateLimit time.Duration
+
+	mu   sync.Mutex
+	next map[string]time.Time // When each host may next be fetched
 }
 
 // Option configures a Fetcher.
@@ -31,6 +42,14 @@ func WithUserAgent(ua string) Option {
 	}
 }
 
+// WithRateLimit sets the shortest time between requests to one host.
+// Requests that come sooner wait their turn.
+func WithRateLimit(d time.Duration) Option {
+	return func(f *Fetcher) {
+		f.rateLimit = d
+	}
+}
+
 // New creates a new Fetcher with the given options.
 func New(opts ...Option) *Fetcher {
 	f := &Fetcher{
@@ -38,6 +57,7 @@ func New(opts ...Option) *Fetcher {
 			Timeout: 30 * time.Second,
 		},
 		userAgent: "rss-graph/1.0",
+		next:      make(map[string]time.Time),
 	}
 	for _, opt := range opts {
 		opt(f)
@@ -45,8 +65,76 @@ func New(opts ...Option) *Fetcher {
 	return f
 }
 
+// Response is the outcome of fetching a URL.
+type Response struct {
+	URL        string        // Final URL after redirects
+	MovedTo    string        // Where the URL permanently redirects to, if it does and that works
+	StatusCode int           // 0 if no response was received
+	Body       []byte        // Nil unless the status was 200
+	Latency    time.Duration // Time until the body was read or the fetch failed
+	MaxAge     time.Duration // Cache-Control max-age; zero if absent
+}
+
+// StatusError is returned for responses other than 200 OK.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d for %s", e.StatusCode, e.URL)
+}
+
+// Error classes returned by Classify.
+const (
+	ErrorDNS        = "dns"
+	ErrorTimeout    = "timeout"
+	ErrorConnection = "connection"
+	ErrorTLS        = "tls"
+	ErrorHTTP       = "http"
+	ErrorOther      = "other"
+)
+
+// Classify sorts a fetch error into a coarse class for health reporting.
+// It returns "" for a nil error.
+func Classify(err error) string {
+	if err == nil {
+		return ""
+	}
+	var statusErr *StatusError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var opErr *net.OpError
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	switch {
+	case errors.As(err, &statusErr):
+		return ErrorHTTP
+	case errors.As(err, &dnsErr):
+		return ErrorDNS
+	case errors.As(err, &certErr), errors.As(err, &recordErr):
+		return ErrorTLS
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case errors.As(err, &opErr):
+		return ErrorConnection
+	}
+	return ErrorOther
+}
+
 // Fetch downloads the content at the given URL.
 func (f *Fetcher) Fetch(url string) ([]byte, error) {
+	resp, err := f.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Get downloads the content at the given URL and reports how the fetch
+// went. For statuses other than 200 it returns the response along with a
+// *StatusError, so callers can record what happened.
+func (f *Fetcher) Get(url string) (*Response, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
@@ -54,21 +142,85 @@ func (f *Fetcher) Fetch(url string) ([]byte, error) {
 
 	req.Header.Set("User-Agent", f.userAgent)
 	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml, text/xml")
+	f.wait(req.URL.Host)
+
+	// Follow redirects as usual, noting whether every hop was permanent
+	result := &Response{URL: url}
+	permanent := true
+	client := *f.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		switch req.Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		default:
+			permanent = false
+		}
+		return nil
+	}
 
-	resp, err := f.client.Do(req)
+	start := time.Now()
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("fetching %s: %w", url, err)
+		result.Latency = time.Since(start)
+		return result, fmt.Errorf("fetching %s: %w", url, err)
 	}
 	defer resp.Body.Close()
 
+	result.StatusCode = resp.StatusCode
+	result.URL = resp.Request.URL.String()
+	result.MaxAge = maxAge(resp.Header.Get("Cache-Control"))
+	// A redirect to a page that fails isn't somewhere to move to
+	if permanent && resp.StatusCode == http.StatusOK && result.URL != url {
+		result.MovedTo = result.URL
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status %d for %s", resp.StatusCode, url)
+		result.Latency = time.Since(start)
+		return result, &StatusError{URL: url, StatusCode: resp.StatusCode}
 	}
 
 	body, err := io.ReadAll(resp.Body)
+	result.Latency = time.Since(start)
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		return result, fmt.Errorf("reading response: %w", err)
+	}
+	result.Body = body
+
+	return result, nil
+}
+
+// wait blocks until host may be fetched under the rate limit, reserving
+// the slot after for the next request.
+func (f *Fetcher) wait(host string) {
+	if f.rateLimit <= 0 {
+		return
+	}
+	f.mu.Lock()
+	at := f.next[host]
+	if now := time.Now(); at.Before(now) {
+		at = now
 	}
+	f.next[host] = at.Add(f.rateLimit)
+	f.mu.Unlock()
+	time.Sleep(time.Until(at))
+}
 
-	return body, nil
+// maxAge returns the max-age directive of a Cache-Control header, or zero
+// if there is none or the response mustn't be cached.
+func maxAge(cacheControl string) time.Duration {
+	var age time.Duration
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache":
+			return 0
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && seconds > 0 {
+				age = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return age
 }
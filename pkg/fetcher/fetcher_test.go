@@ -0,0 +1,173 @@
+package fetcher
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// redirectServer serves a chain of redirects: each path in redirects
+// answers with its status and a Location of the next path.
+func redirectServer(t *testing.T, redirects map[string]redirect) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hop, ok := redirects[r.URL.Path]; ok {
+			http.Redirect(w, r, hop.to, hop.status)
+			return
+		}
+		switch r.URL.Path {
+		case "/feed":
+			w.Header().Set("Cache-Control", "public, max-age=600")
+			w.Write([]byte("<rss></rss>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+type redirect struct {
+	status int
+	to     string
+}
+
+func TestFetcher_Get_Redirects(t *testing.T) {
+	tests := []struct {
+		name      string
+		redirects map[string]redirect
+		wantMoved bool
+		wantCode  int
+	}{
+		{
+			name: "permanent chain",
+			redirects: map[string]redirect{
+				"/old":   {http.StatusMovedPermanently, "/older"},
+				"/older": {http.StatusPermanentRedirect, "/feed"},
+			},
+			wantMoved: true,
+			wantCode:  http.StatusOK,
+		},
+		{
+			name: "temporary hop in a permanent chain",
+			redirects: map[string]redirect{
+				"/old":   {http.StatusMovedPermanently, "/older"},
+				"/older": {http.StatusFound, "/feed"},
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "permanent redirect to a missing page",
+			redirects: map[string]redirect{
+				"/old": {http.StatusMovedPermanently, "/missing"},
+			},
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := redirectServer(t, tt.redirects)
+			resp, err := New().Get(server.URL + "/old")
+			if resp == nil {
+				t.Fatalf("Expected a response, got error %v", err)
+			}
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("Expected status %d, got %d (%v)", tt.wantCode, resp.StatusCode, err)
+			}
+			if tt.wantMoved && resp.MovedTo != server.URL+"/feed" {
+				t.Errorf("Expected moved to /feed, got %q", resp.MovedTo)
+			}
+			if !tt.wantMoved && resp.MovedTo != "" {
+				t.Errorf("Expected no move, got %q", resp.MovedTo)
+			}
+		})
+	}
+}
+
+func TestFetcher_Get(t *testing.T) {
+	server := redirectServer(t, nil)
+
+	resp, err := New().Get(server.URL + "/feed")
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if string(resp.Body) != "<rss></rss>" || resp.MaxAge != 10*time.Minute || resp.MovedTo != "" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+
+	resp, err = New().Get(server.URL + "/missing")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound || statusErr.URL != server.URL+"/missing" {
+		t.Fatalf("Expected a 404 StatusError, got %v", err)
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound || resp.Body != nil || resp.URL != server.URL+"/missing" {
+		t.Errorf("Expected the 404 response filled in, got %+v", resp)
+	}
+	if class := Classify(err); class != ErrorHTTP {
+		t.Errorf("Classify(404) = %q, want %q", class, ErrorHTTP)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	if class := Classify(nil); class != "" {
+		t.Errorf("Classify(nil) = %q, want empty", class)
+	}
+
+	// .invalid never resolves
+	_, err := New().Get("http://feed.invalid/")
+	if class := Classify(err); class != ErrorDNS {
+		t.Errorf("Classify(%v) = %q, want %q", err, class, ErrorDNS)
+	}
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+	resp, err := New(WithTimeout(20 * time.Millisecond)).Get(slow.URL)
+	if class := Classify(err); class != ErrorTimeout {
+		t.Errorf("Classify(%v) = %q, want %q", err, class, ErrorTimeout)
+	}
+	if resp == nil || resp.StatusCode != 0 || resp.Latency <= 0 {
+		t.Errorf("Expected a response with no status and the latency, got %+v", resp)
+	}
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	_, err = New().Get(closed.URL)
+	if class := Classify(err); class != ErrorConnection {
+		t.Errorf("Classify(%v) = %q, want %q", err, class, ErrorConnection)
+	}
+}
+
+func TestFetcher_RateLimit(t *testing.T) {
+	server := redirectServer(t, nil)
+	other := redirectServer(t, nil)
+	const limit = 200 * time.Millisecond
+	f := New(WithRateLimit(limit))
+
+	// The servers share 127.0.0.1, so tell them apart by port as hosts do
+	start := time.Now()
+	f.Get(server.URL + "/feed")
+	f.Get(other.URL + "/feed")
+	if elapsed := time.Since(start); elapsed >= limit {
+		t.Errorf("Expected different hosts not to wait, took %s", elapsed)
+	}
+
+	// Concurrent requests to one host, as from the daemon's workers, each
+	// wait their turn
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.Get(server.URL + "/feed")
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 2*limit {
+		t.Errorf("Expected three requests to one host spaced %s apart, took %s", limit, elapsed)
+	}
+}
@@ -0,0 +1,405 @@
+package graph
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Entity is a person or organization known by several names. Mentions of
+// any alias are counted under the canonical name.
+type Entity struct {
+	ID         int64
+	Name       string // Canonical name
+	EntityType string
+	Aliases    []string // Every name counted as this entity, including Name
+	Mentions   int      // Posts mentioning any alias
+}
+
+// Reasons for suggesting a merge, in the order they are looked for.
+const (
+	ReasonPossessive = "possessive" // "Simon Willison's" and "Simon Willison"
+	ReasonCase       = "case"       // "simon willison" and "Simon Willison"
+	ReasonLastName   = "last-name"  // "Willison" in a post naming "Simon Willison"
+	ReasonSpelling   = "spelling"   // "Simon Wilison" and "Simon Willison"
+)
+
+// MergeSuggestion is a name that likely refers to the same entity as
+// another, more mentioned one.
+type MergeSuggestion struct {
+	Name     string
+	Into     string
+	Reason   string
+	Mentions int // Posts mentioning Name
+}
+
+// entityJoin joins the aliases and entities of a table aliased t with name
+// and entity_type columns, for canonicalName.
+func entityJoin(t string) string {
+	return ` LEFT JOIN entity_aliases ` + t + `_ea ON ` + t + `_ea.alias = ` + t + `.name AND ` + t + `_ea.entity_type = ` + t + `.entity_type
+		 LEFT JOIN entities ` + t + `_e ON ` + t + `_e.id = ` + t + `_ea.entity_id`
+}
+
+// canonicalName is the name a row of t counts under, given entityJoin(t).
+func canonicalName(t string) string {
+	return `COALESCE(` + t + `_e.name, ` + t + `.name)`
+}
+
+// mentionCount counts the posts among mentions aliased t, so a post naming
+// someone by two aliases counts once.
+func mentionCount(t string) string {
+	return `COUNT(DISTINCT ` + t + `.source_id || ' ' || COALESCE(` + t + `.post_url, ''))`
+}
+
+// GetEntities returns the entities of a type, by canonical name.
+func (g *Graph) GetEntities(entityType string) ([]Entity, error) {
+	return g.loadEntities(`e.entity_type = ?`, entityType)
+}
+
+// FindEntity returns the entity a name is an alias of, or nil if the name
+// counts on its own.
+func (g *Graph) FindEntity(entityType, name string) (*Entity, error) {
+	entities, err := g.loadEntities(
+		`e.id = (SELECT entity_id FROM entity_aliases WHERE alias = ? AND entity_type = ?)`,
+		name, entityType,
+	)
+	if err != nil || len(entities) == 0 {
+		return nil, err
+	}
+	return &entities[0], nil
+}
+
+func (g *Graph) loadEntities(where string, args ...any) ([]Entity, error) {
+	rows, err := g.db.Query(
+		`SELECT e.id, e.name, e.entity_type, ea.alias,
+		   (SELECT `+mentionCount("m")+` FROM mentions m
+		    JOIN entity_aliases a ON a.alias = m.name AND a.entity_type = m.entity_type
+		    WHERE a.entity_id = e.id AND m.retracted_at IS NULL)
+		 FROM entities e
+		 JOIN entity_aliases ea ON ea.entity_id = e.id
+		 WHERE `+where+`
+		 ORDER BY e.name, e.id, ea.alias`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entities []Entity
+	for rows.Next() {
+		var e Entity
+		var alias string
+		if err := rows.Scan(&e.ID, &e.Name, &e.EntityType, &alias, &e.Mentions); err != nil {
+			return nil, err
+		}
+		if n := len(entities); n > 0 && entities[n-1].ID == e.ID {
+			entities[n-1].Aliases = append(entities[n-1].Aliases, alias)
+			continue
+		}
+		e.Aliases = []string{alias}
+		entities = append(entities, e)
+	}
+	return entities, rows.Err()
+}
+
+// MergeEntities counts names, along with every alias of the entities they
+// belong to, as one entity under the canonical name. The canonical name
+// joins the entity it is already an alias of, renaming it, or a new one.
+func (g *Graph) MergeEntities(entityType, canonical string, names []string) (*Entity, error) {
+	return g.mergeNames(entityType, canonical, names, true)
+}
+
+// AddAliases counts names as aliases of the entity with the canonical name,
+// as MergeEntities does, but moves only the names themselves out of other
+// entities. Names need not have been mentioned yet.
+func (g *Graph) AddAliases(entityType, canonical string, aliases []string) (*Entity, error) {
+	return g.mergeNames(entityType, canonical, aliases, false)
+}
+
+func (g *Graph) mergeNames(entityType, canonical string, names []string, whole bool) (*Entity, error) {
+	canonical = strings.TrimSpace(canonical)
+	if canonical == "" {
+		return nil, fmt.Errorf("empty canonical name")
+	}
+	tx, err := g.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	target, _, err := entityOf(tx, entityType, canonical)
+	if err != nil {
+		return nil, err
+	}
+	if target == 0 {
+		result, err := tx.Exec(`INSERT INTO entities (name, entity_type) VALUES (?, ?)`, canonical, entityType)
+		if err != nil {
+			return nil, err
+		}
+		target, _ = result.LastInsertId()
+	} else if _, err := tx.Exec(`UPDATE entities SET name = ? WHERE id = ?`, canonical, target); err != nil {
+		return nil, err
+	}
+	if err := setAlias(tx, entityType, canonical, target); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		other, otherName, err := entityOf(tx, entityType, name)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case other == 0 || other == target:
+			err = setAlias(tx, entityType, name, target)
+		case whole:
+			err = absorbEntity(tx, target, other)
+		case name == otherName:
+			return nil, fmt.Errorf("%q is the canonical name of another entity; merge it instead", name)
+		default:
+			err = setAlias(tx, entityType, name, target)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return g.FindEntity(entityType, canonical)
+}
+
+// entityOf returns the ID and canonical name of the entity a name is an
+// alias of, or 0 if there is none.
+func entityOf(tx *sql.Tx, entityType, name string) (int64, string, error) {
+	var id int64
+	var canonical string
+	err := tx.QueryRow(
+		`SELECT e.id, e.name FROM entity_aliases ea JOIN entities e ON e.id = ea.entity_id
+		 WHERE ea.alias = ? AND ea.entity_type = ?`,
+		name, entityType,
+	).Scan(&id, &canonical)
+	if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	return id, canonical, err
+}
+
+func setAlias(tx *sql.Tx, entityType, alias string, entityID int64) error {
+	_, err := tx.Exec(
+		`INSERT INTO entity_aliases (alias, entity_type, entity_id) VALUES (?, ?, ?)
+		 ON CONFLICT(alias, entity_type) DO UPDATE SET entity_id = excluded.entity_id`,
+		alias, entityType, entityID,
+	)
+	return err
+}
+
+// absorbEntity moves every alias of one entity to another and deletes it.
+func absorbEntity(tx *sql.Tx, into, from int64) error {
+	if _, err := tx.Exec(`UPDATE entity_aliases SET entity_id = ? WHERE entity_id = ?`, into, from); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM entities WHERE id = ?`, from)
+	return err
+}
+
+// SplitEntity makes a name count on its own again, reporting whether it was
+// an alias. Splitting an entity's canonical name splits the whole entity,
+// as does leaving it with no other alias.
+func (g *Graph) SplitEntity(entityType, name string) (bool, error) {
+	tx, err := g.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	id, canonical, err := entityOf(tx, entityType, name)
+	if err != nil || id == 0 {
+		return false, err
+	}
+	if name == canonical {
+		_, err = tx.Exec(`DELETE FROM entity_aliases WHERE entity_id = ?`, id)
+	} else {
+		_, err = tx.Exec(`DELETE FROM entity_aliases WHERE alias = ? AND entity_type = ?`, name, entityType)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// An entity known only by its canonical name has nothing left to merge
+	var left int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM entity_aliases WHERE entity_id = ?`, id).Scan(&left); err != nil {
+		return false, err
+	}
+	if left <= 1 {
+		if _, err := tx.Exec(`DELETE FROM entity_aliases WHERE entity_id = ?`, id); err != nil {
+			return false, err
+		}
+		if _, err := tx.Exec(`DELETE FROM entities WHERE id = ?`, id); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// SuggestMerges looks for names, as counted after existing merges, that
+// likely refer to the same entity as a more mentioned name: variants in
+// case or with a possessive, a lone last name in a post that also gives the
+// full name, and near-identical spellings. A name is suggested at most
+// once, and never both merged and merged into.
+func (g *Graph) SuggestMerges(entityType string) ([]MergeSuggestion, error) {
+	counts, err := g.countsByName(
+		`SELECT `+canonicalName("m")+`, `+mentionCount("m")+` FROM mentions m`+entityJoin("m")+`
+		 WHERE m.entity_type = ? AND m.retracted_at IS NULL
+		 GROUP BY 1`,
+		entityType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	// Most mentioned first, so each name is compared with likelier targets
+	// before others
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	var suggestions []MergeSuggestion
+	merged, targets := make(map[string]bool), make(map[string]bool)
+	suggest := func(name, into, reason string) {
+		if name == into || merged[name] || targets[name] || merged[into] {
+			return
+		}
+		merged[name], targets[into] = true, true
+		suggestions = append(suggestions, MergeSuggestion{Name: name, Into: into, Reason: reason, Mentions: counts[name]})
+	}
+
+	// The most mentioned form of each case-folded name
+	byFold := make(map[string]string)
+	for _, name := range names {
+		if _, ok := byFold[strings.ToLower(name)]; !ok {
+			byFold[strings.ToLower(name)] = name
+		}
+	}
+
+	for _, name := range names {
+		if base := trimPossessive(name); base != name {
+			if into, ok := byFold[strings.ToLower(base)]; ok {
+				suggest(name, into, ReasonPossessive)
+			}
+		}
+	}
+	for _, name := range names {
+		suggest(name, byFold[strings.ToLower(name)], ReasonCase)
+	}
+
+	lastNames, err := g.lastNameMatches(entityType)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if into, ok := lastNames[name]; ok {
+			suggest(name, into, ReasonLastName)
+		}
+	}
+
+	for i, into := range names {
+		for _, name := range names[i+1:] {
+			if similarNames(name, into) {
+				suggest(name, into, ReasonSpelling)
+			}
+		}
+	}
+	return suggestions, nil
+}
+
+// lastNameMatches maps single-word names to the one full name ending in
+// them that the same posts give, leaving out those posts pair with several.
+func (g *Graph) lastNameMatches(entityType string) (map[string]string, error) {
+	rows, err := g.db.Query(
+		`SELECT DISTINCT `+canonicalName("s")+`, `+canonicalName("f")+`
+		 FROM mentions s`+entityJoin("s")+`
+		 JOIN mentions f ON f.source_id = s.source_id AND f.post_url = s.post_url AND f.entity_type = s.entity_type`+entityJoin("f")+`
+		 WHERE s.entity_type = ? AND s.post_url != '' AND instr(s.name, ' ') = 0
+		   AND substr(f.name, -length(s.name) - 1) = ' ' || s.name
+		   AND s.retracted_at IS NULL AND f.retracted_at IS NULL`,
+		entityType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := make(map[string]string)
+	ambiguous := make(map[string]bool)
+	for rows.Next() {
+		var short, full string
+		if err := rows.Scan(&short, &full); err != nil {
+			return nil, err
+		}
+		if prev, ok := matches[short]; ok && prev != full {
+			ambiguous[short] = true
+		}
+		matches[short] = full
+	}
+	for short := range ambiguous {
+		delete(matches, short)
+	}
+	return matches, rows.Err()
+}
+
+// trimPossessive drops a trailing possessive from a name.
+func trimPossessive(name string) string {
+	for _, suffix := range []string{"'s", "’s", "'", "’"} {
+		if base, ok := strings.CutSuffix(name, suffix); ok {
+			return strings.TrimSpace(base)
+		}
+	}
+	return name
+}
+
+// similarNames reports whether two names are likely misspellings of each
+// other: within an edit distance of one per ten characters of the shorter.
+func similarNames(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	la, lb := utf8.RuneCountInString(a), utf8.RuneCountInString(b)
+	maxDist := min(la, lb) / 10
+	if maxDist == 0 || max(la-lb, lb-la) > maxDist {
+		return false
+	}
+	return editDistance(a, b) <= maxDist
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,180 @@
+package graph
+
+import (
+	"slices"
+	"testing"
+)
+
+func addMentions(t *testing.T, g *Graph, sourceID int64, postURL string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := g.AddMention(&Mention{SourceID: sourceID, Name: name, EntityType: "PERSON", PostURL: postURL}); err != nil {
+			t.Fatalf("AddMention error: %v", err)
+		}
+	}
+}
+
+func TestGraph_MergeEntities(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	aID, _ := g.AddFeed(&FeedNode{URL: "https://a.com/"})
+	addMentions(t, g, aID, "https://a.com/1", "Simon Willison", "Willison")
+	addMentions(t, g, aID, "https://a.com/2", "simon willison")
+	addMentions(t, g, aID, "https://a.com/3", "Simon Willison's", "Rob Pike")
+	addMentions(t, g, aID, "https://a.com/4", "Rob Pike")
+	g.TakeSnapshot("2024-01-01")
+
+	e, err := g.MergeEntities("PERSON", "Simon Willison", []string{"simon willison", "Willison"})
+	if err != nil {
+		t.Fatalf("MergeEntities error: %v", err)
+	}
+	if e.Name != "Simon Willison" || !slices.Equal(e.Aliases, []string{"Simon Willison", "Willison", "simon willison"}) {
+		t.Errorf("Unexpected entity %+v", e)
+	}
+	if e.Mentions != 2 {
+		t.Errorf("Expected post 1's two aliases counted once, got %d mentions", e.Mentions)
+	}
+
+	// Merging an entity brings along its aliases, and the first name
+	// becomes the canonical one
+	g.AddAliases("PERSON", "Simon Willison's", []string{"Simon W."})
+	e, err = g.MergeEntities("PERSON", "Simon Willison", []string{"Simon W."})
+	if err != nil {
+		t.Fatalf("MergeEntities error: %v", err)
+	}
+	if len(e.Aliases) != 5 || e.Mentions != 3 {
+		t.Errorf("Expected the other entity absorbed, got %+v", e)
+	}
+	if entities, _ := g.GetEntities("PERSON"); len(entities) != 1 {
+		t.Errorf("Expected one entity left, got %+v", entities)
+	}
+
+	ranked, err := g.RankMentions(MentionOptions{EntityType: "PERSON", Limit: 10})
+	if err != nil {
+		t.Fatalf("RankMentions error: %v", err)
+	}
+	if len(ranked) != 2 || ranked[0].Name != "Simon Willison" || ranked[0].MentionCount != 3 {
+		t.Errorf("Expected the aliases ranked together, got %+v", ranked)
+	}
+
+	// The snapshot taken before merging counts the aliases separately, but
+	// is resolved again when comparing; so is the one taken after
+	g.TakeSnapshot("2024-01-08")
+	rising, err := g.GetRisingMentions("PERSON", "2024-01-08", "2024-01-01", 10)
+	if err != nil {
+		t.Fatalf("GetRisingMentions error: %v", err)
+	}
+	for _, r := range rising {
+		if r.Name != "Simon Willison" && r.Name != "Rob Pike" {
+			t.Errorf("Expected only canonical names, got %+v", r)
+		}
+	}
+
+	if _, err := g.AddAliases("PERSON", "Rob Pike", []string{"Simon Willison"}); err == nil {
+		t.Error("Expected an error aliasing another entity's canonical name")
+	}
+	e, err = g.AddAliases("PERSON", "Rob Pike", []string{"Willison"})
+	if err != nil || len(e.Aliases) != 2 {
+		t.Fatalf("AddAliases = %+v, %v", e, err)
+	}
+	if e, _ := g.FindEntity("PERSON", "Simon Willison"); slices.Contains(e.Aliases, "Willison") {
+		t.Error("Expected the alias moved out of the other entity")
+	}
+}
+
+func TestGraph_SplitEntity(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	g.MergeEntities("PERSON", "Simon Willison", []string{"simon willison", "Willison"})
+
+	if split, err := g.SplitEntity("PERSON", "Willison"); err != nil || !split {
+		t.Fatalf("SplitEntity = %v, %v", split, err)
+	}
+	e, _ := g.FindEntity("PERSON", "Simon Willison")
+	if e == nil || !slices.Equal(e.Aliases, []string{"Simon Willison", "simon willison"}) {
+		t.Errorf("Expected one alias split off, got %+v", e)
+	}
+	if split, _ := g.SplitEntity("PERSON", "Willison"); split {
+		t.Error("Expected nothing to split the second time")
+	}
+
+	// Leaving only the canonical name dissolves the entity
+	g.SplitEntity("PERSON", "simon willison")
+	if entities, _ := g.GetEntities("PERSON"); len(entities) != 0 {
+		t.Errorf("Expected no entities left, got %+v", entities)
+	}
+
+	g.MergeEntities("PERSON", "Rob Pike", []string{"rob pike", "Pike"})
+	g.SplitEntity("PERSON", "Rob Pike")
+	if e, _ := g.FindEntity("PERSON", "Pike"); e != nil {
+		t.Errorf("Expected splitting the canonical name to split the entity, got %+v", e)
+	}
+}
+
+func TestGraph_SuggestMerges(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	aID, _ := g.AddFeed(&FeedNode{URL: "https://a.com/"})
+	addMentions(t, g, aID, "https://a.com/1", "Simon Willison", "Willison", "Geoffrey Hinton")
+	addMentions(t, g, aID, "https://a.com/2", "Simon Willison", "Geoffrey Hinton", "Hinton")
+	addMentions(t, g, aID, "https://a.com/3", "simon willison", "Simon Willison's", "Geoffrey Hintn")
+	addMentions(t, g, aID, "https://a.com/4", "Geoffrey Hinton", "Andrej Karpathy")
+	addMentions(t, g, aID, "https://a.com/5", "Andrej Karpathy", "Hinton")
+	// Ambiguous: two different full names end in Smith in posts with it
+	addMentions(t, g, aID, "https://a.com/6", "Alice Smith", "Smith")
+	addMentions(t, g, aID, "https://a.com/7", "Bob Smith", "Smith")
+
+	suggestions, err := g.SuggestMerges("PERSON")
+	if err != nil {
+		t.Fatalf("SuggestMerges error: %v", err)
+	}
+	got := make(map[string]MergeSuggestion)
+	for _, s := range suggestions {
+		got[s.Name] = s
+	}
+	want := map[string]MergeSuggestion{
+		"Simon Willison's": {Into: "Simon Willison", Reason: ReasonPossessive},
+		"simon willison":   {Into: "Simon Willison", Reason: ReasonCase},
+		"Willison":         {Into: "Simon Willison", Reason: ReasonLastName},
+		"Hinton":           {Into: "Geoffrey Hinton", Reason: ReasonLastName},
+		"Geoffrey Hintn":   {Into: "Geoffrey Hinton", Reason: ReasonSpelling},
+	}
+	for name, w := range want {
+		if s, ok := got[name]; !ok || s.Into != w.Into || s.Reason != w.Reason {
+			t.Errorf("Expected %q merged into %q (%s), got %+v", name, w.Into, w.Reason, s)
+		}
+	}
+	if len(suggestions) != len(want) {
+		t.Errorf("Expected %d suggestions, got %+v", len(want), suggestions)
+	}
+
+	// Applying them leaves nothing to suggest
+	for _, s := range suggestions {
+		if _, err := g.MergeEntities("PERSON", s.Into, []string{s.Name}); err != nil {
+			t.Fatalf("MergeEntities error: %v", err)
+		}
+	}
+	if suggestions, _ := g.SuggestMerges("PERSON"); len(suggestions) != 0 {
+		t.Errorf("Expected no suggestions after merging, got %+v", suggestions)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"Willison", "Wilison", 1},
+		{"Łukasz", "Lukasz", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
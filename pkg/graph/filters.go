@@ -0,0 +1,204 @@
+package graph
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// Domain filter actions.
+const (
+	FilterDeny  = "deny"
+	FilterAllow = "allow"
+)
+
+// Domain filter match kinds.
+const (
+	MatchExact  = "exact"  // The host itself
+	MatchSuffix = "suffix" // The domain and any subdomain of it
+	MatchRegex  = "regex"  // Hosts the regular expression matches
+)
+
+// DomainFilter is a rule leaving sites out of rankings, recommendations
+// and expansion, or sparing them from other rules: a site is filtered when
+// a deny rule matches its host and no allow rule does.
+type DomainFilter struct {
+	ID        int64 // Zero for the built-in rules from SetCommonDomains
+	Action    string
+	Match     string
+	Pattern   string // Hosts are lowercased and compared without "www."
+	CreatedAt time.Time
+}
+
+func init() {
+	// SQLite parses "x REGEXP y" but leaves regexp(y, x) to the application
+	sqlite.MustRegisterDeterministicScalarFunction("regexp", 2, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		pattern, _ := args[0].(string)
+		value, _ := args[1].(string)
+		re, err := compileRegexp(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(value), nil
+	})
+}
+
+// regexps caches compiled patterns, since regexp() runs once per row.
+var regexps sync.Map
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexps.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexps.Store(pattern, re)
+	return re, nil
+}
+
+// SetCommonDomains sets the built-in deny rules, which match each domain
+// and its subdomains alongside the rules in the database.
+func (g *Graph) SetCommonDomains(domains []string) {
+	g.commonDomains = nil
+	for _, d := range domains {
+		if d = normalizeHost(d); d != "" {
+			g.commonDomains = append(g.commonDomains, d)
+		}
+	}
+}
+
+// normalizeHost lowercases a host or domain and drops a leading "www.",
+// as siteHost does for feed hosts.
+func normalizeHost(host string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(host)), "www.")
+}
+
+// AddDomainFilter stores a rule, setting its ID. Adding a rule that already
+// exists returns its ID.
+func (g *Graph) AddDomainFilter(f *DomainFilter) (int64, error) {
+	switch f.Action {
+	case FilterDeny, FilterAllow:
+	default:
+		return 0, fmt.Errorf("unknown filter action %q (want deny or allow)", f.Action)
+	}
+	switch f.Match {
+	case MatchExact, MatchSuffix:
+		f.Pattern = normalizeHost(f.Pattern)
+	case MatchRegex:
+		if _, err := regexp.Compile(f.Pattern); err != nil {
+			return 0, fmt.Errorf("invalid regex: %w", err)
+		}
+	default:
+		return 0, fmt.Errorf("unknown match %q (want exact, suffix or regex)", f.Match)
+	}
+	if f.Pattern == "" {
+		return 0, fmt.Errorf("empty filter pattern")
+	}
+
+	_, err := g.db.Exec(
+		`INSERT OR IGNORE INTO domain_filters (action, match, pattern) VALUES (?, ?, ?)`,
+		f.Action, f.Match, f.Pattern,
+	)
+	if err != nil {
+		return 0, err
+	}
+	err = g.db.QueryRow(
+		`SELECT id, created_at FROM domain_filters WHERE action = ? AND match = ? AND pattern = ?`,
+		f.Action, f.Match, f.Pattern,
+	).Scan(&f.ID, &f.CreatedAt)
+	return f.ID, err
+}
+
+// DeleteDomainFilter removes a rule, reporting whether it existed.
+func (g *Graph) DeleteDomainFilter(id int64) (bool, error) {
+	result, err := g.db.Exec(`DELETE FROM domain_filters WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// GetDomainFilters returns the rules in the database, oldest first.
+func (g *Graph) GetDomainFilters() ([]DomainFilter, error) {
+	rows, err := g.db.Query(`SELECT id, action, match, pattern, created_at FROM domain_filters ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var filters []DomainFilter
+	for rows.Next() {
+		var f DomainFilter
+		if err := rows.Scan(&f.ID, &f.Action, &f.Match, &f.Pattern, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, rows.Err()
+}
+
+// CommonDomains returns the built-in deny rules set by SetCommonDomains.
+func (g *Graph) CommonDomains() []DomainFilter {
+	filters := make([]DomainFilter, 0, len(g.commonDomains))
+	for _, d := range g.commonDomains {
+		filters = append(filters, DomainFilter{Action: FilterDeny, Match: MatchSuffix, Pattern: d})
+	}
+	return filters
+}
+
+// filterMatches is a condition on a host expression matching a domain
+// filter rule aliased df.
+const filterMatches = `(df.match = 'exact' AND %[1]s = df.pattern
+	OR df.match = 'suffix' AND (%[1]s = df.pattern OR substr(%[1]s, -length(df.pattern) - 1) = '.' || df.pattern)
+	OR df.match = 'regex' AND %[1]s REGEXP df.pattern)`
+
+// unfiltered returns a condition on the feeds alias f that holds for sites
+// the domain filters keep, with its arguments.
+func (g *Graph) unfiltered(f string) (string, []any) {
+	host := "COALESCE(" + f + ".host, '')"
+	common, _ := json.Marshal(g.commonDomains)
+	cond := fmt.Sprintf(`NOT (
+		(EXISTS (SELECT 1 FROM domain_filters df WHERE df.action = 'deny' AND `+filterMatches+`)
+		 OR EXISTS (SELECT 1 FROM json_each(?) c
+		   WHERE %[1]s = c.value OR substr(%[1]s, -length(c.value) - 1) = '.' || c.value))
+		AND NOT EXISTS (SELECT 1 FROM domain_filters df WHERE df.action = 'allow' AND `+filterMatches+`))`, host)
+	return cond, []any{string(common)}
+}
+
+// FilteredFeeds returns the IDs of the feeds the domain filters leave out.
+func (g *Graph) FilteredFeeds() (map[int64]bool, error) {
+	cond, args := g.unfiltered("f")
+	rows, err := g.db.Query(`SELECT f.id FROM feeds f WHERE NOT `+cond, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	filtered := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		filtered[id] = true
+	}
+	return filtered, rows.Err()
+}
+
+// HostFiltered reports whether the domain filters leave out a site on the
+// URL's host.
+func (g *Graph) HostFiltered(rawURL string) (bool, error) {
+	cond, args := g.unfiltered("f")
+	var kept bool
+	err := g.db.QueryRow(`SELECT `+cond+` FROM (SELECT ? AS host) f`, append(args, siteHost(rawURL))...).Scan(&kept)
+	return !kept, err
+}
@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGraph_DomainFilters(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+	g.SetCommonDomains([]string{"github.com", "WWW.Medium.com"})
+
+	for _, f := range []DomainFilter{
+		{Action: FilterDeny, Match: MatchExact, Pattern: "www.Ads.example"},
+		{Action: FilterDeny, Match: MatchSuffix, Pattern: "wordpress.com"},
+		{Action: FilterDeny, Match: MatchRegex, Pattern: `^news\d*\.`},
+		{Action: FilterAllow, Match: MatchExact, Pattern: "someone.github.com"},
+	} {
+		if _, err := g.AddDomainFilter(&f); err != nil {
+			t.Fatalf("AddDomainFilter(%+v) error: %v", f, err)
+		}
+	}
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://ads.example/", true},
+		{"https://sub.ads.example/", false}, // Exact only matches the host
+		{"https://blog.wordpress.com/feed", true},
+		{"https://notwordpress.com/", false}, // Suffixes match whole labels
+		{"https://news2.example.org/", true},
+		{"https://goodnews.example.org/", false},
+		{"https://github.com/user/repo", true}, // Common domains match subdomains
+		{"https://gist.github.com/x", true},
+		{"https://someone.github.com/", false}, // Allow rules win
+		{"https://www.medium.com/@x", true},
+		{"https://jvns.ca/", false},
+	}
+	for _, tt := range tests {
+		got, err := g.HostFiltered(tt.url)
+		if err != nil {
+			t.Fatalf("HostFiltered(%s) error: %v", tt.url, err)
+		}
+		if got != tt.want {
+			t.Errorf("HostFiltered(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	// Adding a rule again returns the existing one
+	again := DomainFilter{Action: FilterDeny, Match: MatchSuffix, Pattern: "WordPress.com"}
+	id, _ := g.AddDomainFilter(&again)
+	filters, _ := g.GetDomainFilters()
+	if len(filters) != 4 || id != filters[1].ID || filters[0].Pattern != "ads.example" {
+		t.Errorf("Expected 4 normalized rules with the duplicate merged, got %+v (id %d)", filters, id)
+	}
+	if common := g.CommonDomains(); len(common) != 2 || common[1].Pattern != "medium.com" {
+		t.Errorf("Expected normalized common domains, got %+v", common)
+	}
+
+	for _, bad := range []DomainFilter{
+		{Action: "block", Match: MatchExact, Pattern: "x.com"},
+		{Action: FilterDeny, Match: "glob", Pattern: "x.com"},
+		{Action: FilterDeny, Match: MatchRegex, Pattern: "("},
+		{Action: FilterDeny, Match: MatchSuffix, Pattern: "www."},
+	} {
+		if _, err := g.AddDomainFilter(&bad); err == nil {
+			t.Errorf("Expected an error adding %+v", bad)
+		}
+	}
+
+	if removed, _ := g.DeleteDomainFilter(filters[1].ID); !removed {
+		t.Error("Expected the rule removed")
+	}
+	if removed, _ := g.DeleteDomainFilter(filters[1].ID); removed {
+		t.Error("Expected nothing to remove the second time")
+	}
+	if got, _ := g.HostFiltered("https://blog.wordpress.com/"); got {
+		t.Error("Expected the removed rule to stop applying")
+	}
+}
+
+func TestGraph_RankFeeds_Filter(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+	g.SetCommonDomains([]string{"github.com"})
+	g.AddDomainFilter(&DomainFilter{Action: FilterDeny, Match: MatchSuffix, Pattern: "noise.example"})
+
+	// Filtered sites outrank the rest, so the limit must apply after
+	// filtering for any others to show
+	aID, _ := g.AddFeed(&FeedNode{URL: "https://a.com/", Subscribed: true})
+	bID, _ := g.AddFeed(&FeedNode{URL: "https://b.com/", Subscribed: true})
+	for i, target := range []string{"https://github.com/", "https://www.noise.example/", "https://x.com/", "https://y.com/"} {
+		id, _ := g.AddFeed(&FeedNode{URL: target})
+		for n := 0; n < 4-i; n++ {
+			g.AddLink(&LinkEdge{SourceID: aID, TargetID: id, PostURL: fmt.Sprintf("https://a.com/%d/%d", i, n)})
+		}
+		g.AddLink(&LinkEdge{SourceID: bID, TargetID: id, PostURL: fmt.Sprintf("https://b.com/%d", i)})
+	}
+	// A feed with no host isn't filtered out by the NULL
+	g.db.Exec(`UPDATE feeds SET host = NULL WHERE url = 'https://y.com/'`)
+
+	ranked, err := g.RankFeeds(RankOptions{Limit: 2, Filter: true})
+	if err != nil {
+		t.Fatalf("RankFeeds error: %v", err)
+	}
+	if len(ranked) != 2 || ranked[0].Feed.URL != "https://x.com/" || ranked[1].Feed.URL != "https://y.com/" {
+		t.Errorf("Expected x.com and y.com, got %+v", ranked)
+	}
+	if ranked, _ := g.RankFeeds(RankOptions{Limit: 2}); len(ranked) != 2 || ranked[0].Feed.URL != "https://github.com/" {
+		t.Errorf("Expected github.com first without filtering, got %+v", ranked)
+	}
+
+	recs, err := g.GetRecommendations(true)
+	if err != nil {
+		t.Fatalf("GetRecommendations error: %v", err)
+	}
+	if len(recs) != 2 || recs[0].Feed.URL != "https://x.com/" {
+		t.Errorf("Expected filtered recommendations, got %+v", recs)
+	}
+
+	filtered, err := g.FilteredFeeds()
+	if err != nil {
+		t.Fatalf("FilteredFeeds error: %v", err)
+	}
+	if len(filtered) != 2 || filtered[aID] {
+		t.Errorf("Expected github.com and noise.example filtered, got %v", filtered)
+	}
+}
@@ -2,7 +2,13 @@
 package graph
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -10,15 +16,19 @@ import (
 
 // Graph represents the RSS feed relationship graph.
 type Graph struct {
-	db *sql.DB
+	db            *sql.DB
+	commonDomains []string // Built-in deny rules; see SetCommonDomains
 }
 
 // FeedNode represents a feed in the graph.
 type FeedNode struct {
-	ID        int64
-	URL       string
-	Title     string
-	CreatedAt time.Time
+	ID         int64
+	URL        string
+	Title      string
+	SiteURL    string // Homepage of the feed, if known
+	Subscribed bool   // True for feeds we actually follow (vs. discovered link targets)
+	Source     string // Where the subscription came from: miniflux, opml, manual
+	CreatedAt  time.Time
 }
 
 // LinkEdge represents a link from one feed to another.
@@ -26,16 +36,203 @@ type LinkEdge struct {
 	ID           int64
 	SourceID     int64
 	TargetID     int64
-	Context      string // Snippet of text around the link
-	PostURL      string // URL of the post containing the link
-	PostTitle    string // Title of the post
+	PostID       int64     // Post containing the link, 0 if not stored
+	Context      string    // Snippet of text around the link
+	PostURL      string    // URL of the post containing the link
+	PostTitle    string    // Title of the post
+	PublishedAt  time.Time // When the post was published, if known
+	Starred      bool      // Whether we starred the post in our reader
 	DiscoveredAt time.Time
+	RetractedAt  time.Time // When the link was edited out of its post; zero if current
 }
 
 // RankedFeed represents a feed with its link count.
 type RankedFeed struct {
 	Feed         *FeedNode
 	InboundCount int
+	Score        float64 // Inbound links, with starred posts weighted by RankOptions.StarWeight
+}
+
+// RankOptions controls how feeds are ranked.
+type RankOptions struct {
+	Limit      int
+	Category   string   // Only count links from feeds with this tag
+	Cluster    int      // Only rank feeds in this cluster (see DetectClusters)
+	StarWeight *float64 // Weight of links from starred posts; nil counts them as 1
+	Filter     bool     // Leave out sites the domain filters deny
+}
+
+// FeedQuery filters and pages ListFeeds.
+type FeedQuery struct {
+	Search     string // Case-insensitive substring of the URL or title
+	Subscribed bool   // Only feeds we follow
+	Limit      int
+	Offset     int
+}
+
+// Recommendation represents an unsubscribed site cited by our subscriptions.
+type Recommendation struct {
+	Feed        *FeedNode
+	CitingFeeds int // Number of distinct subscriptions linking to it
+	LinkCount   int // Total links from subscriptions
+}
+
+// Post is a crawled entry. Posts are keyed by feed and URL, or GUID for
+// entries without a URL; links and mentions refer to them by ID.
+type Post struct {
+	ID           int64
+	FeedID       int64
+	URL          string // Canonical URL of the post
+	GUID         string // The feed's ID for the entry, if known
+	Title        string
+	Author       string
+	Content      string // Plain text
+	ContentHash  string // See ContentHash; set by AddPost if empty
+	LinkText     string // Anchor text of the post's links
+	PublishedAt  time.Time
+	UpdatedAt    time.Time // When the feed says the post last changed
+	DiscoveredAt time.Time
+	RemovedAt    time.Time // When the post disappeared from its feed; zero if present
+}
+
+// SearchOptions controls SearchPosts.
+type SearchOptions struct {
+	Query string // FTS5 query, e.g. `sqlite "full text" NOT mysql`
+	Limit int
+
+	// Highlight marks matched terms in snippets (default "[" and "]")
+	HighlightStart string
+	HighlightEnd   string
+}
+
+// PostMatch is a post found by SearchPosts.
+type PostMatch struct {
+	Post    Post // Without Content
+	Feed    *FeedNode
+	Snippet string      // Best-matching excerpt with matches highlighted
+	Targets []*FeedNode // Feeds the post links to
+}
+
+// Conversation is a run of links back and forth between two sites, each
+// within a time window of the one before.
+type Conversation struct {
+	Links []LinkEdge // Oldest first
+	Start time.Time
+	End   time.Time
+}
+
+// Cluster is a community of feeds found by DetectClusters.
+type Cluster struct {
+	ID   int // Numbered from 1 by size, largest first
+	Size int // Number of feeds
+}
+
+// CrawlState is the per-feed high-water mark for incremental crawling.
+type CrawlState struct {
+	FeedID          int64
+	LastEntryID     int64     // Highest source entry ID processed
+	LastPublishedAt time.Time // Publish date of the newest entry processed
+	UpdatedAt       time.Time
+}
+
+// Frontier statuses.
+const (
+	FrontierQueued  = "queued"
+	FrontierDone    = "done"
+	FrontierFailed  = "failed"
+	FrontierSkipped = "skipped"
+)
+
+// FrontierEntry is a site queued to be expanded: its feed found, scanned
+// and its own links queued in turn.
+type FrontierEntry struct {
+	FeedID    int64
+	URL       string
+	Depth     int     // Hops from our subscriptions
+	Priority  float64 // Distinct feeds linking to the site; highest goes first
+	Status    string  // One of the Frontier* statuses
+	FeedURL   string  // The site's feed, once found
+	Error     string  // Why the site failed or was skipped
+	UpdatedAt time.Time
+}
+
+// FeedSchedule is when the daemon next polls a feed.
+type FeedSchedule struct {
+	FeedID    int64
+	Interval  time.Duration // Polling interval while the feed is healthy
+	NextFetch time.Time
+	Failures  int // Consecutive failed polls
+}
+
+// WebSub subscription states.
+const (
+	WebSubPending       = "pending"       // Requested, awaiting the hub's verification
+	WebSubActive        = "active"        // Verified; the hub pushes new content
+	WebSubDenied        = "denied"        // The hub refused or cancelled the subscription
+	WebSubUnsubscribing = "unsubscribing" // Unsubscribe requested, awaiting verification
+)
+
+// WebSubSubscription is a push subscription to a feed through its WebSub hub.
+type WebSubSubscription struct {
+	FeedID       int64
+	Hub          string
+	Topic        string // The feed's self URL, as advertised to the hub
+	Secret       string // Shared with the hub to sign pushed content
+	State        string
+	LeaseExpires time.Time // Zero until the hub verifies the subscription
+	Error        string    // Why the hub denied the subscription
+	UpdatedAt    time.Time
+}
+
+// ErrorClassParse is the FetchAttempt error class for bodies that aren't
+// valid feeds.
+const ErrorClassParse = "parse"
+
+// FetchAttempt is one fetch of a feed, successful or not.
+type FetchAttempt struct {
+	ID           int64
+	FeedID       int64
+	AttemptedAt  time.Time // Defaults to now when recorded
+	Status       int       // HTTP status, 0 if there was no response
+	Latency      time.Duration
+	ErrorClass   string // dns, timeout, connection, tls, http, parse or other; empty on success
+	Error        string
+	Bytes        int
+	ItemCount    int
+	NewestItemAt time.Time // Publish date of the newest item in the feed
+	MovedTo      string    // Where the feed permanently redirects to, if it does
+}
+
+// Failed reports whether the fetch failed.
+func (a *FetchAttempt) Failed() bool {
+	return a.ErrorClass != ""
+}
+
+// Feed health statuses, from worst to best.
+const (
+	HealthDead      = "dead"
+	HealthMalformed = "malformed"
+	HealthMoved     = "moved"
+	HealthStale     = "stale"
+	HealthOK        = "ok"
+)
+
+// HealthOptions configures GetFeedHealth.
+type HealthOptions struct {
+	Failures   int       // Consecutive failed fetches that make a feed dead (default 3)
+	StaleSince time.Time // Feeds with no post since then are stale; zero disables
+}
+
+// FeedHealth summarizes how fetching a feed has gone.
+type FeedHealth struct {
+	Feed        *FeedNode
+	Status      string        // One of the Health* statuses
+	Attempts    int           // Fetch attempts recorded
+	Failures    int           // Consecutive failed fetches, up to HealthOptions.Failures
+	Last        *FetchAttempt // Latest attempt; nil if never fetched
+	LastSuccess time.Time     // Zero if never fetched successfully
+	NewestPost  time.Time     // Newest post we know of; zero if unknown
+	MovedTo     string        // Set for moved feeds
 }
 
 // Mention represents a person/org mentioned in a feed post.
@@ -45,11 +242,21 @@ type Mention struct {
 	Name         string // Normalized name
 	EntityType   string // PERSON, ORG, etc.
 	Context      string // Surrounding text
+	PostID       int64  // Post containing the mention, 0 if not stored
 	PostURL      string
 	PostTitle    string
+	PublishedAt  time.Time
 	DiscoveredAt time.Time
 }
 
+// MentionOptions controls how mentions are ranked.
+type MentionOptions struct {
+	EntityType string
+	Category   string // Only count mentions from feeds with this tag
+	Cluster    int    // Only count mentions from feeds in this cluster
+	Limit      int
+}
+
 // RankedMention represents a name with mention count.
 type RankedMention struct {
 	Name         string
@@ -76,9 +283,16 @@ type RisingMention struct {
 	Status        string  // "hot", "rising", "new"
 }
 
-// NewGraph creates or opens a graph database.
+// NewGraph creates or opens a graph database. dbPath is a file path or a
+// URI like "file:graph.db?cache=shared".
 func NewGraph(dbPath string) (*Graph, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	// Writers from concurrent goroutines, such as the daemon's WebSub
+	// callbacks, wait for the lock rather than failing with SQLITE_BUSY
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite", dbPath+sep+"_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, err
 	}
@@ -149,13 +363,355 @@ func (g *Graph) initSchema() error {
 
 		CREATE INDEX IF NOT EXISTS idx_snapshots_date ON mention_snapshots(snapshot_date);
 		CREATE INDEX IF NOT EXISTS idx_snapshots_name ON mention_snapshots(name);
+
+		CREATE TABLE IF NOT EXISTS entities (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			entity_type TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(name, entity_type)
+		);
+
+		CREATE TABLE IF NOT EXISTS entity_aliases (
+			alias TEXT NOT NULL,
+			entity_type TEXT NOT NULL,
+			entity_id INTEGER NOT NULL,
+			FOREIGN KEY (entity_id) REFERENCES entities(id),
+			PRIMARY KEY (alias, entity_type)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_entity_aliases_entity ON entity_aliases(entity_id);
+
+		CREATE TABLE IF NOT EXISTS feed_tags (
+			feed_id INTEGER NOT NULL,
+			tag TEXT NOT NULL COLLATE NOCASE,
+			FOREIGN KEY (feed_id) REFERENCES feeds(id),
+			UNIQUE(feed_id, tag)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_feed_tags_tag ON feed_tags(tag);
+
+		CREATE TABLE IF NOT EXISTS crawl_state (
+			feed_id INTEGER PRIMARY KEY,
+			last_entry_id INTEGER NOT NULL DEFAULT 0,
+			last_published_at DATETIME,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (feed_id) REFERENCES feeds(id)
+		);
+
+		CREATE TABLE IF NOT EXISTS feed_clusters (
+			feed_id INTEGER PRIMARY KEY,
+			cluster INTEGER NOT NULL,
+			FOREIGN KEY (feed_id) REFERENCES feeds(id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_feed_clusters_cluster ON feed_clusters(cluster);
+
+		CREATE TABLE IF NOT EXISTS fetch_attempts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			feed_id INTEGER NOT NULL,
+			attempted_at DATETIME NOT NULL,
+			status INTEGER NOT NULL DEFAULT 0,
+			latency_ms INTEGER NOT NULL DEFAULT 0,
+			error_class TEXT,
+			error TEXT,
+			bytes INTEGER NOT NULL DEFAULT 0,
+			item_count INTEGER NOT NULL DEFAULT 0,
+			newest_item_at DATETIME,
+			moved_to TEXT,
+			FOREIGN KEY (feed_id) REFERENCES feeds(id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_fetch_attempts_feed ON fetch_attempts(feed_id, attempted_at);
+
+		CREATE TABLE IF NOT EXISTS frontier (
+			feed_id INTEGER PRIMARY KEY,
+			depth INTEGER NOT NULL,
+			priority REAL NOT NULL DEFAULT 0,
+			status TEXT NOT NULL DEFAULT 'queued',
+			feed_url TEXT,
+			error TEXT,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (feed_id) REFERENCES feeds(id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_frontier_status ON frontier(status, priority);
+
+		CREATE TABLE IF NOT EXISTS feed_schedule (
+			feed_id INTEGER PRIMARY KEY,
+			interval_seconds INTEGER NOT NULL,
+			next_fetch_at DATETIME NOT NULL,
+			failures INTEGER NOT NULL DEFAULT 0,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (feed_id) REFERENCES feeds(id)
+		);
+
+		CREATE TABLE IF NOT EXISTS domain_filters (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			action TEXT NOT NULL,
+			match TEXT NOT NULL,
+			pattern TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(action, match, pattern)
+		);
+
+		CREATE TABLE IF NOT EXISTS websub_subscriptions (
+			feed_id INTEGER PRIMARY KEY,
+			hub TEXT NOT NULL,
+			topic TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			state TEXT NOT NULL,
+			lease_expires_at DATETIME,
+			error TEXT,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (feed_id) REFERENCES feeds(id)
+		);
+
+		CREATE TABLE IF NOT EXISTS homepages (
+			host TEXT PRIMARY KEY,
+			author TEXT,
+			error TEXT,
+			fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS rel_me_links (
+			host TEXT NOT NULL,
+			target_host TEXT NOT NULL,
+			PRIMARY KEY (host, target_host)
+		);
+
+		CREATE TABLE IF NOT EXISTS posts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			feed_id INTEGER NOT NULL,
+			url TEXT NOT NULL,
+			title TEXT,
+			content TEXT,
+			link_text TEXT,
+			published_at DATETIME,
+			discovered_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (feed_id) REFERENCES feeds(id),
+			UNIQUE(feed_id, url)
+		);
+
+		-- Full-text index over posts, kept in sync by triggers
+		CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts5(
+			title, content, link_text, content='posts', content_rowid='id'
+		);
+
+		CREATE TRIGGER IF NOT EXISTS posts_ai AFTER INSERT ON posts BEGIN
+			INSERT INTO posts_fts(rowid, title, content, link_text)
+			VALUES (new.id, new.title, new.content, new.link_text);
+		END;
+
+		CREATE TRIGGER IF NOT EXISTS posts_ad AFTER DELETE ON posts BEGIN
+			INSERT INTO posts_fts(posts_fts, rowid, title, content, link_text)
+			VALUES ('delete', old.id, old.title, old.content, old.link_text);
+		END;
+
+		CREATE TRIGGER IF NOT EXISTS posts_au AFTER UPDATE ON posts BEGIN
+			INSERT INTO posts_fts(posts_fts, rowid, title, content, link_text)
+			VALUES ('delete', old.id, old.title, old.content, old.link_text);
+			INSERT INTO posts_fts(rowid, title, content, link_text)
+			VALUES (new.id, new.title, new.content, new.link_text);
+		END;
 	`
-	_, err := g.db.Exec(schema)
+	if _, err := g.db.Exec(schema); err != nil {
+		return err
+	}
+	return g.migrate()
+}
+
+// migrate brings databases created by older versions up to date.
+func (g *Graph) migrate() error {
+	columns := []struct{ table, column, def string }{
+		{"feeds", "site_url", "TEXT"},
+		{"feeds", "subscribed", "INTEGER NOT NULL DEFAULT 0"},
+		{"feeds", "source", "TEXT"},
+		{"links", "published_at", "DATETIME"},
+		{"links", "starred", "INTEGER NOT NULL DEFAULT 0"},
+		{"mentions", "published_at", "DATETIME"},
+		{"feeds", "host", "TEXT"},
+		{"posts", "guid", "TEXT"},
+		{"posts", "author", "TEXT"},
+		{"posts", "updated_at", "DATETIME"},
+		{"posts", "content_hash", "TEXT"},
+		{"links", "post_id", "INTEGER REFERENCES posts(id)"},
+		{"mentions", "post_id", "INTEGER REFERENCES posts(id)"},
+		{"links", "retracted_at", "DATETIME"},
+		{"mentions", "retracted_at", "DATETIME"},
+		{"posts", "removed_at", "DATETIME"},
+	}
+	for _, c := range columns {
+		if err := g.addColumn(c.table, c.column, c.def); err != nil {
+			return err
+		}
+	}
+	for _, index := range []string{
+		`CREATE INDEX IF NOT EXISTS idx_feeds_host ON feeds(host)`,
+		`CREATE INDEX IF NOT EXISTS idx_links_post ON links(post_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_mentions_post ON mentions(post_id)`,
+	} {
+		if _, err := g.db.Exec(index); err != nil {
+			return err
+		}
+	}
+	if err := g.backfillHosts(); err != nil {
+		return err
+	}
+	return g.backfillPosts()
+}
+
+// backfillHosts fills in feeds.host for feeds added before the column existed.
+func (g *Graph) backfillHosts() error {
+	rows, err := g.db.Query(`SELECT id, url FROM feeds WHERE host IS NULL`)
+	if err != nil {
+		return err
+	}
+	hosts := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var feedURL string
+		if err := rows.Scan(&id, &feedURL); err != nil {
+			rows.Close()
+			return err
+		}
+		hosts[id] = siteHost(feedURL)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, host := range hosts {
+		if _, err := g.db.Exec(`UPDATE feeds SET host = ? WHERE id = ?`, host, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillPosts creates the posts behind links and mentions crawled before
+// posts were stored, and points those rows at them. Backfilled posts are
+// indexed by title and link text; their content wasn't kept.
+func (g *Graph) backfillPosts() error {
+	tx, err := g.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range []string{
+		`INSERT OR IGNORE INTO posts (feed_id, url, title, published_at)
+		 SELECT source_id, post_url, MAX(post_title), MAX(published_at)
+		 FROM links WHERE post_id IS NULL AND post_url != ''
+		 GROUP BY source_id, post_url`,
+		`INSERT OR IGNORE INTO posts (feed_id, url, title, published_at)
+		 SELECT source_id, post_url, MAX(post_title), MAX(published_at)
+		 FROM mentions WHERE post_id IS NULL AND post_url != ''
+		 GROUP BY source_id, post_url`,
+		`UPDATE posts SET link_text = (
+		   SELECT GROUP_CONCAT(context, ' ') FROM links
+		   WHERE links.source_id = posts.feed_id AND links.post_url = posts.url)
+		 WHERE link_text IS NULL AND id IN (
+		   SELECT p.id FROM links l JOIN posts p ON p.feed_id = l.source_id AND p.url = l.post_url
+		   WHERE l.post_id IS NULL)`,
+		`UPDATE links SET post_id = (
+		   SELECT id FROM posts WHERE feed_id = links.source_id AND url = links.post_url)
+		 WHERE post_id IS NULL AND post_url != ''`,
+		`UPDATE mentions SET post_id = (
+		   SELECT id FROM posts WHERE feed_id = mentions.source_id AND url = mentions.post_url)
+		 WHERE post_id IS NULL AND post_url != ''`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// addColumn adds a column to a table unless it already exists.
+func (g *Graph) addColumn(table, column, def string) error {
+	rows, err := g.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	exists := false
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		if name == column {
+			exists = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	_, err = g.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def))
 	return err
 }
 
+// feedColumns is the column list read by scanFeed, qualified by a table alias.
+func feedColumns(alias string) string {
+	return strings.ReplaceAll(
+		"f.id, f.url, f.title, COALESCE(f.site_url, ''), f.subscribed, COALESCE(f.source, ''), f.created_at",
+		"f.", alias+".",
+	)
+}
+
+// nullTime converts a zero time to NULL for storage.
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
+
+// nullString stores an empty string as NULL.
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullID stores a zero ID as NULL.
+func nullID(id int64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanFeed reads the columns from feedColumns followed by any extra destinations.
+func scanFeed(row scanner, extra ...any) (*FeedNode, error) {
+	feed := &FeedNode{}
+	var title sql.NullString
+	dest := append([]any{&feed.ID, &feed.URL, &title, &feed.SiteURL, &feed.Subscribed, &feed.Source, &feed.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	feed.Title = title.String
+	return feed, nil
+}
+
 // AddFeed adds a feed to the graph, returning its ID.
-// If the feed already exists (by URL), returns the existing ID.
+// If the feed already exists (by URL), returns the existing ID. Adding an
+// existing feed with Subscribed set promotes it to a subscription, taking
+// over the given title, site URL and source.
 func (g *Graph) AddFeed(feed *FeedNode) (int64, error) {
 	// Try to get existing
 	existing, err := g.GetFeedByURL(feed.URL)
@@ -163,13 +719,26 @@ func (g *Graph) AddFeed(feed *FeedNode) (int64, error) {
 		return 0, err
 	}
 	if existing != nil {
+		if feed.Subscribed {
+			_, err := g.db.Exec(
+				`UPDATE feeds SET subscribed = 1,
+				   title = COALESCE(NULLIF(?, ''), title),
+				   site_url = COALESCE(NULLIF(?, ''), site_url),
+				   source = COALESCE(NULLIF(?, ''), source)
+				 WHERE id = ?`,
+				feed.Title, feed.SiteURL, feed.Source, existing.ID,
+			)
+			if err != nil {
+				return 0, err
+			}
+		}
 		return existing.ID, nil
 	}
 
 	// Insert new
 	result, err := g.db.Exec(
-		"INSERT INTO feeds (url, title) VALUES (?, ?)",
-		feed.URL, feed.Title,
+		"INSERT INTO feeds (url, title, site_url, subscribed, source, host) VALUES (?, ?, NULLIF(?, ''), ?, NULLIF(?, ''), ?)",
+		feed.URL, feed.Title, feed.SiteURL, feed.Subscribed, feed.Source, siteHost(feed.URL),
 	)
 	if err != nil {
 		return 0, err
@@ -180,12 +749,11 @@ func (g *Graph) AddFeed(feed *FeedNode) (int64, error) {
 // GetFeedByURL retrieves a feed by its URL.
 func (g *Graph) GetFeedByURL(url string) (*FeedNode, error) {
 	row := g.db.QueryRow(
-		"SELECT id, url, title, created_at FROM feeds WHERE url = ?",
+		"SELECT "+feedColumns("f")+" FROM feeds f WHERE f.url = ?",
 		url,
 	)
 
-	feed := &FeedNode{}
-	err := row.Scan(&feed.ID, &feed.URL, &feed.Title, &feed.CreatedAt)
+	feed, err := scanFeed(row)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -195,195 +763,1266 @@ func (g *Graph) GetFeedByURL(url string) (*FeedNode, error) {
 	return feed, nil
 }
 
-// AddLink adds a link between two feeds.
-func (g *Graph) AddLink(link *LinkEdge) error {
-	_, err := g.db.Exec(
-		`INSERT OR IGNORE INTO links (source_id, target_id, context, post_url, post_title)
-		 VALUES (?, ?, ?, ?, ?)`,
-		link.SourceID, link.TargetID, link.Context, link.PostURL, link.PostTitle,
+// GetFeedByID retrieves a feed by its ID, returning nil if it doesn't exist.
+func (g *Graph) GetFeedByID(id int64) (*FeedNode, error) {
+	row := g.db.QueryRow(
+		"SELECT "+feedColumns("f")+" FROM feeds f WHERE f.id = ?",
+		id,
 	)
-	return err
+
+	feed, err := scanFeed(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return feed, nil
 }
 
-// GetOutboundLinks gets all links from a feed.
-func (g *Graph) GetOutboundLinks(feedID int64) ([]LinkEdge, error) {
+// FindFeeds resolves a user-supplied feed reference: an exact URL, the same
+// URL with or without a trailing slash, or a bare host like "jvns.ca". A host
+// can match several feeds, e.g. a subscription's feed URL and the site root
+// other feeds link to; subscriptions come first.
+func (g *Graph) FindFeeds(ref string) ([]*FeedNode, error) {
+	candidates := []string{ref}
+	if strings.HasSuffix(ref, "/") {
+		candidates = append(candidates, strings.TrimSuffix(ref, "/"))
+	} else {
+		candidates = append(candidates, ref+"/")
+	}
+	for _, u := range candidates {
+		feed, err := g.GetFeedByURL(u)
+		if err != nil {
+			return nil, err
+		}
+		if feed != nil {
+			return []*FeedNode{feed}, nil
+		}
+	}
+
+	hostURL := ref
+	if !strings.Contains(ref, "://") {
+		hostURL = "https://" + ref
+	}
+	host := siteHost(hostURL)
+	if host == "" {
+		return nil, nil
+	}
+
 	rows, err := g.db.Query(
-		`SELECT id, source_id, target_id, context, post_url, post_title, discovered_at
-		 FROM links WHERE source_id = ?`,
-		feedID,
+		"SELECT "+feedColumns("f")+" FROM feeds f WHERE f.host = ? ORDER BY f.subscribed DESC, f.id",
+		host,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return scanLinks(rows)
+	var feeds []*FeedNode
+	for rows.Next() {
+		feed, err := scanFeed(rows)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, rows.Err()
 }
 
-// GetInboundLinks gets all links to a feed.
-func (g *Graph) GetInboundLinks(feedID int64) ([]LinkEdge, error) {
+// GetSubscribedFeeds returns all feeds marked as subscriptions.
+func (g *Graph) GetSubscribedFeeds() ([]*FeedNode, error) {
 	rows, err := g.db.Query(
-		`SELECT id, source_id, target_id, context, post_url, post_title, discovered_at
-		 FROM links WHERE target_id = ?`,
-		feedID,
+		"SELECT " + feedColumns("f") + " FROM feeds f WHERE f.subscribed = 1 ORDER BY f.title",
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return scanLinks(rows)
+	var feeds []*FeedNode
+	for rows.Next() {
+		feed, err := scanFeed(rows)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, rows.Err()
 }
 
-// GetMostLinked returns feeds ranked by inbound link count.
-func (g *Graph) GetMostLinked(limit int) ([]RankedFeed, error) {
+// ListFeeds returns a page of feeds matching q in ID order, along with the
+// total number of matches.
+func (g *Graph) ListFeeds(q FeedQuery) ([]*FeedNode, int, error) {
+	where := "WHERE 1 = 1"
+	var args []any
+	if q.Search != "" {
+		where += " AND (f.url LIKE ? OR f.title LIKE ?)"
+		pattern := "%" + q.Search + "%"
+		args = append(args, pattern, pattern)
+	}
+	if q.Subscribed {
+		where += " AND f.subscribed = 1"
+	}
+
+	var total int
+	if err := g.db.QueryRow("SELECT COUNT(*) FROM feeds f "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = -1
+	}
 	rows, err := g.db.Query(
-		`SELECT f.id, f.url, f.title, f.created_at, COUNT(l.id) as link_count
-		 FROM feeds f
-		 LEFT JOIN links l ON f.id = l.target_id
-		 GROUP BY f.id
-		 HAVING link_count > 0
-		 ORDER BY link_count DESC
-		 LIMIT ?`,
-		limit,
+		"SELECT "+feedColumns("f")+" FROM feeds f "+where+" ORDER BY f.id LIMIT ? OFFSET ?",
+		append(args, limit, q.Offset)...,
 	)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var results []RankedFeed
+	var feeds []*FeedNode
 	for rows.Next() {
-		feed := &FeedNode{}
-		var count int
-		if err := rows.Scan(&feed.ID, &feed.URL, &feed.Title, &feed.CreatedAt, &count); err != nil {
-			return nil, err
+		feed, err := scanFeed(rows)
+		if err != nil {
+			return nil, 0, err
 		}
-		results = append(results, RankedFeed{Feed: feed, InboundCount: count})
+		feeds = append(feeds, feed)
 	}
-	return results, rows.Err()
+	return feeds, total, rows.Err()
 }
 
-func scanLinks(rows *sql.Rows) ([]LinkEdge, error) {
-	var links []LinkEdge
+// GetRecommendations returns unsubscribed sites ranked by how many of our
+// subscriptions link to them. Sites on the same host as a subscription are
+// skipped, since the feed URL we follow rarely matches the site root that
+// links get normalized to. With filter, so are sites the domain filters deny.
+func (g *Graph) GetRecommendations(filter bool) ([]Recommendation, error) {
+	subs, err := g.GetSubscribedFeeds()
+	if err != nil {
+		return nil, err
+	}
+	subscribedHosts := make(map[string]bool)
+	for _, s := range subs {
+		for _, u := range []string{s.URL, s.SiteURL} {
+			if h := siteHost(u); h != "" {
+				subscribedHosts[h] = true
+			}
+		}
+	}
+
+	query := `SELECT ` + feedColumns("t") + `, COUNT(DISTINCT l.source_id) AS citing, COUNT(l.id) AS link_count
+		 FROM links l
+		 JOIN feeds s ON s.id = l.source_id
+		 JOIN feeds t ON t.id = l.target_id
+		 WHERE s.subscribed = 1 AND t.subscribed = 0 AND l.retracted_at IS NULL`
+	var args []any
+	if filter {
+		cond, condArgs := g.unfiltered("t")
+		query += ` AND ` + cond
+		args = condArgs
+	}
+	rows, err := g.db.Query(query+` GROUP BY t.id ORDER BY citing DESC, link_count DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []Recommendation
 	for rows.Next() {
-		var link LinkEdge
-		var postURL, postTitle, context sql.NullString
-		if err := rows.Scan(&link.ID, &link.SourceID, &link.TargetID, &context, &postURL, &postTitle, &link.DiscoveredAt); err != nil {
+		var r Recommendation
+		feed, err := scanFeed(rows, &r.CitingFeeds, &r.LinkCount)
+		if err != nil {
 			return nil, err
 		}
-		link.Context = context.String
-		link.PostURL = postURL.String
-		link.PostTitle = postTitle.String
-		links = append(links, link)
+		if subscribedHosts[siteHost(feed.URL)] {
+			continue
+		}
+		r.Feed = feed
+		results = append(results, r)
 	}
-	return links, rows.Err()
+	return results, rows.Err()
 }
 
-// AddMention adds a mention to the graph.
-func (g *Graph) AddMention(mention *Mention) error {
+// siteHost returns the lowercased host of a URL without a leading "www.".
+func siteHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// AddFeedTag tags a feed, e.g. with its category in our reader.
+func (g *Graph) AddFeedTag(feedID int64, tag string) error {
 	_, err := g.db.Exec(
-		`INSERT OR IGNORE INTO mentions (source_id, name, entity_type, context, post_url, post_title)
-		 VALUES (?, ?, ?, ?, ?, ?)`,
-		mention.SourceID, mention.Name, mention.EntityType, mention.Context, mention.PostURL, mention.PostTitle,
+		`INSERT OR IGNORE INTO feed_tags (feed_id, tag) VALUES (?, ?)`,
+		feedID, tag,
 	)
 	return err
 }
 
-// GetMostMentioned returns names ranked by mention count.
-func (g *Graph) GetMostMentioned(entityType string, limit int) ([]RankedMention, error) {
-	query := `SELECT name, entity_type, COUNT(*) as mention_count
-		 FROM mentions
-		 WHERE entity_type = ?
-		 GROUP BY name
-		 ORDER BY mention_count DESC
-		 LIMIT ?`
-
-	rows, err := g.db.Query(query, entityType, limit)
+// GetFeedTags returns a feed's tags.
+func (g *Graph) GetFeedTags(feedID int64) ([]string, error) {
+	rows, err := g.db.Query(`SELECT tag FROM feed_tags WHERE feed_id = ? ORDER BY tag`, feedID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var results []RankedMention
+	var tags []string
 	for rows.Next() {
-		var r RankedMention
-		if err := rows.Scan(&r.Name, &r.EntityType, &r.MentionCount); err != nil {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
 			return nil, err
 		}
-		results = append(results, r)
+		tags = append(tags, tag)
 	}
-	return results, rows.Err()
+	return tags, rows.Err()
 }
 
-// GetMentionsByFeed returns all mentions from a specific feed.
-func (g *Graph) GetMentionsByFeed(feedID int64) ([]Mention, error) {
+// GetFeedIDsByTag returns the set of feed IDs with the given tag.
+func (g *Graph) GetFeedIDsByTag(tag string) (map[int64]bool, error) {
+	rows, err := g.db.Query(`SELECT feed_id FROM feed_tags WHERE tag = ?`, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// AddLink adds a link between two feeds. Re-adding a link refreshes its
+// starred flag, fills in a missing publish date and restores it if it was
+// retracted.
+func (g *Graph) AddLink(link *LinkEdge) error {
+	_, err := g.db.Exec(
+		`INSERT INTO links (source_id, target_id, post_id, context, post_url, post_title, published_at, starred)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(source_id, target_id, post_url) DO UPDATE SET
+		   starred = excluded.starred,
+		   post_id = COALESCE(excluded.post_id, links.post_id),
+		   retracted_at = NULL,
+		   published_at = COALESCE(links.published_at, excluded.published_at)`,
+		link.SourceID, link.TargetID, nullID(link.PostID), link.Context, link.PostURL, link.PostTitle,
+		nullTime(link.PublishedAt), link.Starred,
+	)
+	if err != nil || link.PostID == 0 {
+		return err
+	}
+	// Keep the post's link text searchable
+	_, err = g.db.Exec(
+		`UPDATE posts SET link_text = (
+		   SELECT GROUP_CONCAT(context, ' ') FROM links WHERE post_id = ? AND retracted_at IS NULL)
+		 WHERE id = ?`,
+		link.PostID, link.PostID,
+	)
+	return err
+}
+
+const linkColumns = "id, source_id, target_id, COALESCE(post_id, 0), context, post_url, post_title, published_at, starred, discovered_at, retracted_at"
+
+// GetOutboundLinks gets all current links from a feed.
+func (g *Graph) GetOutboundLinks(feedID int64) ([]LinkEdge, error) {
+	return g.queryLinks(`source_id = ? AND retracted_at IS NULL`, feedID)
+}
+
+// GetInboundLinks gets all current links to a feed.
+func (g *Graph) GetInboundLinks(feedID int64) ([]LinkEdge, error) {
+	return g.queryLinks(`target_id = ? AND retracted_at IS NULL`, feedID)
+}
+
+// GetOutboundLinkHistory gets all links from a feed, including retracted ones.
+func (g *Graph) GetOutboundLinkHistory(feedID int64) ([]LinkEdge, error) {
+	return g.queryLinks(`source_id = ?`, feedID)
+}
+
+// GetInboundLinkHistory gets all links to a feed, including retracted ones.
+func (g *Graph) GetInboundLinkHistory(feedID int64) ([]LinkEdge, error) {
+	return g.queryLinks(`target_id = ?`, feedID)
+}
+
+func (g *Graph) queryLinks(where string, args ...any) ([]LinkEdge, error) {
+	rows, err := g.db.Query(`SELECT `+linkColumns+` FROM links WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanLinks(rows)
+}
+
+// GetLinksBetween gets all current links from any of sources to any of
+// targets, newest first.
+func (g *Graph) GetLinksBetween(sources, targets []int64) ([]LinkEdge, error) {
+	if len(sources) == 0 || len(targets) == 0 {
+		return nil, nil
+	}
+	var args []any
+	for _, id := range sources {
+		args = append(args, id)
+	}
+	for _, id := range targets {
+		args = append(args, id)
+	}
 	rows, err := g.db.Query(
-		`SELECT id, source_id, name, entity_type, context, post_url, post_title, discovered_at
-		 FROM mentions WHERE source_id = ?`,
+		`SELECT `+linkColumns+`
+		 FROM links WHERE source_id IN (`+placeholders(len(sources))+`)
+		   AND target_id IN (`+placeholders(len(targets))+`)
+		   AND retracted_at IS NULL
+		 ORDER BY COALESCE(published_at, discovered_at) DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanLinks(rows)
+}
+
+// ContentHash fingerprints a post's title and content, so re-crawls can
+// tell whether it changed.
+func ContentHash(title, content string) string {
+	sum := sha256.Sum256([]byte(title + "\x00" + content))
+	return hex.EncodeToString(sum[:])
+}
+
+// postKey is the URL a post is stored under: its URL, else its GUID.
+func postKey(post *Post) string {
+	if post.URL != "" {
+		return post.URL
+	}
+	return post.GUID
+}
+
+// AddPost stores a post, or updates the feed's post with the same key,
+// setting post.ID and post.ContentHash. The post is indexed for search
+// along with the anchor text of its links, whether they're added before or
+// after it.
+func (g *Graph) AddPost(post *Post) (int64, error) {
+	key := postKey(post)
+	if key == "" {
+		return 0, fmt.Errorf("post has neither a URL nor a GUID")
+	}
+	if post.ContentHash == "" {
+		post.ContentHash = ContentHash(post.Title, post.Content)
+	}
+	err := g.db.QueryRow(
+		`INSERT INTO posts (feed_id, url, guid, title, author, content, content_hash, link_text, published_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?,
+		   (SELECT GROUP_CONCAT(context, ' ') FROM links
+		    WHERE source_id = ? AND post_url = ? AND retracted_at IS NULL), ?, ?)
+		 ON CONFLICT(feed_id, url) DO UPDATE SET
+		   guid = COALESCE(excluded.guid, posts.guid),
+		   title = excluded.title,
+		   author = COALESCE(excluded.author, posts.author),
+		   content = excluded.content,
+		   content_hash = excluded.content_hash,
+		   link_text = excluded.link_text,
+		   published_at = COALESCE(excluded.published_at, posts.published_at),
+		   updated_at = COALESCE(excluded.updated_at, posts.updated_at),
+		   removed_at = NULL
+		 RETURNING id`,
+		post.FeedID, key, nullString(post.GUID), post.Title, nullString(post.Author), post.Content, post.ContentHash,
+		post.FeedID, key, nullTime(post.PublishedAt), nullTime(post.UpdatedAt),
+	).Scan(&post.ID)
+	return post.ID, err
+}
+
+// PostChanged reports whether post is new to its feed, differs from the
+// stored copy by content hash, or was marked removed, so re-crawls can skip
+// unchanged entries. It sets post.ContentHash if empty.
+func (g *Graph) PostChanged(post *Post) (bool, error) {
+	if post.ContentHash == "" {
+		post.ContentHash = ContentHash(post.Title, post.Content)
+	}
+	var hash sql.NullString
+	var removed bool
+	err := g.db.QueryRow(
+		`SELECT content_hash, removed_at IS NOT NULL FROM posts WHERE feed_id = ? AND url = ?`,
+		post.FeedID, postKey(post),
+	).Scan(&hash, &removed)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	// A post back in its feed is stored again to restore its links
+	return removed || hash.String != post.ContentHash, nil
+}
+
+// RetractLinks marks a post's links to anything other than targetIDs, the
+// feeds it links to now, as retracted, keeping them as history. It returns
+// how many links it retracted.
+func (g *Graph) RetractLinks(postID int64, targetIDs []int64) (int, error) {
+	query := `UPDATE links SET retracted_at = CURRENT_TIMESTAMP
+		 WHERE post_id = ? AND retracted_at IS NULL`
+	args := []any{postID}
+	if len(targetIDs) > 0 {
+		query += ` AND target_id NOT IN (` + placeholders(len(targetIDs)) + `)`
+		for _, id := range targetIDs {
+			args = append(args, id)
+		}
+	}
+	result, err := g.db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return 0, nil
+	}
+
+	_, err = g.db.Exec(
+		`UPDATE posts SET link_text = (
+		   SELECT GROUP_CONCAT(context, ' ') FROM links WHERE post_id = ? AND retracted_at IS NULL)
+		 WHERE id = ?`,
+		postID, postID,
+	)
+	return int(n), err
+}
+
+// RetractMentions marks a post's mentions of anyone other than names, the
+// people it mentions now, as retracted. It returns how many mentions it
+// retracted.
+func (g *Graph) RetractMentions(postID int64, names []string) (int, error) {
+	query := `UPDATE mentions SET retracted_at = CURRENT_TIMESTAMP
+		 WHERE post_id = ? AND retracted_at IS NULL`
+	args := []any{postID}
+	if len(names) > 0 {
+		query += ` AND name NOT IN (` + placeholders(len(names)) + `)`
+		for _, name := range names {
+			args = append(args, name)
+		}
+	}
+	result, err := g.db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+// MarkRemovedPosts records which of a feed's posts have left it, given the
+// posts it has now, and retracts their links and mentions. Feeds only show
+// their latest entries, so only posts published since the oldest entry
+// still in the feed count as removed. It returns the number of posts
+// newly marked.
+func (g *Graph) MarkRemovedPosts(feedID int64, present []*Post, since time.Time) (int, error) {
+	if since.IsZero() {
+		return 0, nil
+	}
+	query := `SELECT id FROM posts
+		 WHERE feed_id = ? AND removed_at IS NULL AND published_at >= ?`
+	args := []any{feedID, since}
+	var keys []any
+	for _, p := range present {
+		if key := postKey(p); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > 0 {
+		query += ` AND url NOT IN (` + placeholders(len(keys)) + `)`
+		args = append(args, keys...)
+	}
+	rows, err := g.db.Query(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	var removed []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		removed = append(removed, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range removed {
+		if _, err := g.db.Exec(`UPDATE posts SET removed_at = CURRENT_TIMESTAMP WHERE id = ?`, id); err != nil {
+			return 0, err
+		}
+		if _, err := g.RetractLinks(id, nil); err != nil {
+			return 0, err
+		}
+		if _, err := g.RetractMentions(id, nil); err != nil {
+			return 0, err
+		}
+	}
+	return len(removed), nil
+}
+
+const postColumns = `id, feed_id, url, COALESCE(guid, ''), COALESCE(title, ''), COALESCE(author, ''),
+	COALESCE(content, ''), COALESCE(content_hash, ''), COALESCE(link_text, ''), published_at, updated_at, discovered_at,
+	removed_at`
+
+func scanPost(row scanner) (*Post, error) {
+	p := &Post{}
+	var published, updated, removed sql.NullTime
+	if err := row.Scan(&p.ID, &p.FeedID, &p.URL, &p.GUID, &p.Title, &p.Author,
+		&p.Content, &p.ContentHash, &p.LinkText, &published, &updated, &p.DiscoveredAt, &removed); err != nil {
+		return nil, err
+	}
+	p.PublishedAt = published.Time
+	p.UpdatedAt = updated.Time
+	p.RemovedAt = removed.Time
+	return p, nil
+}
+
+// GetPost returns a feed's post by URL (or GUID, for posts without a URL),
+// or nil if it isn't stored.
+func (g *Graph) GetPost(feedID int64, url string) (*Post, error) {
+	p, err := scanPost(g.db.QueryRow(
+		`SELECT `+postColumns+` FROM posts WHERE feed_id = ? AND url = ?`,
+		feedID, url,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// GetFeedPosts returns up to limit of a feed's posts, newest first by
+// publish date, else discovery date.
+func (g *Graph) GetFeedPosts(feedID int64, limit int) ([]Post, error) {
+	rows, err := g.db.Query(
+		`SELECT `+postColumns+` FROM posts WHERE feed_id = ?
+		 ORDER BY COALESCE(published_at, discovered_at) DESC, id DESC
+		 LIMIT ?`,
+		feedID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []Post
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, *p)
+	}
+	return posts, rows.Err()
+}
+
+// SearchPosts returns the posts matching a full-text query over titles,
+// content and link text, best match first. Queries that fail as FTS5 syntax
+// are retried as a list of quoted terms.
+func (g *Graph) SearchPosts(opts SearchOptions) ([]PostMatch, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+	if opts.HighlightStart == "" && opts.HighlightEnd == "" {
+		opts.HighlightStart, opts.HighlightEnd = "[", "]"
+	}
+
+	matches, err := g.searchPosts(opts.Query, opts)
+	if err != nil {
+		// Punctuation is FTS5 syntax: "jvns.ca" is a syntax error and
+		// "type-parameters" a column filter
+		var terms []string
+		for _, term := range strings.Fields(opts.Query) {
+			terms = append(terms, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+		}
+		var retryErr error
+		if matches, retryErr = g.searchPosts(strings.Join(terms, " "), opts); retryErr != nil {
+			return nil, err
+		}
+	}
+
+	// Look up link targets once the search rows are closed
+	for i := range matches {
+		p := matches[i].Post
+		rows, err := g.db.Query(
+			`SELECT DISTINCT `+feedColumns("f")+`
+			 FROM links l JOIN feeds f ON f.id = l.target_id
+			 WHERE l.source_id = ? AND l.post_url = ? AND l.retracted_at IS NULL
+			 ORDER BY f.id`,
+			p.FeedID, p.URL,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			f, err := scanFeed(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			matches[i].Targets = append(matches[i].Targets, f)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+func (g *Graph) searchPosts(query string, opts SearchOptions) ([]PostMatch, error) {
+	rows, err := g.db.Query(
+		`SELECT `+feedColumns("f")+`, p.id, p.feed_id, p.url, COALESCE(p.title, ''),
+		   COALESCE(p.link_text, ''), p.published_at, p.discovered_at,
+		   snippet(posts_fts, -1, ?, ?, '…', 16)
+		 FROM posts_fts
+		 JOIN posts p ON p.id = posts_fts.rowid
+		 JOIN feeds f ON f.id = p.feed_id
+		 WHERE posts_fts MATCH ?
+		 ORDER BY rank
+		 LIMIT ?`,
+		opts.HighlightStart, opts.HighlightEnd, query, opts.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []PostMatch
+	for rows.Next() {
+		var m PostMatch
+		var published sql.NullTime
+		feed, err := scanFeed(rows, &m.Post.ID, &m.Post.FeedID, &m.Post.URL, &m.Post.Title, &m.Post.LinkText,
+			&published, &m.Post.DiscoveredAt, &m.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		m.Post.PublishedAt = published.Time
+		m.Feed = feed
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// GetConversations returns the conversations between two sites, given the
+// feed IDs of each, newest first. Links are dated by publish date, else
+// discovery date; a gap longer than window ends a conversation, and runs
+// that only go one way are not conversations.
+func (g *Graph) GetConversations(a, b []int64, window time.Duration) ([]Conversation, error) {
+	aToB, err := g.GetLinksBetween(a, b)
+	if err != nil {
+		return nil, err
+	}
+	bToA, err := g.GetLinksBetween(b, a)
+	if err != nil {
+		return nil, err
+	}
+	links := append(aToB, bToA...)
+	date := func(l LinkEdge) time.Time {
+		if !l.PublishedAt.IsZero() {
+			return l.PublishedAt
+		}
+		return l.DiscoveredAt
+	}
+	sort.SliceStable(links, func(i, j int) bool { return date(links[i]).Before(date(links[j])) })
+
+	fromA := make(map[int64]bool)
+	for _, id := range a {
+		fromA[id] = true
+	}
+	var conversations []Conversation
+	flush := func(run []LinkEdge) {
+		ways := make(map[bool]bool)
+		for _, l := range run {
+			ways[fromA[l.SourceID]] = true
+		}
+		if len(ways) == 2 {
+			conversations = append(conversations, Conversation{Links: run, Start: date(run[0]), End: date(run[len(run)-1])})
+		}
+	}
+	var run []LinkEdge
+	for _, l := range links {
+		if len(run) > 0 && date(l).Sub(date(run[len(run)-1])) > window {
+			flush(run)
+			run = nil
+		}
+		run = append(run, l)
+	}
+	if len(run) > 0 {
+		flush(run)
+	}
+
+	// Newest first
+	for i, j := 0, len(conversations)-1; i < j; i, j = i+1, j-1 {
+		conversations[i], conversations[j] = conversations[j], conversations[i]
+	}
+	return conversations, nil
+}
+
+// placeholders returns n comma-separated "?" for an IN clause.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// GetMostLinked returns feeds ranked by inbound link count.
+func (g *Graph) GetMostLinked(limit int) ([]RankedFeed, error) {
+	return g.RankFeeds(RankOptions{Limit: limit})
+}
+
+// RankFeeds returns feeds ranked by (optionally weighted) inbound links.
+func (g *Graph) RankFeeds(opts RankOptions) ([]RankedFeed, error) {
+	starWeight := 1.0
+	if opts.StarWeight != nil {
+		starWeight = *opts.StarWeight
+	}
+
+	query := `SELECT ` + feedColumns("f") + `, COUNT(l.id) AS link_count,
+		   SUM(CASE WHEN l.starred = 1 THEN ? ELSE 1 END) AS score
+		 FROM feeds f
+		 JOIN links l ON f.id = l.target_id AND l.retracted_at IS NULL`
+	args := []any{starWeight}
+	if opts.Category != "" {
+		query += ` JOIN feed_tags ft ON ft.feed_id = l.source_id AND ft.tag = ?`
+		args = append(args, opts.Category)
+	}
+	if opts.Cluster != 0 {
+		query += ` JOIN feed_clusters fc ON fc.feed_id = f.id AND fc.cluster = ?`
+		args = append(args, opts.Cluster)
+	}
+	if opts.Filter {
+		cond, condArgs := g.unfiltered("f")
+		query += ` WHERE ` + cond
+		args = append(args, condArgs...)
+	}
+	query += ` GROUP BY f.id ORDER BY score DESC, link_count DESC LIMIT ?`
+	args = append(args, opts.Limit)
+
+	rows, err := g.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []RankedFeed
+	for rows.Next() {
+		var r RankedFeed
+		feed, err := scanFeed(rows, &r.InboundCount, &r.Score)
+		if err != nil {
+			return nil, err
+		}
+		r.Feed = feed
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+func scanLinks(rows *sql.Rows) ([]LinkEdge, error) {
+	var links []LinkEdge
+	for rows.Next() {
+		var link LinkEdge
+		var postURL, postTitle, context sql.NullString
+		var published, retracted sql.NullTime
+		if err := rows.Scan(&link.ID, &link.SourceID, &link.TargetID, &link.PostID, &context, &postURL, &postTitle, &published, &link.Starred, &link.DiscoveredAt, &retracted); err != nil {
+			return nil, err
+		}
+		link.RetractedAt = retracted.Time
+		link.Context = context.String
+		link.PostURL = postURL.String
+		link.PostTitle = postTitle.String
+		link.PublishedAt = published.Time
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// AddMention adds a mention to the graph, restoring it if it was retracted.
+func (g *Graph) AddMention(mention *Mention) error {
+	_, err := g.db.Exec(
+		`INSERT INTO mentions (source_id, post_id, name, entity_type, context, post_url, post_title, published_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(source_id, name, post_url) DO UPDATE SET
+		   post_id = COALESCE(excluded.post_id, mentions.post_id),
+		   retracted_at = NULL`,
+		mention.SourceID, nullID(mention.PostID), mention.Name, mention.EntityType, mention.Context, mention.PostURL, mention.PostTitle,
+		nullTime(mention.PublishedAt),
+	)
+	return err
+}
+
+// GetMostMentioned returns names ranked by mention count.
+func (g *Graph) GetMostMentioned(entityType string, limit int) ([]RankedMention, error) {
+	return g.RankMentions(MentionOptions{EntityType: entityType, Limit: limit})
+}
+
+// RankMentions returns names ranked by mention count, counting the aliases
+// of an entity under its canonical name.
+func (g *Graph) RankMentions(opts MentionOptions) ([]RankedMention, error) {
+	query := `SELECT ` + canonicalName("m") + ` AS canonical, m.entity_type, ` + mentionCount("m") + ` AS mention_count
+		 FROM mentions m` + entityJoin("m")
+	var args []any
+	if opts.Category != "" {
+		query += ` JOIN feed_tags ft ON ft.feed_id = m.source_id AND ft.tag = ?`
+		args = append(args, opts.Category)
+	}
+	if opts.Cluster != 0 {
+		query += ` JOIN feed_clusters fc ON fc.feed_id = m.source_id AND fc.cluster = ?`
+		args = append(args, opts.Cluster)
+	}
+	query += ` WHERE m.entity_type = ? AND m.retracted_at IS NULL
+		 GROUP BY canonical
+		 ORDER BY mention_count DESC
+		 LIMIT ?`
+	args = append(args, opts.EntityType, opts.Limit)
+
+	rows, err := g.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []RankedMention
+	for rows.Next() {
+		var r RankedMention
+		if err := rows.Scan(&r.Name, &r.EntityType, &r.MentionCount); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// GetMentionsByFeed returns all mentions from a specific feed.
+func (g *Graph) GetMentionsByFeed(feedID int64) ([]Mention, error) {
+	rows, err := g.db.Query(
+		`SELECT id, source_id, COALESCE(post_id, 0), name, entity_type, context, post_url, post_title, published_at, discovered_at
+		 FROM mentions WHERE source_id = ? AND retracted_at IS NULL`,
+		feedID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mentions []Mention
+	for rows.Next() {
+		var m Mention
+		var context, postURL, postTitle sql.NullString
+		var published sql.NullTime
+		if err := rows.Scan(&m.ID, &m.SourceID, &m.PostID, &m.Name, &m.EntityType, &context, &postURL, &postTitle, &published, &m.DiscoveredAt); err != nil {
+			return nil, err
+		}
+		m.Context = context.String
+		m.PostURL = postURL.String
+		m.PostTitle = postTitle.String
+		m.PublishedAt = published.Time
+		mentions = append(mentions, m)
+	}
+	return mentions, rows.Err()
+}
+
+// TakeSnapshot saves the current mention counts as a snapshot for velocity tracking.
+func (g *Graph) TakeSnapshot(date string) (int, error) {
+	// Get all current mention counts and insert as snapshot
+	result, err := g.db.Exec(`
+		INSERT OR REPLACE INTO mention_snapshots (name, entity_type, mention_count, snapshot_date)
+		SELECT `+canonicalName("m")+` AS canonical, m.entity_type, `+mentionCount("m")+` as mention_count, ?
+		FROM mentions m`+entityJoin("m")+`
+		WHERE m.retracted_at IS NULL
+		GROUP BY canonical, m.entity_type
+	`, date)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+// GetSnapshotDates returns available snapshot dates.
+func (g *Graph) GetSnapshotDates() ([]string, error) {
+	// Cast so the driver doesn't parse the DATE column into a timestamp
+	rows, err := g.db.Query(`
+		SELECT DISTINCT CAST(snapshot_date AS TEXT) AS d FROM mention_snapshots
+		ORDER BY d DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dates []string
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return nil, err
+		}
+		dates = append(dates, date)
+	}
+	return dates, rows.Err()
+}
+
+// PruneSnapshots removes snapshots older than the given date.
+func (g *Graph) PruneSnapshots(beforeDate string) (int, error) {
+	result, err := g.db.Exec(`DELETE FROM mention_snapshots WHERE snapshot_date < ?`, beforeDate)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+// GetRisingMentions returns mentions sorted by velocity (growth rate).
+func (g *Graph) GetRisingMentions(entityType string, currentDate, previousDate string, limit int) ([]RisingMention, error) {
+	// Snapshots hold the names as they were when taken, so resolve them
+	// again in case entities were merged or split since
+	snapshotCounts := `SELECT ` + canonicalName("s") + `, SUM(s.mention_count)
+		FROM mention_snapshots s` + entityJoin("s") + `
+		WHERE s.entity_type = ? AND s.snapshot_date = ?
+		GROUP BY 1`
+
+	// Get current counts
+	currentCounts, err := g.countsByName(snapshotCounts, entityType, currentDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// If no snapshot, use live counts
+	if len(currentCounts) == 0 {
+		currentCounts, err = g.countsByName(`
+			SELECT `+canonicalName("m")+`, `+mentionCount("m")+` FROM mentions m`+entityJoin("m")+`
+			WHERE m.entity_type = ? AND m.retracted_at IS NULL
+			GROUP BY 1
+		`, entityType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Get previous counts
+	previousCounts, err := g.countsByName(snapshotCounts, entityType, previousDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate velocity for each name
+	var results []RisingMention
+	for name, current := range currentCounts {
+		previous := previousCounts[name]
+		
+		var velocity float64
+		var status string
+		
+		if previous == 0 {
+			// New entry
+			velocity = float64(current)
+			status = "new"
+		} else {
+			velocity = float64(current-previous) / float64(previous)
+			if velocity > 1.0 && current >= 3 {
+				status = "hot"
+			} else if velocity > 0.5 {
+				status = "rising"
+			} else {
+				status = ""
+			}
+		}
+
+		// Only include if rising, hot, or new with decent count
+		if status != "" || (status == "new" && current >= 2) {
+			results = append(results, RisingMention{
+				Name:          name,
+				EntityType:    entityType,
+				CurrentCount:  current,
+				PreviousCount: previous,
+				Velocity:      velocity,
+				Status:        status,
+			})
+		}
+	}
+
+	// Sort by velocity descending
+	for i := 0; i < len(results); i++ {
+		for j := i + 1; j < len(results); j++ {
+			if results[j].Velocity > results[i].Velocity {
+				results[i], results[j] = results[j], results[i]
+			}
+		}
+	}
+
+	// Limit results
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// countsByName runs a query for names and counts, returning the counts.
+func (g *Graph) countsByName(query string, args ...any) (map[string]int, error) {
+	rows, err := g.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var name string
+		var count int
+		if err := rows.Scan(&name, &count); err != nil {
+			return nil, err
+		}
+		counts[name] = count
+	}
+	return counts, rows.Err()
+}
+
+// GetNewFeeds returns feeds added within the last N days.
+func (g *Graph) GetNewFeeds(days int, limit int) ([]RankedFeed, error) {
+	rows, err := g.db.Query(`
+		SELECT `+feedColumns("f")+`, COUNT(l.id) as link_count
+		FROM feeds f
+		LEFT JOIN links l ON f.id = l.target_id AND l.retracted_at IS NULL
+		WHERE f.created_at >= datetime('now', ? || ' days')
+		GROUP BY f.id
+		ORDER BY f.created_at DESC
+		LIMIT ?
+	`, -days, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []RankedFeed
+	for rows.Next() {
+		var count int
+		feed, err := scanFeed(rows, &count)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, RankedFeed{Feed: feed, InboundCount: count, Score: float64(count)})
+	}
+	return results, rows.Err()
+}
+
+// GetCrawlState returns the crawl high-water mark for a feed, or nil if the
+// feed has never been crawled.
+func (g *Graph) GetCrawlState(feedID int64) (*CrawlState, error) {
+	state := &CrawlState{FeedID: feedID}
+	var published sql.NullTime
+	err := g.db.QueryRow(
+		`SELECT last_entry_id, last_published_at, updated_at FROM crawl_state WHERE feed_id = ?`,
 		feedID,
+	).Scan(&state.LastEntryID, &published, &state.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state.LastPublishedAt = published.Time
+	return state, nil
+}
+
+// SetCrawlState records the crawl high-water mark for a feed.
+func (g *Graph) SetCrawlState(state *CrawlState) error {
+	var published any
+	if !state.LastPublishedAt.IsZero() {
+		published = state.LastPublishedAt.UTC()
+	}
+	_, err := g.db.Exec(
+		`INSERT INTO crawl_state (feed_id, last_entry_id, last_published_at, updated_at)
+		 VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		 ON CONFLICT(feed_id) DO UPDATE SET
+		   last_entry_id = excluded.last_entry_id,
+		   last_published_at = excluded.last_published_at,
+		   updated_at = excluded.updated_at`,
+		state.FeedID, state.LastEntryID, published,
+	)
+	return err
+}
+
+// unscannedSite is a condition on feeds alias t matching nodes on sites we
+// have never fetched a feed from: nothing there is subscribed, scanned or
+// crawled.
+const unscannedSite = `NOT EXISTS (
+	SELECT 1 FROM feeds s WHERE s.host = t.host AND (s.subscribed = 1
+	  OR EXISTS (SELECT 1 FROM fetch_attempts a WHERE a.feed_id = s.id)
+	  OR EXISTS (SELECT 1 FROM posts p WHERE p.feed_id = s.id)))`
+
+// SeedFrontier queues the unscanned sites our subscriptions link to, one
+// hop out, and refreshes the priorities of everything still queued. It
+// returns the number of sites newly queued.
+func (g *Graph) SeedFrontier() (int, error) {
+	before, err := g.countFrontier()
+	if err != nil {
+		return 0, err
+	}
+	if err := g.enqueueTargets(`SELECT id FROM feeds WHERE subscribed = 1`, nil, 1); err != nil {
+		return 0, err
+	}
+	_, err = g.db.Exec(
+		`UPDATE frontier SET priority = (
+		   SELECT COUNT(DISTINCT source_id) FROM links
+		   WHERE target_id = frontier.feed_id AND retracted_at IS NULL)
+		 WHERE status = ?`,
+		FrontierQueued,
 	)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
+	after, err := g.countFrontier()
+	return after - before, err
+}
 
-	var mentions []Mention
+// EnqueueTargets queues the unscanned sites a feed links to at the given
+// depth. Sites already queued keep the smaller depth. It returns the number
+// of sites newly queued.
+func (g *Graph) EnqueueTargets(feedID int64, depth int) (int, error) {
+	before, err := g.countFrontier()
+	if err != nil {
+		return 0, err
+	}
+	if err := g.enqueueTargets(`SELECT ?`, []any{feedID}, depth); err != nil {
+		return 0, err
+	}
+	after, err := g.countFrontier()
+	return after - before, err
+}
+
+func (g *Graph) enqueueTargets(sources string, args []any, depth int) error {
+	args = append([]any{depth}, args...)
+	_, err := g.db.Exec(
+		`INSERT INTO frontier (feed_id, depth, priority, status)
+		 SELECT t.id, ?, (SELECT COUNT(DISTINCT source_id) FROM links
+		                  WHERE target_id = t.id AND retracted_at IS NULL), 'queued'
+		 FROM feeds t
+		 WHERE t.id IN (SELECT target_id FROM links
+		                WHERE source_id IN (`+sources+`) AND retracted_at IS NULL)
+		   AND `+unscannedSite+`
+		 ON CONFLICT(feed_id) DO UPDATE SET
+		   depth = MIN(frontier.depth, excluded.depth),
+		   priority = excluded.priority
+		 WHERE frontier.status = 'queued'`,
+		args...,
+	)
+	return err
+}
+
+func (g *Graph) countFrontier() (int, error) {
+	var n int
+	err := g.db.QueryRow(`SELECT COUNT(*) FROM frontier`).Scan(&n)
+	return n, err
+}
+
+const frontierColumns = `f.feed_id, t.url, f.depth, f.priority, f.status,
+	COALESCE(f.feed_url, ''), COALESCE(f.error, ''), f.updated_at`
+
+func scanFrontier(rows *sql.Rows) ([]FrontierEntry, error) {
+	var entries []FrontierEntry
 	for rows.Next() {
-		var m Mention
-		var context, postURL, postTitle sql.NullString
-		if err := rows.Scan(&m.ID, &m.SourceID, &m.Name, &m.EntityType, &context, &postURL, &postTitle, &m.DiscoveredAt); err != nil {
+		var e FrontierEntry
+		if err := rows.Scan(&e.FeedID, &e.URL, &e.Depth, &e.Priority, &e.Status,
+			&e.FeedURL, &e.Error, &e.UpdatedAt); err != nil {
 			return nil, err
 		}
-		m.Context = context.String
-		m.PostURL = postURL.String
-		m.PostTitle = postTitle.String
-		mentions = append(mentions, m)
+		entries = append(entries, e)
 	}
-	return mentions, rows.Err()
+	return entries, rows.Err()
 }
 
-// TakeSnapshot saves the current mention counts as a snapshot for velocity tracking.
-func (g *Graph) TakeSnapshot(date string) (int, error) {
-	// Get all current mention counts and insert as snapshot
-	result, err := g.db.Exec(`
-		INSERT OR REPLACE INTO mention_snapshots (name, entity_type, mention_count, snapshot_date)
-		SELECT name, entity_type, COUNT(*) as mention_count, ?
-		FROM mentions
-		GROUP BY name, entity_type
-	`, date)
+// NextFrontier returns up to limit queued sites at most maxDepth hops out
+// with at least minPriority inbound feeds, highest priority first. Sites
+// that have since been scanned some other way are marked done and left out.
+func (g *Graph) NextFrontier(maxDepth int, minPriority float64, limit int) ([]FrontierEntry, error) {
+	_, err := g.db.Exec(
+		`UPDATE frontier SET status = ?, updated_at = CURRENT_TIMESTAMP
+		 WHERE status = ? AND feed_id IN (SELECT t.id FROM feeds t WHERE NOT `+unscannedSite+`)`,
+		FrontierDone, FrontierQueued,
+	)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	n, _ := result.RowsAffected()
-	return int(n), nil
+
+	rows, err := g.db.Query(
+		`SELECT `+frontierColumns+`
+		 FROM frontier f JOIN feeds t ON t.id = f.feed_id
+		 WHERE f.status = ? AND f.depth <= ? AND f.priority >= ?
+		 ORDER BY f.priority DESC, f.depth, f.feed_id
+		 LIMIT ?`,
+		FrontierQueued, maxDepth, minPriority, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFrontier(rows)
 }
 
-// GetSnapshotDates returns available snapshot dates.
-func (g *Graph) GetSnapshotDates() ([]string, error) {
-	rows, err := g.db.Query(`
-		SELECT DISTINCT snapshot_date FROM mention_snapshots
-		ORDER BY snapshot_date DESC
-	`)
+// GetFrontier returns the sites in the frontier with the given status, or
+// all of them if status is empty, highest priority first.
+func (g *Graph) GetFrontier(status string, limit int) ([]FrontierEntry, error) {
+	rows, err := g.db.Query(
+		`SELECT `+frontierColumns+`
+		 FROM frontier f JOIN feeds t ON t.id = f.feed_id
+		 WHERE ? = '' OR f.status = ?
+		 ORDER BY f.priority DESC, f.depth, f.feed_id
+		 LIMIT ?`,
+		status, status, limit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	return scanFrontier(rows)
+}
 
-	var dates []string
+// CountFrontier returns the number of sites in the frontier by status.
+func (g *Graph) CountFrontier() (map[string]int, error) {
+	rows, err := g.db.Query(`SELECT status, COUNT(*) FROM frontier GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
 	for rows.Next() {
-		var date string
-		if err := rows.Scan(&date); err != nil {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
 			return nil, err
 		}
-		dates = append(dates, date)
+		counts[status] = n
 	}
-	return dates, rows.Err()
+	return counts, rows.Err()
 }
 
-// PruneSnapshots removes snapshots older than the given date.
-func (g *Graph) PruneSnapshots(beforeDate string) (int, error) {
-	result, err := g.db.Exec(`DELETE FROM mention_snapshots WHERE snapshot_date < ?`, beforeDate)
+// SetFrontierStatus records the outcome of expanding a site: the feed found
+// for it, if any, and why it failed or was skipped.
+func (g *Graph) SetFrontierStatus(feedID int64, status, feedURL, reason string) error {
+	_, err := g.db.Exec(
+		`UPDATE frontier SET status = ?, feed_url = COALESCE(?, feed_url), error = ?,
+		   updated_at = CURRENT_TIMESTAMP
+		 WHERE feed_id = ?`,
+		status, nullString(feedURL), nullString(reason), feedID,
+	)
+	return err
+}
+
+// RequeueFrontier puts failed and skipped sites back in the queue,
+// returning how many there were.
+func (g *Graph) RequeueFrontier() (int, error) {
+	result, err := g.db.Exec(
+		`UPDATE frontier SET status = ?, error = NULL, updated_at = CURRENT_TIMESTAMP
+		 WHERE status IN (?, ?)`,
+		FrontierQueued, FrontierFailed, FrontierSkipped,
+	)
 	if err != nil {
 		return 0, err
 	}
@@ -391,147 +2030,334 @@ func (g *Graph) PruneSnapshots(beforeDate string) (int, error) {
 	return int(n), nil
 }
 
-// GetRisingMentions returns mentions sorted by velocity (growth rate).
-func (g *Graph) GetRisingMentions(entityType string, currentDate, previousDate string, limit int) ([]RisingMention, error) {
-	// Get current counts
-	currentCounts := make(map[string]int)
-	rows, err := g.db.Query(`
-		SELECT name, mention_count FROM mention_snapshots
-		WHERE entity_type = ? AND snapshot_date = ?
-	`, entityType, currentDate)
+// GetSchedules returns the daemon's polling schedule, keyed by feed ID.
+// Feeds it hasn't polled yet have no entry.
+func (g *Graph) GetSchedules() (map[int64]FeedSchedule, error) {
+	rows, err := g.db.Query(`SELECT feed_id, interval_seconds, next_fetch_at, failures FROM feed_schedule`)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	schedules := make(map[int64]FeedSchedule)
 	for rows.Next() {
-		var name string
-		var count int
-		if err := rows.Scan(&name, &count); err != nil {
-			rows.Close()
+		var s FeedSchedule
+		var seconds int64
+		if err := rows.Scan(&s.FeedID, &seconds, &s.NextFetch, &s.Failures); err != nil {
 			return nil, err
 		}
-		currentCounts[name] = count
+		s.Interval = time.Duration(seconds) * time.Second
+		schedules[s.FeedID] = s
 	}
-	rows.Close()
+	return schedules, rows.Err()
+}
 
-	// If no snapshot, use live counts
-	if len(currentCounts) == 0 {
-		liveRows, err := g.db.Query(`
-			SELECT name, COUNT(*) as mention_count FROM mentions
-			WHERE entity_type = ?
-			GROUP BY name
-		`, entityType)
+// SetSchedule records when the daemon next polls a feed.
+func (g *Graph) SetSchedule(s *FeedSchedule) error {
+	_, err := g.db.Exec(
+		`INSERT INTO feed_schedule (feed_id, interval_seconds, next_fetch_at, failures, updated_at)
+		 VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		 ON CONFLICT(feed_id) DO UPDATE SET
+		   interval_seconds = excluded.interval_seconds,
+		   next_fetch_at = excluded.next_fetch_at,
+		   failures = excluded.failures,
+		   updated_at = excluded.updated_at`,
+		s.FeedID, int64(s.Interval/time.Second), s.NextFetch.UTC(), s.Failures,
+	)
+	return err
+}
+
+const webSubColumns = `feed_id, hub, topic, secret, state, lease_expires_at, COALESCE(error, ''), updated_at`
+
+func scanWebSub(row scanner) (*WebSubSubscription, error) {
+	var s WebSubSubscription
+	var lease sql.NullTime
+	if err := row.Scan(&s.FeedID, &s.Hub, &s.Topic, &s.Secret, &s.State, &lease, &s.Error, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	s.LeaseExpires = lease.Time
+	return &s, nil
+}
+
+// GetWebSub returns a feed's WebSub subscription, or nil if it has none.
+func (g *Graph) GetWebSub(feedID int64) (*WebSubSubscription, error) {
+	s, err := scanWebSub(g.db.QueryRow(`SELECT `+webSubColumns+` FROM websub_subscriptions WHERE feed_id = ?`, feedID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// GetWebSubs returns every WebSub subscription, soonest lease expiry first.
+func (g *Graph) GetWebSubs() ([]WebSubSubscription, error) {
+	rows, err := g.db.Query(`SELECT ` + webSubColumns + ` FROM websub_subscriptions
+		ORDER BY lease_expires_at IS NULL, lease_expires_at, feed_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []WebSubSubscription
+	for rows.Next() {
+		s, err := scanWebSub(rows)
 		if err != nil {
 			return nil, err
 		}
-		for liveRows.Next() {
-			var name string
-			var count int
-			if err := liveRows.Scan(&name, &count); err != nil {
-				liveRows.Close()
-				return nil, err
-			}
-			currentCounts[name] = count
+		subs = append(subs, *s)
+	}
+	return subs, rows.Err()
+}
+
+// SetWebSub creates or replaces a feed's WebSub subscription.
+func (g *Graph) SetWebSub(s *WebSubSubscription) error {
+	_, err := g.db.Exec(
+		`INSERT INTO websub_subscriptions (feed_id, hub, topic, secret, state, lease_expires_at, error, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		 ON CONFLICT(feed_id) DO UPDATE SET
+		   hub = excluded.hub,
+		   topic = excluded.topic,
+		   secret = excluded.secret,
+		   state = excluded.state,
+		   lease_expires_at = excluded.lease_expires_at,
+		   error = excluded.error,
+		   updated_at = excluded.updated_at`,
+		s.FeedID, s.Hub, s.Topic, s.Secret, s.State, nullTime(s.LeaseExpires), nullString(s.Error),
+	)
+	return err
+}
+
+// DeleteWebSub removes a feed's WebSub subscription.
+func (g *Graph) DeleteWebSub(feedID int64) error {
+	_, err := g.db.Exec(`DELETE FROM websub_subscriptions WHERE feed_id = ?`, feedID)
+	return err
+}
+
+// RecordFetch stores a fetch attempt, setting its ID.
+func (g *Graph) RecordFetch(attempt *FetchAttempt) error {
+	if attempt.AttemptedAt.IsZero() {
+		attempt.AttemptedAt = time.Now()
+	}
+	result, err := g.db.Exec(
+		`INSERT INTO fetch_attempts (feed_id, attempted_at, status, latency_ms, error_class, error,
+		   bytes, item_count, newest_item_at, moved_to)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		attempt.FeedID, attempt.AttemptedAt.UTC(), attempt.Status, attempt.Latency.Milliseconds(),
+		nullString(attempt.ErrorClass), nullString(attempt.Error), attempt.Bytes, attempt.ItemCount,
+		nullTime(attempt.NewestItemAt), nullString(attempt.MovedTo),
+	)
+	if err != nil {
+		return err
+	}
+	attempt.ID, err = result.LastInsertId()
+	return err
+}
+
+// GetFetchAttempts returns a feed's latest fetch attempts, newest first.
+func (g *Graph) GetFetchAttempts(feedID int64, limit int) ([]FetchAttempt, error) {
+	rows, err := g.db.Query(
+		`SELECT id, feed_id, attempted_at, status, latency_ms, COALESCE(error_class, ''), COALESCE(error, ''),
+		   bytes, item_count, newest_item_at, COALESCE(moved_to, '')
+		 FROM fetch_attempts WHERE feed_id = ?
+		 ORDER BY attempted_at DESC, id DESC
+		 LIMIT ?`,
+		feedID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []FetchAttempt
+	for rows.Next() {
+		var a FetchAttempt
+		var latency int64
+		var newest sql.NullTime
+		if err := rows.Scan(&a.ID, &a.FeedID, &a.AttemptedAt, &a.Status, &latency, &a.ErrorClass, &a.Error,
+			&a.Bytes, &a.ItemCount, &newest, &a.MovedTo); err != nil {
+			return nil, err
 		}
-		liveRows.Close()
+		a.Latency = time.Duration(latency) * time.Millisecond
+		a.NewestItemAt = newest.Time
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+// GetFeedHealth checks every subscribed or fetched feed against its fetch
+// history and posts, worst first. A feed is dead after opts.Failures
+// consecutive failed fetches, malformed if its latest fetch couldn't be
+// parsed, moved if it permanently redirects elsewhere and stale if its
+// newest post is older than opts.StaleSince.
+func (g *Graph) GetFeedHealth(opts HealthOptions) ([]FeedHealth, error) {
+	if opts.Failures <= 0 {
+		opts.Failures = 3
 	}
 
-	// Get previous counts
-	previousCounts := make(map[string]int)
-	rows, err = g.db.Query(`
-		SELECT name, mention_count FROM mention_snapshots
-		WHERE entity_type = ? AND snapshot_date = ?
-	`, entityType, previousDate)
+	rows, err := g.db.Query(
+		`SELECT ` + feedColumns("f") + `,
+		   (SELECT COUNT(*) FROM fetch_attempts a WHERE a.feed_id = f.id)
+		 FROM feeds f
+		 WHERE f.subscribed = 1 OR f.id IN (SELECT feed_id FROM fetch_attempts)
+		 ORDER BY f.title, f.url`,
+	)
 	if err != nil {
 		return nil, err
 	}
+	var results []FeedHealth
 	for rows.Next() {
-		var name string
-		var count int
-		if err := rows.Scan(&name, &count); err != nil {
+		var h FeedHealth
+		if h.Feed, err = scanFeed(rows, &h.Attempts); err != nil {
 			rows.Close()
 			return nil, err
 		}
-		previousCounts[name] = count
+		results = append(results, h)
 	}
 	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	// Calculate velocity for each name
-	var results []RisingMention
-	for name, current := range currentCounts {
-		previous := previousCounts[name]
-		
-		var velocity float64
-		var status string
-		
-		if previous == 0 {
-			// New entry
-			velocity = float64(current)
-			status = "new"
-		} else {
-			velocity = float64(current-previous) / float64(previous)
-			if velocity > 1.0 && current >= 3 {
-				status = "hot"
-			} else if velocity > 0.5 {
-				status = "rising"
-			} else {
-				status = ""
+	for i := range results {
+		h := &results[i]
+		attempts, err := g.GetFetchAttempts(h.Feed.ID, opts.Failures)
+		if err != nil {
+			return nil, err
+		}
+		if h.LastSuccess, err = g.latestTime(
+			`SELECT attempted_at FROM fetch_attempts WHERE feed_id = ? AND error_class IS NULL
+			 ORDER BY attempted_at DESC LIMIT 1`, h.Feed.ID); err != nil {
+			return nil, err
+		}
+		if h.NewestPost, err = g.latestTime(
+			`SELECT published_at FROM posts WHERE feed_id = ? AND published_at IS NOT NULL
+			 ORDER BY published_at DESC LIMIT 1`, h.Feed.ID); err != nil {
+			return nil, err
+		}
+		newestItem, err := g.latestTime(
+			`SELECT newest_item_at FROM fetch_attempts WHERE feed_id = ? AND newest_item_at IS NOT NULL
+			 ORDER BY newest_item_at DESC LIMIT 1`, h.Feed.ID)
+		if err != nil {
+			return nil, err
+		}
+		if newestItem.After(h.NewestPost) {
+			h.NewestPost = newestItem
+		}
+		for _, a := range attempts {
+			if !a.Failed() || a.ErrorClass == ErrorClassParse {
+				break
 			}
+			h.Failures++
+		}
+		if len(attempts) > 0 {
+			h.Last = &attempts[0]
 		}
 
-		// Only include if rising, hot, or new with decent count
-		if status != "" || (status == "new" && current >= 2) {
-			results = append(results, RisingMention{
-				Name:          name,
-				EntityType:    entityType,
-				CurrentCount:  current,
-				PreviousCount: previous,
-				Velocity:      velocity,
-				Status:        status,
-			})
+		switch {
+		case h.Failures >= opts.Failures:
+			h.Status = HealthDead
+		case h.Last != nil && h.Last.ErrorClass == ErrorClassParse:
+			h.Status = HealthMalformed
+		case h.Last != nil && h.Last.MovedTo != "" && h.Last.MovedTo != h.Feed.URL:
+			h.Status = HealthMoved
+			h.MovedTo = h.Last.MovedTo
+		case !opts.StaleSince.IsZero() && !h.NewestPost.IsZero() && h.NewestPost.Before(opts.StaleSince):
+			h.Status = HealthStale
+		default:
+			h.Status = HealthOK
 		}
 	}
 
-	// Sort by velocity descending
-	for i := 0; i < len(results); i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[j].Velocity > results[i].Velocity {
-				results[i], results[j] = results[j], results[i]
-			}
-		}
+	severity := map[string]int{HealthDead: 0, HealthMalformed: 1, HealthMoved: 2, HealthStale: 3, HealthOK: 4}
+	sort.SliceStable(results, func(i, j int) bool {
+		return severity[results[i].Status] < severity[results[j].Status]
+	})
+	return results, nil
+}
+
+// latestTime runs a query for a single nullable timestamp, returning zero
+// if there is no row.
+func (g *Graph) latestTime(query string, args ...any) (time.Time, error) {
+	var t sql.NullTime
+	err := g.db.QueryRow(query, args...).Scan(&t)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
 	}
+	return t.Time, err
+}
 
-	// Limit results
-	if len(results) > limit {
-		results = results[:limit]
+// UpdateFeedURL changes a feed's URL, e.g. after it has permanently moved.
+// It fails if another feed already has the new URL.
+func (g *Graph) UpdateFeedURL(feedID int64, url string) error {
+	existing, err := g.GetFeedByURL(url)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != feedID {
+		return fmt.Errorf("feed %s already exists (id: %d)", url, existing.ID)
+	}
+	_, err = g.db.Exec(`UPDATE feeds SET url = ?, host = ? WHERE id = ?`, url, siteHost(url), feedID)
+	return err
+}
+
+// DetectClusters finds communities in the link graph and replaces the stored
+// cluster assignments, returning the number of clusters. All feeds on a site
+// are clustered together; feeds without links get no cluster.
+func (g *Graph) DetectClusters() (int, error) {
+	network, err := g.LoadNetwork()
+	if err != nil {
+		return 0, err
 	}
+	merged := network.MergeHosts()
+	communities := merged.Communities()
 
-	return results, nil
+	tx, err := g.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM feed_clusters`); err != nil {
+		return 0, err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO feed_clusters (feed_id, cluster) VALUES (?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	clusters := make(map[int]bool)
+	for id, cluster := range communities {
+		clusters[cluster] = true
+		for _, member := range merged.Members[id] {
+			if _, err := stmt.Exec(member, cluster); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(clusters), tx.Commit()
 }
 
-// GetNewFeeds returns feeds added within the last N days.
-func (g *Graph) GetNewFeeds(days int, limit int) ([]RankedFeed, error) {
-	rows, err := g.db.Query(`
-		SELECT f.id, f.url, f.title, f.created_at, COUNT(l.id) as link_count
-		FROM feeds f
-		LEFT JOIN links l ON f.id = l.target_id
-		WHERE f.created_at >= datetime('now', ? || ' days')
-		GROUP BY f.id
-		ORDER BY f.created_at DESC
-		LIMIT ?
-	`, -days, limit)
+// GetClusters returns the stored clusters with at least minSize feeds,
+// largest first.
+func (g *Graph) GetClusters(minSize int) ([]Cluster, error) {
+	rows, err := g.db.Query(
+		`SELECT cluster, COUNT(*) AS size FROM feed_clusters
+		 GROUP BY cluster HAVING size >= ?
+		 ORDER BY size DESC, cluster`,
+		minSize,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var results []RankedFeed
+	var clusters []Cluster
 	for rows.Next() {
-		feed := &FeedNode{}
-		var count int
-		if err := rows.Scan(&feed.ID, &feed.URL, &feed.Title, &feed.CreatedAt, &count); err != nil {
+		var c Cluster
+		if err := rows.Scan(&c.ID, &c.Size); err != nil {
 			return nil, err
 		}
-		results = append(results, RankedFeed{Feed: feed, InboundCount: count})
+		clusters = append(clusters, c)
 	}
-	return results, rows.Err()
+	return clusters, rows.Err()
 }
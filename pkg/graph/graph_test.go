@@ -1,8 +1,10 @@
 package graph
 
 import (
+	"database/sql"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -22,6 +24,27 @@ func TestNewGraph_CreatesDatabase(t *testing.T) {
 	}
 }
 
+func TestNewGraph_URIPath(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	g, err := NewGraph("file:" + dbPath + "?cache=private")
+	if err != nil {
+		t.Fatalf("NewGraph error: %v", err)
+	}
+	defer g.Close()
+	if _, err := g.AddFeed(&FeedNode{URL: "https://jvns.ca/"}); err != nil {
+		t.Fatalf("AddFeed error: %v", err)
+	}
+
+	var timeout int
+	if err := g.db.QueryRow(`PRAGMA busy_timeout`).Scan(&timeout); err != nil || timeout != 5000 {
+		t.Errorf("Expected the busy timeout kept alongside the URI's parameters, got %d (%v)", timeout, err)
+	}
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		t.Error("Database file was not created at the URI's path")
+	}
+}
+
 func TestGraph_AddFeed(t *testing.T) {
 	g := newTestGraph(t)
 	defer g.Close()
@@ -87,6 +110,58 @@ func TestGraph_GetFeedByURL(t *testing.T) {
 	}
 }
 
+func TestGraph_GetFeedByID(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	id, _ := g.AddFeed(&FeedNode{URL: "https://hamel.dev/", Title: "Hamel's Blog"})
+
+	found, err := g.GetFeedByID(id)
+	if err != nil {
+		t.Fatalf("GetFeedByID error: %v", err)
+	}
+	if found == nil || found.URL != "https://hamel.dev/" {
+		t.Errorf("Expected hamel.dev, got %+v", found)
+	}
+
+	missing, err := g.GetFeedByID(id + 100)
+	if err != nil || missing != nil {
+		t.Errorf("Expected nil for unknown ID, got %v, %v", missing, err)
+	}
+}
+
+func TestGraph_ListFeeds(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	g.AddFeed(&FeedNode{URL: "https://simonwillison.net/atom/everything/", Title: "Simon Willison", Subscribed: true})
+	g.AddFeed(&FeedNode{URL: "https://jvns.ca/atom.xml", Title: "Julia Evans"})
+	g.AddFeed(&FeedNode{URL: "https://example.com/", Title: "Example"})
+
+	feeds, total, err := g.ListFeeds(FeedQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListFeeds error: %v", err)
+	}
+	if total != 3 || len(feeds) != 2 {
+		t.Errorf("Expected 2 of 3 feeds, got %d of %d", len(feeds), total)
+	}
+
+	feeds, _, _ = g.ListFeeds(FeedQuery{Limit: 2, Offset: 2})
+	if len(feeds) != 1 || feeds[0].Title != "Example" {
+		t.Errorf("Expected Example on the second page, got %+v", feeds)
+	}
+
+	feeds, total, _ = g.ListFeeds(FeedQuery{Search: "julia"})
+	if total != 1 || feeds[0].URL != "https://jvns.ca/atom.xml" {
+		t.Errorf("Expected search to match by title, got %+v", feeds)
+	}
+
+	feeds, total, _ = g.ListFeeds(FeedQuery{Subscribed: true})
+	if total != 1 || feeds[0].Title != "Simon Willison" {
+		t.Errorf("Expected only the subscription, got %+v", feeds)
+	}
+}
+
 func TestGraph_GetFeedByURL_NotFound(t *testing.T) {
 	g := newTestGraph(t)
 	defer g.Close()
@@ -178,6 +253,32 @@ func TestGraph_GetInboundLinks(t *testing.T) {
 	}
 }
 
+func TestGraph_GetLinksBetween(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	a, _ := g.AddFeed(&FeedNode{URL: "https://a.com/"})
+	b, _ := g.AddFeed(&FeedNode{URL: "https://b.com/"})
+	c, _ := g.AddFeed(&FeedNode{URL: "https://c.com/"})
+	g.AddLink(&LinkEdge{SourceID: a, TargetID: b, PostURL: "https://a.com/old", PublishedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)})
+	g.AddLink(&LinkEdge{SourceID: a, TargetID: b, PostURL: "https://a.com/new", PublishedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)})
+	g.AddLink(&LinkEdge{SourceID: a, TargetID: c, PostURL: "https://a.com/other"})
+	g.AddLink(&LinkEdge{SourceID: b, TargetID: a, PostURL: "https://b.com/reply"})
+
+	links, err := g.GetLinksBetween([]int64{a}, []int64{b})
+	if err != nil {
+		t.Fatalf("GetLinksBetween error: %v", err)
+	}
+	if len(links) != 2 || links[0].PostURL != "https://a.com/new" {
+		t.Errorf("Expected 2 links a -> b newest first, got %+v", links)
+	}
+
+	links, _ = g.GetLinksBetween([]int64{a, b}, []int64{a, c})
+	if len(links) != 2 {
+		t.Errorf("Expected a -> c and b -> a, got %d links", len(links))
+	}
+}
+
 func TestGraph_GetMostLinked(t *testing.T) {
 	g := newTestGraph(t)
 	defer g.Close()
@@ -238,6 +339,870 @@ func TestGraph_LinkTimestamp(t *testing.T) {
 	}
 }
 
+func TestGraph_AddFeed_PromotesToSubscription(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	// Discovered first as a link target, with anchor text as title
+	id1, _ := g.AddFeed(&FeedNode{URL: "https://jvns.ca/atom.xml", Title: "this post"})
+
+	id2, err := g.AddFeed(&FeedNode{
+		URL:        "https://jvns.ca/atom.xml",
+		Title:      "Julia Evans",
+		SiteURL:    "https://jvns.ca/",
+		Subscribed: true,
+		Source:     "opml",
+	})
+	if err != nil {
+		t.Fatalf("AddFeed error: %v", err)
+	}
+	if id1 != id2 {
+		t.Errorf("Expected same ID, got %d and %d", id1, id2)
+	}
+
+	found, _ := g.GetFeedByURL("https://jvns.ca/atom.xml")
+	if !found.Subscribed {
+		t.Error("Expected feed to be subscribed")
+	}
+	if found.Title != "Julia Evans" || found.Source != "opml" || found.SiteURL != "https://jvns.ca/" {
+		t.Errorf("Expected subscription metadata, got %+v", found)
+	}
+
+	// Re-adding as a plain link target must not unsubscribe it
+	g.AddFeed(&FeedNode{URL: "https://jvns.ca/atom.xml", Title: "another post"})
+	found, _ = g.GetFeedByURL("https://jvns.ca/atom.xml")
+	if !found.Subscribed || found.Title != "Julia Evans" {
+		t.Errorf("Expected subscription to be kept, got %+v", found)
+	}
+}
+
+func TestGraph_GetRecommendations(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	// A and B are subscriptions; C is discovered only.
+	aID, _ := g.AddFeed(&FeedNode{URL: "https://a.com/feed.xml", SiteURL: "https://a.com/", Subscribed: true})
+	bID, _ := g.AddFeed(&FeedNode{URL: "https://www.b.com/feed.xml", Subscribed: true})
+	cID, _ := g.AddFeed(&FeedNode{URL: "https://c.com/"})
+
+	popular, _ := g.AddFeed(&FeedNode{URL: "https://popular.com/", Title: "Popular"})
+	niche, _ := g.AddFeed(&FeedNode{URL: "https://niche.com/", Title: "Niche"})
+	bSite, _ := g.AddFeed(&FeedNode{URL: "https://b.com/"})
+
+	g.AddLink(&LinkEdge{SourceID: aID, TargetID: popular, PostURL: "https://a.com/1"})
+	g.AddLink(&LinkEdge{SourceID: aID, TargetID: popular, PostURL: "https://a.com/2"})
+	g.AddLink(&LinkEdge{SourceID: bID, TargetID: popular, PostURL: "https://b.com/1"})
+	g.AddLink(&LinkEdge{SourceID: aID, TargetID: niche, PostURL: "https://a.com/3"})
+	g.AddLink(&LinkEdge{SourceID: aID, TargetID: bSite, PostURL: "https://a.com/4"})
+	// Links from unsubscribed feeds don't count
+	g.AddLink(&LinkEdge{SourceID: cID, TargetID: niche, PostURL: "https://c.com/1"})
+
+	recs, err := g.GetRecommendations(false)
+	if err != nil {
+		t.Fatalf("GetRecommendations error: %v", err)
+	}
+
+	if len(recs) != 2 {
+		t.Fatalf("Expected 2 recommendations, got %d: %+v", len(recs), recs)
+	}
+	if recs[0].Feed.URL != "https://popular.com/" {
+		t.Errorf("Expected popular.com first, got %s", recs[0].Feed.URL)
+	}
+	if recs[0].CitingFeeds != 2 || recs[0].LinkCount != 3 {
+		t.Errorf("Expected 2 citing feeds and 3 links, got %d and %d", recs[0].CitingFeeds, recs[0].LinkCount)
+	}
+	if recs[1].CitingFeeds != 1 {
+		t.Errorf("Expected niche.com to have 1 citing feed, got %d", recs[1].CitingFeeds)
+	}
+}
+
+func TestNewGraph_MigratesOldSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+
+	// Feeds table as created by earlier versions
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE feeds (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			url TEXT UNIQUE NOT NULL,
+			title TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		INSERT INTO feeds (url, title) VALUES ('https://example.com/', 'Example');
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("Creating old schema: %v", err)
+	}
+
+	g, err := NewGraph(dbPath)
+	if err != nil {
+		t.Fatalf("NewGraph error: %v", err)
+	}
+	defer g.Close()
+
+	found, err := g.GetFeedByURL("https://example.com/")
+	if err != nil || found == nil {
+		t.Fatalf("Expected migrated feed, got %v, %v", found, err)
+	}
+	if found.Subscribed {
+		t.Error("Expected existing feeds to default to unsubscribed")
+	}
+
+	byHost, err := g.FindFeeds("example.com")
+	if err != nil || len(byHost) != 1 {
+		t.Errorf("Expected host backfilled for existing feeds, got %v, %v", byHost, err)
+	}
+}
+
+func TestGraph_FindFeeds(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	rootID, _ := g.AddFeed(&FeedNode{URL: "https://jvns.ca/"})
+	feedID, _ := g.AddFeed(&FeedNode{URL: "https://jvns.ca/atom.xml", Subscribed: true})
+	wwwID, _ := g.AddFeed(&FeedNode{URL: "https://www.example.com/"})
+
+	tests := []struct {
+		ref  string
+		want []int64
+	}{
+		{"https://jvns.ca/atom.xml", []int64{feedID}},
+		{"https://jvns.ca", []int64{rootID}},
+		{"jvns.ca", []int64{feedID, rootID}},
+		{"https://JVNS.ca/blog/", []int64{feedID, rootID}},
+		{"example.com", []int64{wwwID}},
+		{"nowhere.example.org", nil},
+	}
+	for _, tt := range tests {
+		feeds, err := g.FindFeeds(tt.ref)
+		if err != nil {
+			t.Fatalf("FindFeeds(%q) error: %v", tt.ref, err)
+		}
+		var got []int64
+		for _, f := range feeds {
+			got = append(got, f.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("FindFeeds(%q) = %v, want %v", tt.ref, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("FindFeeds(%q) = %v, want %v", tt.ref, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestGraph_CrawlState(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	id, _ := g.AddFeed(&FeedNode{URL: "https://a.com/feed.xml", Subscribed: true})
+
+	state, err := g.GetCrawlState(id)
+	if err != nil {
+		t.Fatalf("GetCrawlState error: %v", err)
+	}
+	if state != nil {
+		t.Fatalf("Expected no state for uncrawled feed, got %+v", state)
+	}
+
+	published := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := g.SetCrawlState(&CrawlState{FeedID: id, LastEntryID: 120, LastPublishedAt: published}); err != nil {
+		t.Fatalf("SetCrawlState error: %v", err)
+	}
+	if err := g.SetCrawlState(&CrawlState{FeedID: id, LastEntryID: 150, LastPublishedAt: published}); err != nil {
+		t.Fatalf("SetCrawlState update error: %v", err)
+	}
+
+	state, err = g.GetCrawlState(id)
+	if err != nil || state == nil {
+		t.Fatalf("Expected state, got %v, %v", state, err)
+	}
+	if state.LastEntryID != 150 {
+		t.Errorf("Expected last entry 150, got %d", state.LastEntryID)
+	}
+	if !state.LastPublishedAt.Equal(published) {
+		t.Errorf("Expected published %v, got %v", published, state.LastPublishedAt)
+	}
+}
+
+func TestGraph_RankFeeds_Category(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	// A is tagged "AI"; B isn't. A cites X, B cites Y twice.
+	aID, _ := g.AddFeed(&FeedNode{URL: "https://a.com/", Subscribed: true})
+	bID, _ := g.AddFeed(&FeedNode{URL: "https://b.com/", Subscribed: true})
+	xID, _ := g.AddFeed(&FeedNode{URL: "https://x.com/"})
+	yID, _ := g.AddFeed(&FeedNode{URL: "https://y.com/"})
+	g.AddFeedTag(aID, "AI")
+
+	g.AddLink(&LinkEdge{SourceID: aID, TargetID: xID, PostURL: "https://a.com/1"})
+	g.AddLink(&LinkEdge{SourceID: bID, TargetID: yID, PostURL: "https://b.com/1"})
+	g.AddLink(&LinkEdge{SourceID: bID, TargetID: yID, PostURL: "https://b.com/2"})
+
+	ranked, err := g.RankFeeds(RankOptions{Limit: 10, Category: "ai"})
+	if err != nil {
+		t.Fatalf("RankFeeds error: %v", err)
+	}
+	if len(ranked) != 1 || ranked[0].Feed.URL != "https://x.com/" {
+		t.Errorf("Expected only x.com for category AI, got %+v", ranked)
+	}
+
+	tags, _ := g.GetFeedTags(aID)
+	if len(tags) != 1 || tags[0] != "AI" {
+		t.Errorf("Expected tag AI, got %v", tags)
+	}
+}
+
+func TestGraph_RankFeeds_StarWeight(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	aID, _ := g.AddFeed(&FeedNode{URL: "https://a.com/"})
+	xID, _ := g.AddFeed(&FeedNode{URL: "https://x.com/"})
+	yID, _ := g.AddFeed(&FeedNode{URL: "https://y.com/"})
+
+	// Y has more links, but X's one link comes from a starred post
+	g.AddLink(&LinkEdge{SourceID: aID, TargetID: xID, PostURL: "https://a.com/1", Starred: true})
+	g.AddLink(&LinkEdge{SourceID: aID, TargetID: yID, PostURL: "https://a.com/2"})
+	g.AddLink(&LinkEdge{SourceID: aID, TargetID: yID, PostURL: "https://a.com/3"})
+
+	ranked, _ := g.RankFeeds(RankOptions{Limit: 10})
+	if ranked[0].Feed.URL != "https://y.com/" {
+		t.Errorf("Expected y.com first without weighting, got %s", ranked[0].Feed.URL)
+	}
+
+	weight := 3.0
+	ranked, _ = g.RankFeeds(RankOptions{Limit: 10, StarWeight: &weight})
+	if ranked[0].Feed.URL != "https://x.com/" || ranked[0].Score != 3 {
+		t.Errorf("Expected x.com first with score 3, got %s (%.1f)", ranked[0].Feed.URL, ranked[0].Score)
+	}
+	if ranked[0].InboundCount != 1 {
+		t.Errorf("Expected unweighted count 1, got %d", ranked[0].InboundCount)
+	}
+
+	// A weight of 0 leaves starred links out of the score
+	weight = 0
+	ranked, _ = g.RankFeeds(RankOptions{Limit: 10, StarWeight: &weight})
+	if ranked[1].Feed.URL != "https://x.com/" || ranked[1].Score != 0 || ranked[1].InboundCount != 1 {
+		t.Errorf("Expected x.com last with score 0, got %s (%.1f)", ranked[1].Feed.URL, ranked[1].Score)
+	}
+}
+
+func TestGraph_AddLink_PublishedAt(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	aID, _ := g.AddFeed(&FeedNode{URL: "https://a.com/"})
+	bID, _ := g.AddFeed(&FeedNode{URL: "https://b.com/"})
+
+	published := time.Date(2024, 2, 10, 8, 0, 0, 0, time.UTC)
+	g.AddLink(&LinkEdge{SourceID: aID, TargetID: bID, PostURL: "https://a.com/1", PublishedAt: published})
+	// Re-crawling after starring the post updates the flag
+	g.AddLink(&LinkEdge{SourceID: aID, TargetID: bID, PostURL: "https://a.com/1", Starred: true})
+
+	links, _ := g.GetOutboundLinks(aID)
+	if len(links) != 1 {
+		t.Fatalf("Expected 1 link, got %d", len(links))
+	}
+	if !links[0].PublishedAt.Equal(published) {
+		t.Errorf("Expected published %v, got %v", published, links[0].PublishedAt)
+	}
+	if !links[0].Starred {
+		t.Error("Expected link to be starred after re-crawl")
+	}
+}
+
+func TestGraph_RankMentions_Category(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	aID, _ := g.AddFeed(&FeedNode{URL: "https://a.com/"})
+	bID, _ := g.AddFeed(&FeedNode{URL: "https://b.com/"})
+	g.AddFeedTag(aID, "Go")
+
+	g.AddMention(&Mention{SourceID: aID, Name: "Rob Pike", EntityType: "PERSON", PostURL: "https://a.com/1"})
+	g.AddMention(&Mention{SourceID: bID, Name: "Simon Willison", EntityType: "PERSON", PostURL: "https://b.com/1"})
+	g.AddMention(&Mention{SourceID: bID, Name: "Simon Willison", EntityType: "PERSON", PostURL: "https://b.com/2"})
+
+	all, _ := g.GetMostMentioned("PERSON", 10)
+	if len(all) != 2 || all[0].Name != "Simon Willison" {
+		t.Errorf("Expected Simon Willison first overall, got %+v", all)
+	}
+
+	inGo, err := g.RankMentions(MentionOptions{EntityType: "PERSON", Category: "Go", Limit: 10})
+	if err != nil {
+		t.Fatalf("RankMentions error: %v", err)
+	}
+	if len(inGo) != 1 || inGo[0].Name != "Rob Pike" {
+		t.Errorf("Expected only Rob Pike in Go, got %+v", inGo)
+	}
+}
+
+func TestGraph_GetSnapshotDates(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	feedID, _ := g.AddFeed(&FeedNode{URL: "https://example.com/"})
+	g.AddMention(&Mention{SourceID: feedID, Name: "Ada Lovelace", EntityType: "PERSON"})
+	g.TakeSnapshot("2024-01-01")
+	g.TakeSnapshot("2024-01-08")
+
+	dates, err := g.GetSnapshotDates()
+	if err != nil {
+		t.Fatalf("GetSnapshotDates error: %v", err)
+	}
+	if len(dates) != 2 || dates[0] != "2024-01-08" || dates[1] != "2024-01-01" {
+		t.Errorf("Expected [2024-01-08 2024-01-01], got %v", dates)
+	}
+}
+
+func TestGraph_DetectClusters(t *testing.T) {
+	g, ids := newTwoCommunityGraph(t)
+	defer g.Close()
+
+	n, err := g.DetectClusters()
+	if err != nil {
+		t.Fatalf("DetectClusters error: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("Expected 2 clusters, got %d", n)
+	}
+
+	clusters, err := g.GetClusters(1)
+	if err != nil {
+		t.Fatalf("GetClusters error: %v", err)
+	}
+	if len(clusters) != 2 || clusters[0].Size != 3 || clusters[1].Size != 3 {
+		t.Errorf("Expected two clusters of 3, got %+v", clusters)
+	}
+	if clusters, _ := g.GetClusters(4); len(clusters) != 0 {
+		t.Errorf("Expected minSize to filter clusters, got %+v", clusters)
+	}
+
+	// Find which cluster the Go blogs landed in
+	goCluster := clusters[0].ID
+	ranked, _ := g.RankFeeds(RankOptions{Limit: 10, Cluster: goCluster})
+	if len(ranked) == 0 || ranked[0].Feed.URL[8:10] != "go" {
+		goCluster = clusters[1].ID
+		ranked, _ = g.RankFeeds(RankOptions{Limit: 10, Cluster: goCluster})
+	}
+	if len(ranked) != 3 {
+		t.Fatalf("Expected 3 feeds in the Go cluster, got %d", len(ranked))
+	}
+	for _, r := range ranked {
+		if r.Feed.URL[8:10] != "go" {
+			t.Errorf("Expected only Go feeds in cluster %d, got %s", goCluster, r.Feed.URL)
+		}
+	}
+
+	g.AddMention(&Mention{SourceID: ids["go2"], Name: "Rob Pike", EntityType: "PERSON"})
+	g.AddMention(&Mention{SourceID: ids["ai2"], Name: "Geoffrey Hinton", EntityType: "PERSON"})
+	mentions, _ := g.RankMentions(MentionOptions{EntityType: "PERSON", Cluster: goCluster, Limit: 10})
+	if len(mentions) != 1 || mentions[0].Name != "Rob Pike" {
+		t.Errorf("Expected only Rob Pike mentioned in the Go cluster, got %+v", mentions)
+	}
+
+	// Detecting again replaces the old assignments
+	if n, _ := g.DetectClusters(); n != 2 {
+		t.Errorf("Expected 2 clusters on rerun, got %d", n)
+	}
+	if clusters, _ := g.GetClusters(1); len(clusters) != 2 {
+		t.Errorf("Expected assignments replaced, got %+v", clusters)
+	}
+}
+
+func TestGraph_GetConversations(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	a, _ := g.AddFeed(&FeedNode{URL: "https://a.com/feed", Subscribed: true})
+	aRoot, _ := g.AddFeed(&FeedNode{URL: "https://a.com/"})
+	b, _ := g.AddFeed(&FeedNode{URL: "https://b.com/"})
+
+	day := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+	link := func(source, target int64, post string, at time.Time) {
+		g.AddLink(&LinkEdge{SourceID: source, TargetID: target, PostURL: post, PublishedAt: at})
+	}
+	// January: a post, b replies, a replies again
+	link(a, b, "https://a.com/1", day(1, 1))
+	link(b, aRoot, "https://b.com/1", day(1, 5))
+	link(a, b, "https://a.com/2", day(1, 20))
+	// March: a cites b, no reply
+	link(a, b, "https://a.com/3", day(3, 1))
+	// June: b cites a, a replies
+	link(b, aRoot, "https://b.com/2", day(6, 1))
+	link(a, b, "https://a.com/4", day(6, 3))
+
+	conversations, err := g.GetConversations([]int64{a, aRoot}, []int64{b}, 21*24*time.Hour)
+	if err != nil {
+		t.Fatalf("GetConversations error: %v", err)
+	}
+	if len(conversations) != 2 {
+		t.Fatalf("Expected 2 conversations, got %+v", conversations)
+	}
+	if c := conversations[0]; len(c.Links) != 2 || !c.Start.Equal(day(6, 1)) || !c.End.Equal(day(6, 3)) {
+		t.Errorf("Expected the June exchange first, got %+v", c)
+	}
+	c := conversations[1]
+	if len(c.Links) != 3 || c.Links[0].PostURL != "https://a.com/1" || c.Links[2].PostURL != "https://a.com/2" {
+		t.Errorf("Expected the January thread oldest first, got %+v", c.Links)
+	}
+
+	// A wider window joins March into January's thread
+	conversations, _ = g.GetConversations([]int64{a, aRoot}, []int64{b}, 60*24*time.Hour)
+	if len(conversations) != 2 || len(conversations[1].Links) != 4 {
+		t.Errorf("Expected March joined to January, got %+v", conversations)
+	}
+}
+
+func TestGraph_SearchPosts(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	source, _ := g.AddFeed(&FeedNode{URL: "https://blog.example.com/feed", Title: "Example Blog", Subscribed: true})
+	jvns, _ := g.AddFeed(&FeedNode{URL: "https://jvns.ca/"})
+	other, _ := g.AddFeed(&FeedNode{URL: "https://other.com/"})
+
+	published := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	g.AddLink(&LinkEdge{SourceID: source, TargetID: jvns, PostURL: "https://blog.example.com/dns", Context: "a zine about networking"})
+	g.AddLink(&LinkEdge{SourceID: source, TargetID: other, PostURL: "https://blog.example.com/dns", Context: "resolvers"})
+	id, err := g.AddPost(&Post{
+		FeedID:      source,
+		URL:         "https://blog.example.com/dns",
+		Title:       "Debugging DNS",
+		Content:     "Every outage is a DNS outage until proven otherwise.",
+		PublishedAt: published,
+	})
+	if err != nil {
+		t.Fatalf("AddPost error: %v", err)
+	}
+	g.AddPost(&Post{FeedID: source, URL: "https://blog.example.com/go", Title: "Go generics", Content: "Type parameters at last."})
+
+	matches, err := g.SearchPosts(SearchOptions{Query: "outage"})
+	if err != nil {
+		t.Fatalf("SearchPosts error: %v", err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 match, got %d", len(matches))
+	}
+	m := matches[0]
+	if m.Post.ID != id || m.Post.Title != "Debugging DNS" || !m.Post.PublishedAt.Equal(published) {
+		t.Errorf("Unexpected post: %+v", m.Post)
+	}
+	if m.Feed.ID != source {
+		t.Errorf("Expected the post's feed, got %+v", m.Feed)
+	}
+	if !strings.Contains(m.Snippet, "[outage]") {
+		t.Errorf("Expected highlighted snippet, got %q", m.Snippet)
+	}
+	if len(m.Targets) != 2 || m.Targets[0].ID != jvns || m.Targets[1].ID != other {
+		t.Errorf("Expected links to jvns.ca and other.com, got %+v", m.Targets)
+	}
+
+	// Link text is searchable too
+	matches, _ = g.SearchPosts(SearchOptions{Query: "zine", HighlightStart: "<b>", HighlightEnd: "</b>"})
+	if len(matches) != 1 || !strings.Contains(matches[0].Snippet, "<b>zine</b>") {
+		t.Errorf("Expected a match on link text, got %+v", matches)
+	}
+
+	// Invalid FTS5 syntax falls back to quoted terms
+	for _, query := range []string{"type-parameters", "outage."} {
+		if matches, err := g.SearchPosts(SearchOptions{Query: query}); err != nil || len(matches) == 0 {
+			t.Errorf("Expected %q to fall back to quoted terms, got %v, %v", query, matches, err)
+		}
+	}
+
+	// Re-adding a post updates it in place and in the index
+	again, _ := g.AddPost(&Post{FeedID: source, URL: "https://blog.example.com/go", Title: "Go iterators", Content: "Range over func."})
+	if matches, _ := g.SearchPosts(SearchOptions{Query: "generics"}); len(matches) != 0 {
+		t.Errorf("Expected old title gone from the index, got %+v", matches)
+	}
+	matches, _ = g.SearchPosts(SearchOptions{Query: "iterators"})
+	if len(matches) != 1 || matches[0].Post.ID != again {
+		t.Errorf("Expected updated post found, got %+v", matches)
+	}
+}
+
+func TestNewGraph_BackfillsPosts(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "posts.db")
+	g, err := NewGraph(dbPath)
+	if err != nil {
+		t.Fatalf("NewGraph error: %v", err)
+	}
+	source, _ := g.AddFeed(&FeedNode{URL: "https://a.com/feed"})
+	target, _ := g.AddFeed(&FeedNode{URL: "https://b.com/"})
+	g.AddLink(&LinkEdge{SourceID: source, TargetID: target, PostURL: "https://a.com/1", PostTitle: "Weekly notes", Context: "b's great essay"})
+	g.AddMention(&Mention{SourceID: source, Name: "Ada Lovelace", EntityType: "PERSON", PostURL: "https://a.com/2", PostTitle: "On engines"})
+	// As if crawled before posts were stored
+	if _, err := g.db.Exec(`DELETE FROM posts; UPDATE links SET post_id = NULL`); err != nil {
+		t.Fatalf("Deleting posts: %v", err)
+	}
+	g.Close()
+
+	g, err = NewGraph(dbPath)
+	if err != nil {
+		t.Fatalf("NewGraph error: %v", err)
+	}
+	defer g.Close()
+
+	matches, err := g.SearchPosts(SearchOptions{Query: "essay"})
+	if err != nil {
+		t.Fatalf("SearchPosts error: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Post.Title != "Weekly notes" || matches[0].Post.URL != "https://a.com/1" {
+		t.Fatalf("Expected post backfilled from links, got %+v", matches)
+	}
+
+	links, _ := g.GetOutboundLinks(source)
+	if len(links) != 1 || links[0].PostID != matches[0].Post.ID {
+		t.Errorf("Expected link to reference post %d, got %+v", matches[0].Post.ID, links)
+	}
+	mentions, _ := g.GetMentionsByFeed(source)
+	post, _ := g.GetPost(source, "https://a.com/2")
+	if post == nil || post.Title != "On engines" || len(mentions) != 1 || mentions[0].PostID != post.ID {
+		t.Errorf("Expected mention backfilled to a post, got %+v and %+v", post, mentions)
+	}
+}
+
+func TestGraph_Posts(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	source, _ := g.AddFeed(&FeedNode{URL: "https://a.com/feed", Subscribed: true})
+	target, _ := g.AddFeed(&FeedNode{URL: "https://b.com/"})
+
+	older := &Post{
+		FeedID:      source,
+		URL:         "https://a.com/old",
+		GUID:        "tag:a.com,2024:old",
+		Title:       "Old",
+		Author:      "Ann",
+		Content:     "First draft",
+		PublishedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	changed, err := g.PostChanged(older)
+	if err != nil || !changed {
+		t.Fatalf("Expected a new post to count as changed, got %v, %v", changed, err)
+	}
+	id, err := g.AddPost(older)
+	if err != nil {
+		t.Fatalf("AddPost error: %v", err)
+	}
+	if id == 0 || older.ID != id || older.ContentHash != ContentHash("Old", "First draft") {
+		t.Errorf("Expected ID and hash set, got %+v", older)
+	}
+	g.AddPost(&Post{FeedID: source, GUID: "urn:no-url", Title: "No URL", PublishedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)})
+	if _, err := g.AddPost(&Post{FeedID: source, Title: "Anonymous"}); err == nil {
+		t.Error("Expected error for a post without URL or GUID")
+	}
+
+	// Links and mentions refer to the post
+	g.AddLink(&LinkEdge{SourceID: source, TargetID: target, PostID: id, PostURL: older.URL, Context: "b's essay"})
+	g.AddMention(&Mention{SourceID: source, PostID: id, Name: "Ada Lovelace", EntityType: "PERSON", PostURL: older.URL})
+	if links, _ := g.GetOutboundLinks(source); len(links) != 1 || links[0].PostID != id {
+		t.Errorf("Expected link with post ID %d, got %+v", id, links)
+	}
+	if mentions, _ := g.GetMentionsByFeed(source); len(mentions) != 1 || mentions[0].PostID != id {
+		t.Errorf("Expected mention with post ID %d, got %+v", id, mentions)
+	}
+
+	post, err := g.GetPost(source, older.URL)
+	if err != nil || post == nil {
+		t.Fatalf("GetPost error: %v, %v", post, err)
+	}
+	if post.GUID != older.GUID || post.Author != "Ann" || post.LinkText != "b's essay" || !post.PublishedAt.Equal(older.PublishedAt) {
+		t.Errorf("Unexpected stored post: %+v", post)
+	}
+	if missing, _ := g.GetPost(source, "https://a.com/missing"); missing != nil {
+		t.Errorf("Expected nil for a missing post, got %+v", missing)
+	}
+
+	// Unchanged content is skipped, edits are not
+	same := &Post{FeedID: source, URL: older.URL, Title: "Old", Content: "First draft"}
+	if changed, _ := g.PostChanged(same); changed {
+		t.Error("Expected identical post to be unchanged")
+	}
+	edited := &Post{FeedID: source, URL: older.URL, Title: "Old", Content: "Second draft"}
+	if changed, _ := g.PostChanged(edited); !changed {
+		t.Error("Expected edited post to be changed")
+	}
+	if again, _ := g.AddPost(edited); again != id {
+		t.Errorf("Expected update in place, got ID %d", again)
+	}
+	if post, _ := g.GetPost(source, older.URL); post.Author != "Ann" || post.LinkText != "b's essay" {
+		t.Errorf("Expected author and link text kept on update, got %+v", post)
+	}
+
+	posts, err := g.GetFeedPosts(source, 10)
+	if err != nil {
+		t.Fatalf("GetFeedPosts error: %v", err)
+	}
+	if len(posts) != 2 || posts[0].URL != "urn:no-url" || posts[1].ID != id {
+		t.Errorf("Expected posts newest first, got %+v", posts)
+	}
+}
+
+func TestGraph_RetractLinks(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	source, _ := g.AddFeed(&FeedNode{URL: "https://a.com/feed", Subscribed: true})
+	kept, _ := g.AddFeed(&FeedNode{URL: "https://b.com/"})
+	dropped, _ := g.AddFeed(&FeedNode{URL: "https://c.com/"})
+
+	post := &Post{FeedID: source, URL: "https://a.com/post", Title: "Post"}
+	g.AddPost(post)
+	for _, target := range []int64{kept, dropped} {
+		g.AddLink(&LinkEdge{SourceID: source, TargetID: target, PostID: post.ID, PostURL: post.URL, Context: "link"})
+	}
+	for _, name := range []string{"Ada Lovelace", "Alan Turing"} {
+		g.AddMention(&Mention{SourceID: source, PostID: post.ID, Name: name, EntityType: "PERSON", PostURL: post.URL})
+	}
+
+	// The edited post only links to b.com and mentions Ada
+	if n, err := g.RetractLinks(post.ID, []int64{kept}); err != nil || n != 1 {
+		t.Fatalf("Expected 1 retracted link, got %d, %v", n, err)
+	}
+	if n, err := g.RetractMentions(post.ID, []string{"Ada Lovelace"}); err != nil || n != 1 {
+		t.Fatalf("Expected 1 retracted mention, got %d, %v", n, err)
+	}
+
+	if in, _ := g.GetInboundLinks(dropped); len(in) != 0 {
+		t.Errorf("Expected retracted link to be hidden, got %+v", in)
+	}
+	history, _ := g.GetInboundLinkHistory(dropped)
+	if len(history) != 1 || history[0].RetractedAt.IsZero() {
+		t.Errorf("Expected retracted link in history, got %+v", history)
+	}
+	ranked, _ := g.RankFeeds(RankOptions{Limit: 10})
+	if len(ranked) != 1 || ranked[0].Feed.ID != kept {
+		t.Errorf("Expected only b.com ranked, got %+v", ranked)
+	}
+	if mentions, _ := g.GetMentionsByFeed(source); len(mentions) != 1 || mentions[0].Name != "Ada Lovelace" {
+		t.Errorf("Expected only Ada mentioned, got %+v", mentions)
+	}
+
+	// Re-adding the link restores it
+	g.AddLink(&LinkEdge{SourceID: source, TargetID: dropped, PostID: post.ID, PostURL: post.URL, Context: "link"})
+	if in, _ := g.GetInboundLinks(dropped); len(in) != 1 || !in[0].RetractedAt.IsZero() {
+		t.Errorf("Expected restored link, got %+v", in)
+	}
+}
+
+func TestGraph_MarkRemovedPosts(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	source, _ := g.AddFeed(&FeedNode{URL: "https://a.com/feed", Subscribed: true})
+	target, _ := g.AddFeed(&FeedNode{URL: "https://b.com/"})
+
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	ancient := &Post{FeedID: source, URL: "https://a.com/ancient", PublishedAt: day(1)}
+	deleted := &Post{FeedID: source, URL: "https://a.com/deleted", PublishedAt: day(10)}
+	current := &Post{FeedID: source, URL: "https://a.com/current", PublishedAt: day(5)}
+	for _, p := range []*Post{ancient, deleted, current} {
+		g.AddPost(p)
+		g.AddLink(&LinkEdge{SourceID: source, TargetID: target, PostID: p.ID, PostURL: p.URL})
+	}
+
+	// The feed now only has current; ancient is older than anything in it
+	n, err := g.MarkRemovedPosts(source, []*Post{current}, day(5))
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 removed post, got %d, %v", n, err)
+	}
+	if p, _ := g.GetPost(source, deleted.URL); p == nil || p.RemovedAt.IsZero() {
+		t.Errorf("Expected deleted post marked removed, got %+v", p)
+	}
+	if p, _ := g.GetPost(source, ancient.URL); p == nil || !p.RemovedAt.IsZero() {
+		t.Errorf("Expected ancient post kept, got %+v", p)
+	}
+	if in, _ := g.GetInboundLinks(target); len(in) != 2 {
+		t.Errorf("Expected 2 current links, got %d", len(in))
+	}
+
+	// A post that comes back unchanged still counts as changed, so it is
+	// stored again, and is no longer removed
+	if changed, _ := g.PostChanged(&Post{FeedID: source, URL: deleted.URL}); !changed {
+		t.Error("Expected a removed post to count as changed")
+	}
+	g.AddPost(deleted)
+	if p, _ := g.GetPost(source, deleted.URL); p == nil || !p.RemovedAt.IsZero() {
+		t.Errorf("Expected restored post, got %+v", p)
+	}
+}
+
+func TestGraph_GetFeedHealth(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	add := func(url string) int64 {
+		id, _ := g.AddFeed(&FeedNode{URL: url, Title: url, Subscribed: true})
+		return id
+	}
+	healthy, dead, broken, moved, stale := add("https://ok.com/feed"), add("https://dead.com/feed"),
+		add("https://broken.com/feed"), add("https://moved.com/feed"), add("https://stale.com/feed")
+	g.AddFeed(&FeedNode{URL: "https://linked.com/"})
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	record := func(feedID int64, daysAgo int, a FetchAttempt) {
+		a.FeedID = feedID
+		a.AttemptedAt = now.AddDate(0, 0, -daysAgo)
+		if err := g.RecordFetch(&a); err != nil {
+			t.Fatalf("RecordFetch error: %v", err)
+		}
+	}
+	record(healthy, 3, FetchAttempt{Status: 500, ErrorClass: "http"})
+	record(healthy, 2, FetchAttempt{Status: 200, ItemCount: 10, NewestItemAt: now.AddDate(0, 0, -5), Latency: 120 * time.Millisecond})
+	record(healthy, 1, FetchAttempt{Status: 404, ErrorClass: "http"})
+	record(dead, 4, FetchAttempt{Status: 200})
+	for day := 3; day > 0; day-- {
+		record(dead, day, FetchAttempt{ErrorClass: "dns", Error: "no such host"})
+	}
+	record(broken, 1, FetchAttempt{Status: 200, ErrorClass: ErrorClassParse})
+	record(moved, 1, FetchAttempt{Status: 200, MovedTo: "https://moved.net/feed"})
+	g.AddPost(&Post{FeedID: stale, URL: "https://stale.com/last", PublishedAt: now.AddDate(-1, 0, 0)})
+
+	health, err := g.GetFeedHealth(HealthOptions{StaleSince: now.AddDate(0, -6, 0)})
+	if err != nil {
+		t.Fatalf("GetFeedHealth error: %v", err)
+	}
+	if len(health) != 5 {
+		t.Fatalf("Expected 5 subscribed feeds, got %d", len(health))
+	}
+	want := []string{HealthDead, HealthMalformed, HealthMoved, HealthStale, HealthOK}
+	for i, h := range health {
+		if h.Status != want[i] {
+			t.Errorf("Expected %s at %d, got %s for %s", want[i], i, h.Status, h.Feed.URL)
+		}
+	}
+	if health[0].Failures != 3 || health[0].Last.Error != "no such host" || !health[0].LastSuccess.Equal(now.AddDate(0, 0, -4)) {
+		t.Errorf("Unexpected dead feed health: %+v", health[0])
+	}
+	if health[2].MovedTo != "https://moved.net/feed" {
+		t.Errorf("Expected moved feed to point at its new URL, got %q", health[2].MovedTo)
+	}
+	ok := health[4]
+	if ok.Attempts != 3 || ok.Failures != 1 || !ok.NewestPost.Equal(now.AddDate(0, 0, -5)) {
+		t.Errorf("Unexpected healthy feed: %+v", ok)
+	}
+
+	// Following the move clears it
+	if err := g.UpdateFeedURL(moved, "https://moved.net/feed"); err != nil {
+		t.Fatalf("UpdateFeedURL error: %v", err)
+	}
+	if feeds, _ := g.FindFeeds("moved.net"); len(feeds) != 1 || feeds[0].ID != moved {
+		t.Errorf("Expected feed found under its new host, got %+v", feeds)
+	}
+	health, _ = g.GetFeedHealth(HealthOptions{})
+	for _, h := range health {
+		if h.Feed.ID == moved && h.Status != HealthOK {
+			t.Errorf("Expected moved feed to be ok after update, got %s", h.Status)
+		}
+	}
+	if err := g.UpdateFeedURL(moved, "https://ok.com/feed"); err == nil {
+		t.Error("Expected error moving onto an existing feed")
+	}
+}
+
+func TestGraph_Schedules(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	id, _ := g.AddFeed(&FeedNode{URL: "https://a.com/feed", Subscribed: true})
+	if schedules, err := g.GetSchedules(); err != nil || len(schedules) != 0 {
+		t.Fatalf("Expected no schedules, got %v, %v", schedules, err)
+	}
+
+	next := time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+	g.SetSchedule(&FeedSchedule{FeedID: id, Interval: time.Hour, NextFetch: next})
+	g.SetSchedule(&FeedSchedule{FeedID: id, Interval: 2 * time.Hour, NextFetch: next.Add(4 * time.Hour), Failures: 1})
+
+	schedules, err := g.GetSchedules()
+	if err != nil {
+		t.Fatalf("GetSchedules error: %v", err)
+	}
+	s := schedules[id]
+	if len(schedules) != 1 || s.Interval != 2*time.Hour || !s.NextFetch.Equal(next.Add(4*time.Hour)) || s.Failures != 1 {
+		t.Errorf("Unexpected schedule: %+v", schedules)
+	}
+}
+
+func TestGraph_Frontier(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	sub1, _ := g.AddFeed(&FeedNode{URL: "https://a.com/feed", Subscribed: true})
+	sub2, _ := g.AddFeed(&FeedNode{URL: "https://b.com/feed", Subscribed: true})
+	popular, _ := g.AddFeed(&FeedNode{URL: "https://popular.com/"})
+	niche, _ := g.AddFeed(&FeedNode{URL: "https://niche.com/"})
+	own, _ := g.AddFeed(&FeedNode{URL: "https://b.com/"})
+	for _, l := range []LinkEdge{
+		{SourceID: sub1, TargetID: popular, PostURL: "https://a.com/1"},
+		{SourceID: sub2, TargetID: popular, PostURL: "https://b.com/1"},
+		{SourceID: sub1, TargetID: niche, PostURL: "https://a.com/2"},
+		{SourceID: sub1, TargetID: own, PostURL: "https://a.com/3"},
+	} {
+		g.AddLink(&l)
+	}
+
+	// b.com is a subscription's site, so only the other two are queued
+	n, err := g.SeedFrontier()
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 sites queued, got %d, %v", n, err)
+	}
+	next, err := g.NextFrontier(1, 1, 10)
+	if err != nil {
+		t.Fatalf("NextFrontier error: %v", err)
+	}
+	if len(next) != 2 || next[0].FeedID != popular || next[0].Priority != 2 || next[0].Depth != 1 || next[1].FeedID != niche {
+		t.Fatalf("Expected popular then niche, got %+v", next)
+	}
+	if next, _ := g.NextFrontier(1, 2, 10); len(next) != 1 {
+		t.Errorf("Expected min priority to leave 1 site, got %d", len(next))
+	}
+
+	// Expanding popular.com finds its feed, which links further out
+	feed, _ := g.AddFeed(&FeedNode{URL: "https://popular.com/feed.xml"})
+	g.AddPost(&Post{FeedID: feed, URL: "https://popular.com/post"})
+	far, _ := g.AddFeed(&FeedNode{URL: "https://far.com/"})
+	g.AddLink(&LinkEdge{SourceID: feed, TargetID: far, PostURL: "https://popular.com/post"})
+	g.AddLink(&LinkEdge{SourceID: feed, TargetID: niche, PostURL: "https://popular.com/post"})
+	g.SetFrontierStatus(popular, FrontierDone, "https://popular.com/feed.xml", "")
+	if n, _ := g.EnqueueTargets(feed, 2); n != 1 {
+		t.Errorf("Expected only far.com newly queued, got %d", n)
+	}
+
+	next, _ = g.NextFrontier(2, 1, 10)
+	if len(next) != 2 || next[0].FeedID != niche || next[0].Depth != 1 || next[1].FeedID != far || next[1].Depth != 2 {
+		t.Errorf("Expected niche at depth 1 then far at depth 2, got %+v", next)
+	}
+	if next, _ := g.NextFrontier(1, 1, 10); len(next) != 1 {
+		t.Errorf("Expected depth limit to leave 1 site, got %d", len(next))
+	}
+
+	g.SetFrontierStatus(far, FrontierSkipped, "", "denied")
+	counts, _ := g.CountFrontier()
+	if counts[FrontierQueued] != 1 || counts[FrontierDone] != 1 || counts[FrontierSkipped] != 1 {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+	done, _ := g.GetFrontier(FrontierDone, 10)
+	if len(done) != 1 || done[0].FeedURL != "https://popular.com/feed.xml" || done[0].URL != "https://popular.com/" {
+		t.Errorf("Unexpected done entries: %+v", done)
+	}
+	if n, _ := g.RequeueFrontier(); n != 1 {
+		t.Errorf("Expected 1 site requeued, got %d", n)
+	}
+}
+
 // Helper to create in-memory test graph
 func newTestGraph(t *testing.T) *Graph {
 	t.Helper()
@@ -0,0 +1,622 @@
+package graph
+
+import (
+	"database/sql"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// NetworkEdge aggregates all links from one feed to another.
+type NetworkEdge struct {
+	SourceID  int64
+	TargetID  int64
+	Weight    int       // Number of posts linking
+	FirstSeen time.Time // Earliest post date (publish date, else discovery date)
+	LastSeen  time.Time // Latest post date
+}
+
+// Network is an in-memory copy of the feed graph for whole-graph analysis
+// and export. Subgraph methods return new Networks sharing the FeedNodes.
+type Network struct {
+	Nodes map[int64]*FeedNode
+	Edges []NetworkEdge // Sorted by source, then target
+
+	// Inbound is each feed's inbound link count in the full graph, so it
+	// stays meaningful after filtering.
+	Inbound map[int64]int
+
+	// Members maps each node to the feed IDs it stands for after
+	// MergeHosts; nil otherwise.
+	Members map[int64][]int64
+}
+
+// LoadNetwork reads every feed and current link into memory.
+func (g *Graph) LoadNetwork() (*Network, error) {
+	n := &Network{
+		Nodes:   make(map[int64]*FeedNode),
+		Inbound: make(map[int64]int),
+	}
+
+	rows, err := g.db.Query("SELECT " + feedColumns("f") + " FROM feeds f")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		feed, err := scanFeed(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		n.Nodes[feed.ID] = feed
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = g.db.Query(`SELECT source_id, target_id, published_at, discovered_at FROM links WHERE retracted_at IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edges := make(map[[2]int64]*NetworkEdge)
+	for rows.Next() {
+		var source, target int64
+		var published sql.NullTime
+		var discovered time.Time
+		if err := rows.Scan(&source, &target, &published, &discovered); err != nil {
+			return nil, err
+		}
+		seen := discovered
+		if published.Valid {
+			seen = published.Time
+		}
+
+		key := [2]int64{source, target}
+		e, ok := edges[key]
+		if !ok {
+			e = &NetworkEdge{SourceID: source, TargetID: target, FirstSeen: seen, LastSeen: seen}
+			edges[key] = e
+		}
+		e.Weight++
+		if seen.Before(e.FirstSeen) {
+			e.FirstSeen = seen
+		}
+		if seen.After(e.LastSeen) {
+			e.LastSeen = seen
+		}
+		n.Inbound[target]++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, e := range edges {
+		n.Edges = append(n.Edges, *e)
+	}
+	sortEdges(n.Edges)
+	return n, nil
+}
+
+func sortEdges(edges []NetworkEdge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].SourceID != edges[j].SourceID {
+			return edges[i].SourceID < edges[j].SourceID
+		}
+		return edges[i].TargetID < edges[j].TargetID
+	})
+}
+
+// NodeIDs returns the IDs of all nodes in ascending order.
+func (n *Network) NodeIDs() []int64 {
+	ids := make([]int64, 0, len(n.Nodes))
+	for id := range n.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Neighbors returns each node's distinct neighbors, ignoring edge direction.
+func (n *Network) Neighbors() map[int64][]int64 {
+	neighbors := make(map[int64][]int64)
+	for _, e := range n.Edges {
+		if e.SourceID == e.TargetID {
+			continue
+		}
+		neighbors[e.SourceID] = append(neighbors[e.SourceID], e.TargetID)
+		neighbors[e.TargetID] = append(neighbors[e.TargetID], e.SourceID)
+	}
+	// An edge each way between two feeds would otherwise count twice
+	for id, ns := range neighbors {
+		sort.Slice(ns, func(i, j int) bool { return ns[i] < ns[j] })
+		unique := ns[:0]
+		for i, v := range ns {
+			if i == 0 || v != ns[i-1] {
+				unique = append(unique, v)
+			}
+		}
+		neighbors[id] = unique
+	}
+	return neighbors
+}
+
+// Subgraph returns the network induced by the nodes for which keep is true.
+func (n *Network) Subgraph(keep func(*FeedNode) bool) *Network {
+	sub := &Network{Nodes: make(map[int64]*FeedNode), Inbound: n.Inbound, Members: n.Members}
+	for id, node := range n.Nodes {
+		if keep(node) {
+			sub.Nodes[id] = node
+		}
+	}
+	for _, e := range n.Edges {
+		if sub.Nodes[e.SourceID] != nil && sub.Nodes[e.TargetID] != nil {
+			sub.Edges = append(sub.Edges, e)
+		}
+	}
+	return sub
+}
+
+// Distances returns the hop count from the nearest center to every node
+// within radius, following links in either direction.
+func (n *Network) Distances(centers []int64, radius int) map[int64]int {
+	neighbors := n.Neighbors()
+	dist := make(map[int64]int)
+	var frontier []int64
+	for _, c := range centers {
+		if _, ok := n.Nodes[c]; ok {
+			dist[c] = 0
+			frontier = append(frontier, c)
+		}
+	}
+	for d := 1; d <= radius && len(frontier) > 0; d++ {
+		var next []int64
+		for _, id := range frontier {
+			for _, other := range neighbors[id] {
+				if _, seen := dist[other]; !seen {
+					dist[other] = d
+					next = append(next, other)
+				}
+			}
+		}
+		frontier = next
+	}
+	return dist
+}
+
+// Ego returns the network within radius hops of any of the centers,
+// following links in either direction.
+func (n *Network) Ego(centers []int64, radius int) *Network {
+	dist := n.Distances(centers, radius)
+	return n.Subgraph(func(f *FeedNode) bool {
+		_, ok := dist[f.ID]
+		return ok
+	})
+}
+
+// ShortestPaths returns up to limit shortest chains of links from any of
+// from to any of to, as node IDs. Unless undirected is set, links are only
+// followed from citing feed to cited feed.
+func (n *Network) ShortestPaths(from, to []int64, undirected bool, limit int) [][]int64 {
+	next := make(map[int64][]int64)
+	if undirected {
+		next = n.Neighbors()
+	} else {
+		for _, e := range n.Edges {
+			if e.SourceID != e.TargetID {
+				next[e.SourceID] = append(next[e.SourceID], e.TargetID)
+			}
+		}
+	}
+	targets := make(map[int64]bool)
+	for _, id := range to {
+		targets[id] = true
+	}
+
+	// Breadth-first, recording every parent at the previous level so all
+	// shortest paths can be rebuilt
+	dist := make(map[int64]int)
+	parents := make(map[int64][]int64)
+	var frontier, found []int64
+	for _, id := range from {
+		if _, ok := n.Nodes[id]; ok {
+			dist[id] = 0
+			frontier = append(frontier, id)
+			if targets[id] {
+				found = append(found, id)
+			}
+		}
+	}
+	for d := 1; len(frontier) > 0 && len(found) == 0; d++ {
+		var level []int64
+		for _, id := range frontier {
+			for _, other := range next[id] {
+				seen, ok := dist[other]
+				if !ok {
+					dist[other] = d
+					level = append(level, other)
+					if targets[other] {
+						found = append(found, other)
+					}
+				}
+				if !ok || seen == d {
+					parents[other] = append(parents[other], id)
+				}
+			}
+		}
+		frontier = level
+	}
+
+	var paths [][]int64
+	var walk func(id int64, suffix []int64)
+	walk = func(id int64, suffix []int64) {
+		if len(paths) >= limit {
+			return
+		}
+		path := append([]int64{id}, suffix...)
+		if dist[id] == 0 {
+			paths = append(paths, path)
+			return
+		}
+		for _, p := range parents[id] {
+			walk(p, path)
+		}
+	}
+	for _, id := range found {
+		walk(id, nil)
+	}
+	return paths
+}
+
+// MergeHosts returns a network with one node per site host, so the feed URL
+// we subscribe to and the site root other feeds link to become one node.
+// Each merged node keeps the ID and details of a subscribed member if there
+// is one, else its lowest ID, taking a member's title if it has none. Links
+// within a host are dropped.
+func (n *Network) MergeHosts() *Network {
+	byHost := make(map[string][]*FeedNode)
+	for _, id := range n.NodeIDs() {
+		f := n.Nodes[id]
+		host := siteHost(f.URL)
+		if host == "" {
+			host = f.URL
+		}
+		byHost[host] = append(byHost[host], f)
+	}
+
+	merged := &Network{
+		Nodes:   make(map[int64]*FeedNode),
+		Inbound: make(map[int64]int),
+		Members: make(map[int64][]int64),
+	}
+	rep := make(map[int64]int64)
+	for _, feeds := range byHost {
+		// feeds are in ID order, so the first is the lowest
+		keep := feeds[0]
+		for _, f := range feeds {
+			if f.Subscribed {
+				keep = f
+				break
+			}
+		}
+		// Copy so a borrowed title doesn't leak into the unmerged network
+		node := *keep
+		for _, f := range feeds {
+			if node.Title == "" {
+				node.Title = f.Title
+			}
+		}
+		merged.Nodes[keep.ID] = &node
+		for _, f := range feeds {
+			rep[f.ID] = keep.ID
+			merged.Members[keep.ID] = append(merged.Members[keep.ID], f.ID)
+			merged.Inbound[keep.ID] += n.Inbound[f.ID]
+		}
+	}
+
+	edges := make(map[[2]int64]*NetworkEdge)
+	for _, e := range n.Edges {
+		source, target := rep[e.SourceID], rep[e.TargetID]
+		if source == target {
+			continue
+		}
+		key := [2]int64{source, target}
+		m, ok := edges[key]
+		if !ok {
+			edges[key] = &NetworkEdge{SourceID: source, TargetID: target, Weight: e.Weight, FirstSeen: e.FirstSeen, LastSeen: e.LastSeen}
+			continue
+		}
+		m.Weight += e.Weight
+		if e.FirstSeen.Before(m.FirstSeen) {
+			m.FirstSeen = e.FirstSeen
+		}
+		if e.LastSeen.After(m.LastSeen) {
+			m.LastSeen = e.LastSeen
+		}
+	}
+	for _, e := range edges {
+		merged.Edges = append(merged.Edges, *e)
+	}
+	sortEdges(merged.Edges)
+	return merged
+}
+
+// Resolve returns the node standing for a feed ID: itself, or the merged
+// node containing it after MergeHosts. It returns 0 if the feed isn't in n.
+func (n *Network) Resolve(feedID int64) int64 {
+	if _, ok := n.Nodes[feedID]; ok {
+		return feedID
+	}
+	for id, members := range n.Members {
+		for _, m := range members {
+			if m == feedID {
+				return id
+			}
+		}
+	}
+	return 0
+}
+
+// MinDegree returns the network without nodes that have fewer than k
+// distinct neighbors in it.
+func (n *Network) MinDegree(k int) *Network {
+	neighbors := n.Neighbors()
+	return n.Subgraph(func(f *FeedNode) bool {
+		return len(neighbors[f.ID]) >= k
+	})
+}
+
+// Communities groups nodes by weighted label propagation, treating links
+// as undirected: every node repeatedly adopts the label with the most link
+// weight among its neighbors until nothing changes. Nodes are visited in a
+// fixed pseudo-random order so results are reproducible. It returns a
+// community number per node, numbered from 1 by size, largest first; nodes
+// without links are left out.
+func (n *Network) Communities() map[int64]int {
+	adj := make(map[int64]map[int64]int)
+	for _, e := range n.Edges {
+		if e.SourceID == e.TargetID {
+			continue
+		}
+		for _, pair := range [][2]int64{{e.SourceID, e.TargetID}, {e.TargetID, e.SourceID}} {
+			if adj[pair[0]] == nil {
+				adj[pair[0]] = make(map[int64]int)
+			}
+			adj[pair[0]][pair[1]] += e.Weight
+		}
+	}
+
+	var order []int64
+	labels := make(map[int64]int64)
+	for _, id := range n.NodeIDs() {
+		if len(adj[id]) > 0 {
+			order = append(order, id)
+			labels[id] = id
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 100; iter++ {
+		rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		changed := false
+		for _, id := range order {
+			weights := make(map[int64]int)
+			for other, w := range adj[id] {
+				weights[labels[other]] += w
+			}
+			// Keep the current label on ties so the process settles,
+			// otherwise prefer the lowest label for determinism
+			best, bestWeight := labels[id], weights[labels[id]]
+			for label, w := range weights {
+				if w > bestWeight || (w == bestWeight && label < best && weights[labels[id]] < w) {
+					best, bestWeight = label, w
+				}
+			}
+			if best != labels[id] {
+				labels[id] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	// Renumber by size, breaking ties by label
+	sizes := make(map[int64]int)
+	for _, label := range labels {
+		sizes[label]++
+	}
+	var byLabel []int64
+	for label := range sizes {
+		byLabel = append(byLabel, label)
+	}
+	sort.Slice(byLabel, func(i, j int) bool {
+		if sizes[byLabel[i]] != sizes[byLabel[j]] {
+			return sizes[byLabel[i]] > sizes[byLabel[j]]
+		}
+		return byLabel[i] < byLabel[j]
+	})
+	number := make(map[int64]int)
+	for i, label := range byLabel {
+		number[label] = i + 1
+	}
+
+	communities := make(map[int64]int)
+	for id, label := range labels {
+		communities[id] = number[label]
+	}
+	return communities
+}
+
+// SimilarityMetric measures the overlap of two sets of feeds.
+type SimilarityMetric string
+
+const (
+	Cosine  SimilarityMetric = "cosine"  // |A∩B| / √(|A|·|B|)
+	Jaccard SimilarityMetric = "jaccard" // |A∩B| / |A∪B|
+)
+
+// SimilarFeed is a node that shares citers or cited feeds with another.
+type SimilarFeed struct {
+	ID           int64
+	CoCitation   float64 // Similarity of the feeds citing each
+	Coupling     float64 // Similarity of the feeds each cites
+	Score        float64 // Mean of CoCitation and Coupling
+	SharedCiters []int64 // Feeds citing both, most linked first
+	SharedCited  []int64 // Feeds both cite, most linked first
+}
+
+// Similar returns up to limit nodes most like id, by co-citation (being
+// cited by the same feeds) and bibliographic coupling (citing the same
+// feeds), best first. Edge weights are ignored: one link counts as much
+// as many.
+func (n *Network) Similar(id int64, metric SimilarityMetric, limit int) []SimilarFeed {
+	citers := make(map[int64]map[int64]bool)
+	cited := make(map[int64]map[int64]bool)
+	for _, e := range n.Edges {
+		if e.SourceID == e.TargetID {
+			continue
+		}
+		if citers[e.TargetID] == nil {
+			citers[e.TargetID] = make(map[int64]bool)
+		}
+		citers[e.TargetID][e.SourceID] = true
+		if cited[e.SourceID] == nil {
+			cited[e.SourceID] = make(map[int64]bool)
+		}
+		cited[e.SourceID][e.TargetID] = true
+	}
+
+	// Candidates share at least one citer or cited feed with id
+	candidates := make(map[int64]*SimilarFeed)
+	candidate := func(other int64) *SimilarFeed {
+		c, ok := candidates[other]
+		if !ok {
+			c = &SimilarFeed{ID: other}
+			candidates[other] = c
+		}
+		return c
+	}
+	for citer := range citers[id] {
+		for other := range cited[citer] {
+			if other != id {
+				c := candidate(other)
+				c.SharedCiters = append(c.SharedCiters, citer)
+			}
+		}
+	}
+	for target := range cited[id] {
+		for other := range citers[target] {
+			if other != id {
+				c := candidate(other)
+				c.SharedCited = append(c.SharedCited, target)
+			}
+		}
+	}
+
+	byInbound := func(ids []int64) {
+		sort.Slice(ids, func(i, j int) bool {
+			if n.Inbound[ids[i]] != n.Inbound[ids[j]] {
+				return n.Inbound[ids[i]] > n.Inbound[ids[j]]
+			}
+			return ids[i] < ids[j]
+		})
+	}
+	var similar []SimilarFeed
+	for other, c := range candidates {
+		c.CoCitation = overlap(metric, len(c.SharedCiters), len(citers[id]), len(citers[other]))
+		c.Coupling = overlap(metric, len(c.SharedCited), len(cited[id]), len(cited[other]))
+		c.Score = (c.CoCitation + c.Coupling) / 2
+		byInbound(c.SharedCiters)
+		byInbound(c.SharedCited)
+		similar = append(similar, *c)
+	}
+	sort.Slice(similar, func(i, j int) bool {
+		a, b := similar[i], similar[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if shared := len(a.SharedCiters) + len(a.SharedCited); shared != len(b.SharedCiters)+len(b.SharedCited) {
+			return shared > len(b.SharedCiters)+len(b.SharedCited)
+		}
+		return a.ID < b.ID
+	})
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+	return similar
+}
+
+// overlap scores sets of sizes a and b sharing shared members.
+func overlap(metric SimilarityMetric, shared, a, b int) float64 {
+	if shared == 0 {
+		return 0
+	}
+	if metric == Jaccard {
+		return float64(shared) / float64(a+b-shared)
+	}
+	return float64(shared) / math.Sqrt(float64(a)*float64(b))
+}
+
+// ReciprocalPair is two nodes that link to each other.
+type ReciprocalPair struct {
+	AToB NetworkEdge
+	BToA NetworkEdge
+}
+
+// Reciprocal returns the pairs of nodes that link to each other, the most
+// balanced exchanges first: by the weight of the lighter direction, then
+// total weight, then most recent link. AToB.SourceID is the lower ID.
+func (n *Network) Reciprocal() []ReciprocalPair {
+	edges := make(map[[2]int64]NetworkEdge)
+	for _, e := range n.Edges {
+		edges[[2]int64{e.SourceID, e.TargetID}] = e
+	}
+	var pairs []ReciprocalPair
+	for _, e := range n.Edges {
+		if e.SourceID >= e.TargetID {
+			continue
+		}
+		if back, ok := edges[[2]int64{e.TargetID, e.SourceID}]; ok {
+			pairs = append(pairs, ReciprocalPair{AToB: e, BToA: back})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		a, b := pairs[i], pairs[j]
+		if a.Min() != b.Min() {
+			return a.Min() > b.Min()
+		}
+		if a.Total() != b.Total() {
+			return a.Total() > b.Total()
+		}
+		return a.LastSeen().After(b.LastSeen())
+	})
+	return pairs
+}
+
+// Min is the weight of the lighter direction.
+func (p ReciprocalPair) Min() int {
+	return min(p.AToB.Weight, p.BToA.Weight)
+}
+
+// Total is the weight of both directions.
+func (p ReciprocalPair) Total() int {
+	return p.AToB.Weight + p.BToA.Weight
+}
+
+// LastSeen is the date of the latest link either way.
+func (p ReciprocalPair) LastSeen() time.Time {
+	if p.BToA.LastSeen.After(p.AToB.LastSeen) {
+		return p.BToA.LastSeen
+	}
+	return p.AToB.LastSeen
+}
+
+// Swap returns the pair seen from B.
+func (p ReciprocalPair) Swap() ReciprocalPair {
+	return ReciprocalPair{AToB: p.BToA, BToA: p.AToB}
+}
@@ -0,0 +1,338 @@
+package graph
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// newTestNetwork builds a -> b (2 posts), b -> a, b -> c, c -> d, with e isolated.
+func newTestNetwork(t *testing.T) (*Graph, map[string]int64) {
+	t.Helper()
+	g := newTestGraph(t)
+
+	ids := make(map[string]int64)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		id, err := g.AddFeed(&FeedNode{URL: "https://" + name + ".com/", Title: name})
+		if err != nil {
+			t.Fatalf("AddFeed error: %v", err)
+		}
+		ids[name] = id
+	}
+
+	early := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, l := range []*LinkEdge{
+		{SourceID: ids["a"], TargetID: ids["b"], PostURL: "https://a.com/1", PublishedAt: late},
+		{SourceID: ids["a"], TargetID: ids["b"], PostURL: "https://a.com/2", PublishedAt: early},
+		{SourceID: ids["b"], TargetID: ids["a"], PostURL: "https://b.com/1"},
+		{SourceID: ids["b"], TargetID: ids["c"], PostURL: "https://b.com/2"},
+		{SourceID: ids["c"], TargetID: ids["d"], PostURL: "https://c.com/1"},
+	} {
+		if err := g.AddLink(l); err != nil {
+			t.Fatalf("AddLink error: %v", err)
+		}
+	}
+	return g, ids
+}
+
+func TestGraph_LoadNetwork(t *testing.T) {
+	g, ids := newTestNetwork(t)
+	defer g.Close()
+
+	n, err := g.LoadNetwork()
+	if err != nil {
+		t.Fatalf("LoadNetwork error: %v", err)
+	}
+	if len(n.Nodes) != 5 {
+		t.Errorf("Expected 5 nodes, got %d", len(n.Nodes))
+	}
+	if len(n.Edges) != 4 {
+		t.Fatalf("Expected 4 aggregated edges, got %d", len(n.Edges))
+	}
+
+	ab := n.Edges[0]
+	if ab.SourceID != ids["a"] || ab.TargetID != ids["b"] {
+		t.Fatalf("Expected edges sorted with a->b first, got %+v", ab)
+	}
+	if ab.Weight != 2 {
+		t.Errorf("Expected a->b weight 2, got %d", ab.Weight)
+	}
+	if !ab.FirstSeen.Equal(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)) || !ab.LastSeen.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected a->b seen 2023-01-01 to 2024-06-01, got %v to %v", ab.FirstSeen, ab.LastSeen)
+	}
+	if n.Inbound[ids["b"]] != 2 || n.Inbound[ids["e"]] != 0 {
+		t.Errorf("Expected inbound b=2 e=0, got b=%d e=%d", n.Inbound[ids["b"]], n.Inbound[ids["e"]])
+	}
+}
+
+func TestNetwork_Neighbors(t *testing.T) {
+	g, ids := newTestNetwork(t)
+	defer g.Close()
+	n, _ := g.LoadNetwork()
+
+	neighbors := n.Neighbors()
+	// a <-> b counts once despite edges both ways
+	if len(neighbors[ids["a"]]) != 1 || len(neighbors[ids["b"]]) != 2 {
+		t.Errorf("Expected a to have 1 neighbor and b 2, got %v and %v", neighbors[ids["a"]], neighbors[ids["b"]])
+	}
+	if len(neighbors[ids["e"]]) != 0 {
+		t.Errorf("Expected e isolated, got %v", neighbors[ids["e"]])
+	}
+}
+
+func TestNetwork_Ego(t *testing.T) {
+	g, ids := newTestNetwork(t)
+	defer g.Close()
+	n, _ := g.LoadNetwork()
+
+	ego := n.Ego([]int64{ids["a"]}, 1)
+	if len(ego.Nodes) != 2 || len(ego.Edges) != 2 {
+		t.Errorf("Expected a's 1-hop network to be a, b with 2 edges, got %d nodes %d edges", len(ego.Nodes), len(ego.Edges))
+	}
+
+	// Links are followed against their direction too
+	ego = n.Ego([]int64{ids["d"]}, 2)
+	if len(ego.Nodes) != 3 || ego.Nodes[ids["b"]] == nil {
+		t.Errorf("Expected d's 2-hop network to reach b, got %d nodes", len(ego.Nodes))
+	}
+	if ego.Inbound[ids["b"]] != 2 {
+		t.Error("Expected inbound counts from the full graph")
+	}
+}
+
+func TestNetwork_MinDegree(t *testing.T) {
+	g, ids := newTestNetwork(t)
+	defer g.Close()
+	n, _ := g.LoadNetwork()
+
+	filtered := n.MinDegree(2)
+	// Only b and c have 2 distinct neighbors
+	if len(filtered.Nodes) != 2 || filtered.Nodes[ids["b"]] == nil || filtered.Nodes[ids["c"]] == nil {
+		t.Errorf("Expected b and c, got %v", filtered.NodeIDs())
+	}
+	if len(filtered.Edges) != 1 {
+		t.Errorf("Expected only b->c to remain, got %d edges", len(filtered.Edges))
+	}
+}
+
+func TestNetwork_ShortestPaths(t *testing.T) {
+	g, ids := newTestNetwork(t)
+	defer g.Close()
+	n, _ := g.LoadNetwork()
+
+	paths := n.ShortestPaths([]int64{ids["a"]}, []int64{ids["d"]}, false, 5)
+	if len(paths) != 1 {
+		t.Fatalf("Expected 1 path a -> d, got %v", paths)
+	}
+	want := []int64{ids["a"], ids["b"], ids["c"], ids["d"]}
+	for i := range want {
+		if paths[0][i] != want[i] {
+			t.Fatalf("Expected path %v, got %v", want, paths[0])
+		}
+	}
+
+	// Nothing cites a from d, unless direction is ignored
+	if paths := n.ShortestPaths([]int64{ids["d"]}, []int64{ids["a"]}, false, 5); len(paths) != 0 {
+		t.Errorf("Expected no directed path d -> a, got %v", paths)
+	}
+	if paths := n.ShortestPaths([]int64{ids["d"]}, []int64{ids["a"]}, true, 5); len(paths) != 1 || len(paths[0]) != 4 {
+		t.Errorf("Expected undirected path d - a of 4 nodes, got %v", paths)
+	}
+	if paths := n.ShortestPaths([]int64{ids["a"]}, []int64{ids["e"]}, true, 5); len(paths) != 0 {
+		t.Errorf("Expected no path to isolated e, got %v", paths)
+	}
+}
+
+func TestNetwork_ShortestPaths_All(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	// Two equally short routes from a to d
+	ids := make(map[string]int64)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		ids[name], _ = g.AddFeed(&FeedNode{URL: "https://" + name + ".com/"})
+	}
+	for _, pair := range [][2]string{{"a", "b"}, {"a", "c"}, {"b", "d"}, {"c", "d"}} {
+		g.AddLink(&LinkEdge{SourceID: ids[pair[0]], TargetID: ids[pair[1]], PostURL: "https://" + pair[0] + ".com/1"})
+	}
+	n, _ := g.LoadNetwork()
+
+	if paths := n.ShortestPaths([]int64{ids["a"]}, []int64{ids["d"]}, false, 5); len(paths) != 2 {
+		t.Errorf("Expected both routes, got %v", paths)
+	}
+	if paths := n.ShortestPaths([]int64{ids["a"]}, []int64{ids["d"]}, false, 1); len(paths) != 1 {
+		t.Errorf("Expected limit to apply, got %v", paths)
+	}
+}
+
+func TestNetwork_MergeHosts(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+
+	// b.com's feed links to c.com, while a.com links to b.com's site root
+	a, _ := g.AddFeed(&FeedNode{URL: "https://a.com/feed", Subscribed: true})
+	bRoot, _ := g.AddFeed(&FeedNode{URL: "https://b.com/", Title: "Bee"})
+	bFeed, _ := g.AddFeed(&FeedNode{URL: "https://www.b.com/feed.xml", Subscribed: true})
+	c, _ := g.AddFeed(&FeedNode{URL: "https://c.com/"})
+	g.AddLink(&LinkEdge{SourceID: a, TargetID: bRoot, PostURL: "https://a.com/1"})
+	g.AddLink(&LinkEdge{SourceID: bFeed, TargetID: c, PostURL: "https://b.com/1"})
+	g.AddLink(&LinkEdge{SourceID: bFeed, TargetID: bRoot, PostURL: "https://b.com/2"})
+
+	n, _ := g.LoadNetwork()
+	if paths := n.ShortestPaths([]int64{a}, []int64{c}, false, 5); len(paths) != 0 {
+		t.Fatalf("Expected no path before merging, got %v", paths)
+	}
+
+	merged := n.MergeHosts()
+	if len(merged.Nodes) != 3 {
+		t.Fatalf("Expected 3 sites, got %d", len(merged.Nodes))
+	}
+	if merged.Nodes[bFeed] == nil || len(merged.Members[bFeed]) != 2 {
+		t.Errorf("Expected b.com merged into its subscribed feed, got %v", merged.Members)
+	}
+	if merged.Nodes[bFeed].Title != "Bee" || n.Nodes[bFeed].Title != "" {
+		t.Errorf("Expected merged node to borrow its member's title, got %q", merged.Nodes[bFeed].Title)
+	}
+	if merged.Resolve(bRoot) != bFeed || merged.Resolve(a) != a || merged.Resolve(999) != 0 {
+		t.Error("Expected Resolve to map members to their merged node")
+	}
+	if len(merged.Edges) != 2 {
+		t.Errorf("Expected self-links within b.com dropped, got %d edges", len(merged.Edges))
+	}
+	if paths := merged.ShortestPaths([]int64{a}, []int64{c}, false, 5); len(paths) != 1 || len(paths[0]) != 3 {
+		t.Errorf("Expected a -> b -> c after merging, got %v", paths)
+	}
+}
+
+func TestNetwork_Distances(t *testing.T) {
+	g, ids := newTestNetwork(t)
+	defer g.Close()
+	n, _ := g.LoadNetwork()
+
+	dist := n.Distances([]int64{ids["a"]}, 2)
+	if len(dist) != 3 || dist[ids["a"]] != 0 || dist[ids["b"]] != 1 || dist[ids["c"]] != 2 {
+		t.Errorf("Expected a=0 b=1 c=2, got %v", dist)
+	}
+}
+
+// newTwoCommunityGraph builds two triangles of feeds linked heavily within
+// themselves and joined by a single link, plus an isolated feed.
+func newTwoCommunityGraph(t *testing.T) (*Graph, map[string]int64) {
+	t.Helper()
+	g := newTestGraph(t)
+
+	ids := make(map[string]int64)
+	for _, name := range []string{"ai1", "ai2", "ai3", "go1", "go2", "go3", "lonely"} {
+		ids[name], _ = g.AddFeed(&FeedNode{URL: "https://" + name + ".com/", Title: name})
+	}
+	link := func(from, to string, posts int) {
+		for i := 0; i < posts; i++ {
+			g.AddLink(&LinkEdge{SourceID: ids[from], TargetID: ids[to], PostURL: "https://" + from + ".com/" + to + string(rune('a'+i))})
+		}
+	}
+	for _, group := range [][]string{{"ai1", "ai2", "ai3"}, {"go1", "go2", "go3"}} {
+		link(group[0], group[1], 3)
+		link(group[1], group[2], 3)
+		link(group[2], group[0], 3)
+	}
+	link("ai1", "go1", 1)
+	return g, ids
+}
+
+func TestNetwork_Communities(t *testing.T) {
+	g, ids := newTwoCommunityGraph(t)
+	defer g.Close()
+	n, _ := g.LoadNetwork()
+
+	communities := n.Communities()
+	if _, ok := communities[ids["lonely"]]; ok {
+		t.Error("Expected feeds without links to be left out")
+	}
+	ai, golang := communities[ids["ai1"]], communities[ids["go1"]]
+	if ai == golang {
+		t.Fatalf("Expected two communities, got %v", communities)
+	}
+	for _, name := range []string{"ai2", "ai3"} {
+		if communities[ids[name]] != ai {
+			t.Errorf("Expected %s with ai1, got %d", name, communities[ids[name]])
+		}
+	}
+	for _, name := range []string{"go2", "go3"} {
+		if communities[ids[name]] != golang {
+			t.Errorf("Expected %s with go1, got %d", name, communities[ids[name]])
+		}
+	}
+
+	// Same input, same answer
+	again := n.Communities()
+	for id, c := range communities {
+		if again[id] != c {
+			t.Fatalf("Expected deterministic communities, got %v then %v", communities, again)
+		}
+	}
+}
+
+func TestNetwork_Similar(t *testing.T) {
+	// 1 and 2 cite both 10 and 11, 3 cites 10 and 12; 10 and 11 both cite 20
+	n := &Network{Nodes: make(map[int64]*FeedNode), Inbound: make(map[int64]int)}
+	for _, e := range [][2]int64{{1, 10}, {1, 11}, {2, 10}, {2, 11}, {3, 10}, {3, 12}, {10, 20}, {11, 20}, {11, 21}} {
+		n.Edges = append(n.Edges, NetworkEdge{SourceID: e[0], TargetID: e[1], Weight: 1})
+		n.Inbound[e[1]]++
+	}
+	n.Inbound[2] = 5 // 2 is better known than 1
+
+	similar := n.Similar(10, Cosine, 10)
+	if len(similar) != 2 || similar[0].ID != 11 || similar[1].ID != 12 {
+		t.Fatalf("Expected 11 then 12, got %+v", similar)
+	}
+	s := similar[0]
+	if math.Abs(s.CoCitation-2/math.Sqrt(6)) > 1e-9 || math.Abs(s.Coupling-1/math.Sqrt(2)) > 1e-9 {
+		t.Errorf("Unexpected cosine similarity: %+v", s)
+	}
+	if math.Abs(s.Score-(s.CoCitation+s.Coupling)/2) > 1e-9 {
+		t.Errorf("Expected score to be the mean, got %+v", s)
+	}
+	if len(s.SharedCiters) != 2 || s.SharedCiters[0] != 2 || s.SharedCiters[1] != 1 {
+		t.Errorf("Expected shared citers 2, 1, got %v", s.SharedCiters)
+	}
+	if len(s.SharedCited) != 1 || s.SharedCited[0] != 20 {
+		t.Errorf("Expected shared cited 20, got %v", s.SharedCited)
+	}
+
+	jaccard := n.Similar(10, Jaccard, 1)
+	if len(jaccard) != 1 || jaccard[0].CoCitation != 2.0/3 || jaccard[0].Coupling != 0.5 {
+		t.Errorf("Unexpected Jaccard similarity: %+v", jaccard)
+	}
+
+	if similar := n.Similar(99, Cosine, 10); len(similar) != 0 {
+		t.Errorf("Expected nothing similar to an unknown node, got %+v", similar)
+	}
+}
+
+func TestNetwork_Reciprocal(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	n := &Network{Edges: []NetworkEdge{
+		{SourceID: 1, TargetID: 2, Weight: 5, LastSeen: day(1)},
+		{SourceID: 1, TargetID: 3, Weight: 2, LastSeen: day(2)},
+		{SourceID: 2, TargetID: 1, Weight: 1, LastSeen: day(3)},
+		{SourceID: 2, TargetID: 4, Weight: 9, LastSeen: day(4)},
+		{SourceID: 3, TargetID: 1, Weight: 2, LastSeen: day(5)},
+	}}
+
+	pairs := n.Reciprocal()
+	if len(pairs) != 2 {
+		t.Fatalf("Expected 2 reciprocal pairs, got %+v", pairs)
+	}
+	// 1 <-> 3 trades 2 links each way, 1 <-> 2 only 1 back
+	if p := pairs[0]; p.AToB.SourceID != 1 || p.AToB.TargetID != 3 || p.Min() != 2 || p.Total() != 4 {
+		t.Errorf("Expected 1 <-> 3 first, got %+v", p)
+	}
+	p := pairs[1]
+	if p.AToB.Weight != 5 || p.BToA.Weight != 1 || !p.LastSeen().Equal(day(3)) {
+		t.Errorf("Unexpected 1 <-> 2 pair: %+v", p)
+	}
+	if s := p.Swap(); s.AToB.SourceID != 2 || s.AToB.Weight != 1 {
+		t.Errorf("Expected swapped pair from 2, got %+v", s)
+	}
+}
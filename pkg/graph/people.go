@@ -0,0 +1,597 @@
+package graph
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PersonSite links a person to a site they likely run, with the evidence
+// for it.
+type PersonSite struct {
+	Name     string // Canonical name of the person
+	Host     string
+	FeedID   int64 // The site's feed: a subscription if there is one, else the first found
+	Anchors  int   // Feeds linking to the site with the person's name as the link text
+	Posts    int   // Posts on the site by the person, per the feed's author field
+	Homepage bool  // The site's homepage names the person as its author
+	RelMe    bool  // rel="me" links tie the site to another of the person's sites
+	Score    float64
+}
+
+// Weights of the evidence for a person running a site. A site needs
+// minPersonScore to be linked to a person, so one link with their name
+// isn't enough on its own.
+const (
+	anchorWeight   = 1 // Per linking feed
+	authorWeight   = 3
+	homepageWeight = 3
+	relMeWeight    = 2
+	minPersonScore = 2
+)
+
+// Homepage is what a site's homepage says about who runs it.
+type Homepage struct {
+	Host      string
+	Author    string   // From <meta name="author">
+	Me        []string // Hosts of the homepage's rel="me" links
+	Error     string   // Why the homepage couldn't be fetched
+	FetchedAt time.Time
+}
+
+// SetHomepage stores what a site's homepage says, replacing what it said
+// before.
+func (g *Graph) SetHomepage(h *Homepage) error {
+	tx, err := g.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`INSERT INTO homepages (host, author, error, fetched_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		 ON CONFLICT(host) DO UPDATE SET
+		   author = excluded.author, error = excluded.error, fetched_at = excluded.fetched_at`,
+		h.Host, nullString(h.Author), nullString(h.Error),
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM rel_me_links WHERE host = ?`, h.Host); err != nil {
+		return err
+	}
+	for _, me := range h.Me {
+		if me == h.Host {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO rel_me_links (host, target_host) VALUES (?, ?)`, h.Host, me); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetHomepages returns the homepages fetched so far, by host.
+func (g *Graph) GetHomepages() (map[string]*Homepage, error) {
+	rows, err := g.db.Query(`SELECT host, author, error, fetched_at FROM homepages`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	homepages := make(map[string]*Homepage)
+	for rows.Next() {
+		var h Homepage
+		var author, errMsg sql.NullString
+		if err := rows.Scan(&h.Host, &author, &errMsg, &h.FetchedAt); err != nil {
+			return nil, err
+		}
+		h.Author, h.Error = author.String, errMsg.String
+		homepages[h.Host] = &h
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = g.db.Query(`SELECT host, target_host FROM rel_me_links ORDER BY host, target_host`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var host, target string
+		if err := rows.Scan(&host, &target); err != nil {
+			return nil, err
+		}
+		if h := homepages[host]; h != nil {
+			h.Me = append(h.Me, target)
+		}
+	}
+	return homepages, rows.Err()
+}
+
+// PersonSites links people to the sites they likely run, best first. The
+// evidence is links to a site whose text is a person's name, as mentions
+// or entity aliases know it; the author field of the site's posts; its
+// homepage's author; and rel="me" links between a site and one already
+// linked to someone. Sites the domain filters deny are left out.
+func (g *Graph) PersonSites() ([]PersonSite, error) {
+	return g.personSites(nil)
+}
+
+// SiteOwners returns the person most likely running each of hosts, for
+// those that have one. Only the evidence for those sites is looked at, so
+// it suits a page of results.
+func (g *Graph) SiteOwners(hosts []string) (map[string]PersonSite, error) {
+	if len(hosts) == 0 {
+		return map[string]PersonSite{}, nil
+	}
+	sites, err := g.personSites(hosts)
+	if err != nil {
+		return nil, err
+	}
+	owners := bestByHost(sites)
+	wanted := make(map[string]PersonSite, len(hosts))
+	for _, host := range hosts {
+		if owner, ok := owners[host]; ok {
+			wanted[host] = owner
+		}
+	}
+	return wanted, nil
+}
+
+// PeopleSites returns the likeliest site of each of names, canonical
+// names of people, for those that have one. Only the sites with evidence
+// for those people are looked at.
+func (g *Graph) PeopleSites(names []string) (map[string]PersonSite, error) {
+	if len(names) == 0 {
+		return map[string]PersonSite{}, nil
+	}
+	hosts, err := g.hostsNaming(names)
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		return map[string]PersonSite{}, nil
+	}
+	sites, err := g.personSites(hosts)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+	var theirs []PersonSite
+	for _, s := range sites {
+		if wanted[s.Name] {
+			theirs = append(theirs, s)
+		}
+	}
+	return bestByName(theirs), nil
+}
+
+// personSites works out PersonSites for the given hosts, or every host if
+// nil. The hosts' rel="me" neighbors are looked at too, since their owners
+// carry over.
+func (g *Graph) personSites(hosts []string) ([]PersonSite, error) {
+	var scope map[string]bool
+	if hosts != nil {
+		var err error
+		if hosts, err = g.withRelMeNeighbors(hosts); err != nil {
+			return nil, err
+		}
+		scope = make(map[string]bool, len(hosts))
+		for _, host := range hosts {
+			scope[host] = true
+		}
+	}
+	inScope := func(host string) bool { return scope == nil || scope[host] }
+	hostsIn := func(col string) (string, []any) {
+		if scope == nil {
+			return "", nil
+		}
+		args := make([]any, len(hosts))
+		for i, host := range hosts {
+			args[i] = host
+		}
+		return ` AND ` + col + ` IN (` + placeholders(len(hosts)) + `)`, args
+	}
+
+	names, err := g.personNames()
+	if err != nil {
+		return nil, err
+	}
+	resolve := func(name string, known bool) string {
+		if canonical, ok := names[strings.ToLower(name)]; ok {
+			return canonical
+		}
+		if known {
+			return ""
+		}
+		return name
+	}
+
+	feeds, err := g.siteFeeds()
+	if err != nil {
+		return nil, err
+	}
+	evidence := make(map[[2]string]*PersonSite)
+	site := func(name, host string) *PersonSite {
+		key := [2]string{name, host}
+		if evidence[key] == nil {
+			evidence[key] = &PersonSite{Name: name, Host: host, FeedID: feeds[host]}
+		}
+		return evidence[key]
+	}
+
+	// Link text is only trusted when it's a name we already know
+	cond, args := g.unfiltered("t")
+	hostCond, hostArgs := hostsIn("t.host")
+	rows, err := g.db.Query(
+		`SELECT trim(l.context), t.host, COUNT(DISTINCT l.source_id)
+		 FROM links l
+		 JOIN feeds t ON t.id = l.target_id
+		 WHERE l.retracted_at IS NULL AND instr(trim(l.context), ' ') > 0 AND length(l.context) <= 100
+		   AND t.host != '' AND `+cond+hostCond+`
+		 GROUP BY 1, 2`,
+		append(args, hostArgs...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var text, host string
+		var feeds int
+		if err := rows.Scan(&text, &host, &feeds); err != nil {
+			return nil, err
+		}
+		if name := resolve(text, true); name != "" {
+			site(name, host).Anchors += feeds
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cond, args = g.unfiltered("f")
+	hostCond, hostArgs = hostsIn("f.host")
+	rows, err = g.db.Query(
+		`SELECT p.author, f.host, COUNT(*)
+		 FROM posts p
+		 JOIN feeds f ON f.id = p.feed_id
+		 WHERE p.author IS NOT NULL AND p.removed_at IS NULL AND f.host != '' AND `+cond+hostCond+`
+		 GROUP BY 1, 2`,
+		append(args, hostArgs...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var author, host string
+		var posts int
+		if err := rows.Scan(&author, &host, &posts); err != nil {
+			return nil, err
+		}
+		if name := authorName(author); name != "" {
+			site(resolve(name, false), host).Posts += posts
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	homepages, err := g.GetHomepages()
+	if err != nil {
+		return nil, err
+	}
+	filtered, err := g.filteredHosts()
+	if err != nil {
+		return nil, err
+	}
+	for host, h := range homepages {
+		if name := authorName(h.Author); name != "" && inScope(host) && !filtered[host] {
+			site(resolve(name, false), host).Homepage = true
+		}
+	}
+
+	var sites []PersonSite
+	for _, s := range evidence {
+		s.Score = float64(anchorWeight*s.Anchors) + boolWeight(s.Posts > 0, authorWeight) + boolWeight(s.Homepage, homepageWeight)
+		if s.Score >= minPersonScore {
+			sites = append(sites, *s)
+		}
+	}
+
+	// rel="me" links tie a site to the person running the site on the
+	// other end, whichever way they point
+	owners := bestByHost(sortPersonSites(sites))
+	for host, h := range homepages {
+		for _, other := range h.Me {
+			for _, pair := range [][2]string{{host, other}, {other, host}} {
+				owner, ok := owners[pair[0]]
+				if !ok || !inScope(pair[1]) || filtered[pair[1]] || feeds[pair[1]] == 0 {
+					continue
+				}
+				s := site(owner.Name, pair[1])
+				if !s.RelMe {
+					s.RelMe = true
+					s.Score += relMeWeight
+					if s.Score-relMeWeight < minPersonScore {
+						sites = append(sites, *s)
+					}
+				}
+			}
+		}
+	}
+	// Scores of sites listed before rel="me" raised them
+	for i := range sites {
+		sites[i] = *evidence[[2]string{sites[i].Name, sites[i].Host}]
+	}
+	return sortPersonSites(sites), nil
+}
+
+func boolWeight(b bool, weight float64) float64 {
+	if b {
+		return weight
+	}
+	return 0
+}
+
+func sortPersonSites(sites []PersonSite) []PersonSite {
+	sort.Slice(sites, func(i, j int) bool {
+		if sites[i].Score != sites[j].Score {
+			return sites[i].Score > sites[j].Score
+		}
+		if sites[i].Name != sites[j].Name {
+			return sites[i].Name < sites[j].Name
+		}
+		return sites[i].Host < sites[j].Host
+	})
+	return sites
+}
+
+// bestByHost returns the first of sites, best first, for each host.
+func bestByHost(sites []PersonSite) map[string]PersonSite {
+	byHost := make(map[string]PersonSite)
+	for _, s := range sites {
+		if _, ok := byHost[s.Host]; !ok {
+			byHost[s.Host] = s
+		}
+	}
+	return byHost
+}
+
+// bestByName returns the first of sites, best first, for each person.
+func bestByName(sites []PersonSite) map[string]PersonSite {
+	byName := make(map[string]PersonSite)
+	for _, s := range sites {
+		if _, ok := byName[s.Name]; !ok {
+			byName[s.Name] = s
+		}
+	}
+	return byName
+}
+
+// withRelMeNeighbors returns hosts along with the hosts they have rel="me"
+// links to or from.
+func (g *Graph) withRelMeNeighbors(hosts []string) ([]string, error) {
+	args := make([]any, 0, 2*len(hosts))
+	for range 2 {
+		for _, host := range hosts {
+			args = append(args, host)
+		}
+	}
+	rows, err := g.db.Query(
+		`SELECT target_host FROM rel_me_links WHERE host IN (`+placeholders(len(hosts))+`)
+		 UNION
+		 SELECT host FROM rel_me_links WHERE target_host IN (`+placeholders(len(hosts))+`)`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool, len(hosts))
+	all := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if !seen[host] {
+			seen[host] = true
+			all = append(all, host)
+		}
+	}
+	for rows.Next() {
+		var host string
+		if err := rows.Scan(&host); err != nil {
+			return nil, err
+		}
+		if !seen[host] {
+			seen[host] = true
+			all = append(all, host)
+		}
+	}
+	return all, rows.Err()
+}
+
+// hostsNaming returns the hosts with evidence of their own for any of
+// names: links naming the person, posts by them or a homepage naming them
+// as its author.
+func (g *Graph) hostsNaming(names []string) ([]string, error) {
+	known, err := g.personNames()
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+	var aliases []any
+	for alias, canonical := range known {
+		if wanted[canonical] {
+			aliases = append(aliases, alias)
+		}
+	}
+	if len(aliases) == 0 {
+		return nil, nil
+	}
+	resolves := func(name string) bool {
+		if canonical, ok := known[strings.ToLower(name)]; ok {
+			return wanted[canonical]
+		}
+		return wanted[name]
+	}
+
+	hosts := make(map[string]bool)
+	rows, err := g.db.Query(
+		`SELECT DISTINCT t.host FROM links l JOIN feeds t ON t.id = l.target_id
+		 WHERE l.retracted_at IS NULL AND t.host != ''
+		   AND lower(trim(l.context)) IN (`+placeholders(len(aliases))+`)`,
+		aliases...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var host string
+		if err := rows.Scan(&host); err != nil {
+			return nil, err
+		}
+		hosts[host] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Author fields hold more than the name, so match loosely and check
+	// each candidate
+	matches := strings.TrimSuffix(strings.Repeat("instr(lower(p.author), ?) > 0 OR ", len(aliases)), " OR ")
+	rows, err = g.db.Query(
+		`SELECT DISTINCT p.author, f.host FROM posts p JOIN feeds f ON f.id = p.feed_id
+		 WHERE p.removed_at IS NULL AND f.host != '' AND (`+matches+`)`,
+		aliases...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var author, host string
+		if err := rows.Scan(&author, &host); err != nil {
+			return nil, err
+		}
+		if name := authorName(author); name != "" && resolves(name) {
+			hosts[host] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	homepages, err := g.GetHomepages()
+	if err != nil {
+		return nil, err
+	}
+	for host, h := range homepages {
+		if name := authorName(h.Author); name != "" && resolves(name) {
+			hosts[host] = true
+		}
+	}
+
+	list := make([]string, 0, len(hosts))
+	for host := range hosts {
+		list = append(list, host)
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// personNames maps the case-folded names of people, as mentioned or as
+// entity aliases, to their canonical names.
+func (g *Graph) personNames() (map[string]string, error) {
+	rows, err := g.db.Query(
+		`SELECT DISTINCT m.name, ` + canonicalName("m") + ` FROM mentions m` + entityJoin("m") + `
+		 WHERE m.entity_type = 'PERSON' AND m.retracted_at IS NULL
+		 UNION
+		 SELECT ea.alias, e.name FROM entity_aliases ea JOIN entities e ON e.id = ea.entity_id
+		 WHERE ea.entity_type = 'PERSON'`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[string]string)
+	for rows.Next() {
+		var name, canonical string
+		if err := rows.Scan(&name, &canonical); err != nil {
+			return nil, err
+		}
+		names[strings.ToLower(name)] = canonical
+	}
+	return names, rows.Err()
+}
+
+// siteFeeds maps hosts to their feed: a subscription if there is one, else
+// the first found.
+func (g *Graph) siteFeeds() (map[string]int64, error) {
+	rows, err := g.db.Query(`SELECT host, id FROM feeds WHERE host != '' ORDER BY subscribed DESC, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feeds := make(map[string]int64)
+	for rows.Next() {
+		var host string
+		var id int64
+		if err := rows.Scan(&host, &id); err != nil {
+			return nil, err
+		}
+		if feeds[host] == 0 {
+			feeds[host] = id
+		}
+	}
+	return feeds, rows.Err()
+}
+
+// filteredHosts returns the hosts of the feeds the domain filters leave out.
+func (g *Graph) filteredHosts() (map[string]bool, error) {
+	cond, args := g.unfiltered("f")
+	rows, err := g.db.Query(`SELECT DISTINCT f.host FROM feeds f WHERE f.host != '' AND NOT `+cond, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	filtered := make(map[string]bool)
+	for rows.Next() {
+		var host string
+		if err := rows.Scan(&host); err != nil {
+			return nil, err
+		}
+		filtered[host] = true
+	}
+	return filtered, rows.Err()
+}
+
+// authorName extracts a person's name from an author field, such as RSS's
+// "simon@example.com (Simon Willison)". It returns "" for fields that
+// aren't a full name, like an email address or a lone handle.
+func authorName(author string) string {
+	author = strings.TrimSpace(author)
+	if open := strings.LastIndex(author, "("); open > 0 && strings.HasSuffix(author, ")") {
+		author = author[open+1 : len(author)-1]
+	} else if open := strings.Index(author, "<"); open > 0 && strings.HasSuffix(author, ">") {
+		author = author[:open]
+	}
+	author = strings.Join(strings.Fields(author), " ")
+	if !strings.Contains(author, " ") || strings.ContainsAny(author, "@/") {
+		return ""
+	}
+	return author
+}
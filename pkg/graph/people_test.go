@@ -0,0 +1,118 @@
+package graph
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGraph_PersonSites(t *testing.T) {
+	g := newTestGraph(t)
+	defer g.Close()
+	g.SetCommonDomains([]string{"github.com"})
+
+	aID, _ := g.AddFeed(&FeedNode{URL: "https://a.com/", Subscribed: true})
+	bID, _ := g.AddFeed(&FeedNode{URL: "https://b.com/", Subscribed: true})
+	simonID, _ := g.AddFeed(&FeedNode{URL: "https://simonwillison.net/atom/everything/"})
+	tilID, _ := g.AddFeed(&FeedNode{URL: "https://til.simonwillison.net/feed"})
+	juliaID, _ := g.AddFeed(&FeedNode{URL: "https://jvns.ca/atom.xml", Subscribed: true})
+	githubID, _ := g.AddFeed(&FeedNode{URL: "https://github.com/simonw"})
+	robID, _ := g.AddFeed(&FeedNode{URL: "https://commandcenter.blogspot.com/"})
+
+	addMentions(t, g, aID, "https://a.com/1", "Simon Willison", "Rob Pike")
+	addMentions(t, g, bID, "https://b.com/1", "simon willison")
+	g.MergeEntities("PERSON", "Simon Willison", []string{"simon willison"})
+
+	// Two feeds link to Simon's blog with his name; the filtered GitHub
+	// profile gets the same, and one link with Rob's name isn't enough
+	for i, link := range []LinkEdge{
+		{SourceID: aID, TargetID: simonID, Context: "Simon Willison"},
+		{SourceID: bID, TargetID: simonID, Context: " simon willison "},
+		{SourceID: aID, TargetID: githubID, Context: "Simon Willison"},
+		{SourceID: bID, TargetID: githubID, Context: "Simon Willison"},
+		{SourceID: aID, TargetID: robID, Context: "Rob Pike"},
+		{SourceID: aID, TargetID: juliaID, Context: "this great post"},
+	} {
+		link.PostURL = fmt.Sprintf("https://x.com/%d", i)
+		if err := g.AddLink(&link); err != nil {
+			t.Fatalf("AddLink error: %v", err)
+		}
+	}
+	g.AddPost(&Post{FeedID: juliaID, URL: "https://jvns.ca/1", Author: "Julia Evans"})
+	g.AddPost(&Post{FeedID: juliaID, URL: "https://jvns.ca/2", Author: "julia@example.com"})
+	g.SetHomepage(&Homepage{Host: "simonwillison.net", Me: []string{"til.simonwillison.net", "simonwillison.net"}})
+
+	sites, err := g.PersonSites()
+	if err != nil {
+		t.Fatalf("PersonSites error: %v", err)
+	}
+	byHost := bestByHost(sites)
+	if s := byHost["simonwillison.net"]; s.Name != "Simon Willison" || s.Anchors != 2 || s.FeedID != simonID {
+		t.Errorf("Expected Simon's blog from two links, got %+v", s)
+	}
+	if s := byHost["til.simonwillison.net"]; s.Name != "Simon Willison" || !s.RelMe || s.FeedID != tilID {
+		t.Errorf("Expected Simon's TIL from rel=me, got %+v", s)
+	}
+	if s := byHost["jvns.ca"]; s.Name != "Julia Evans" || s.Posts != 1 || s.Score != authorWeight {
+		t.Errorf("Expected Julia's blog from the author field, got %+v", s)
+	}
+	for _, host := range []string{"github.com", "commandcenter.blogspot.com"} {
+		if s, ok := byHost[host]; ok {
+			t.Errorf("Expected no one linked to %s, got %+v", host, s)
+		}
+	}
+	if len(sites) != 3 {
+		t.Errorf("Expected 3 sites, got %+v", sites)
+	}
+
+	// The homepage's author is enough on its own, and rel=me stays with
+	// the host that has it
+	g.SetHomepage(&Homepage{Host: "commandcenter.blogspot.com", Author: "Rob Pike"})
+	sites, _ = g.PersonSites()
+	if s := bestByName(sites)["Rob Pike"]; s.Host != "commandcenter.blogspot.com" || s.Score != homepageWeight+anchorWeight {
+		t.Errorf("Expected Rob's blog from its homepage, got %+v", s)
+	}
+	// Looking up only some sites or people gives what the full list has
+	// for them
+	owners, err := g.SiteOwners([]string{"til.simonwillison.net", "jvns.ca", "github.com"})
+	if err != nil {
+		t.Fatalf("SiteOwners error: %v", err)
+	}
+	byHost = bestByHost(sites)
+	if len(owners) != 2 || owners["til.simonwillison.net"] != byHost["til.simonwillison.net"] || owners["jvns.ca"] != byHost["jvns.ca"] {
+		t.Errorf("Expected the owners of Simon's TIL and Julia's blog, got %+v", owners)
+	}
+	people, err := g.PeopleSites([]string{"Simon Willison", "Rob Pike", "Nobody"})
+	if err != nil {
+		t.Fatalf("PeopleSites error: %v", err)
+	}
+	byName := bestByName(sites)
+	if len(people) != 2 || people["Simon Willison"] != byName["Simon Willison"] || people["Rob Pike"] != byName["Rob Pike"] {
+		t.Errorf("Expected Simon's and Rob's sites, got %+v", people)
+	}
+
+	homepages, err := g.GetHomepages()
+	if err != nil {
+		t.Fatalf("GetHomepages error: %v", err)
+	}
+	if h := homepages["simonwillison.net"]; len(h.Me) != 1 || h.Me[0] != "til.simonwillison.net" {
+		t.Errorf("Expected the rel=me link to the other host only, got %+v", h)
+	}
+}
+
+func TestAuthorName(t *testing.T) {
+	tests := []struct {
+		author, want string
+	}{
+		{"Simon Willison", "Simon Willison"},
+		{"simon@example.com (Simon Willison)", "Simon Willison"},
+		{"Julia  Evans <julia@example.com>", "Julia Evans"},
+		{"julia@example.com", ""},
+		{"admin", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := authorName(tt.author); got != tt.want {
+			t.Errorf("authorName(%q) = %q, want %q", tt.author, got, tt.want)
+		}
+	}
+}
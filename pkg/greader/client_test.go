@@ -0,0 +1,199 @@
+package greader
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daniel-butler/rss-graph/pkg/source"
+)
+
+// newTestServer returns a FreshRSS-like stand-in serving feed/1 as two pages.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/accounts/ClientLogin", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("Email") != "alice" || r.FormValue("Passwd") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("Error=BadAuthentication"))
+			return
+		}
+		w.Write([]byte("SID=alice/abc\nLSID=null\nAuth=alice/abc\n"))
+	})
+
+	requireAuth := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Authorization") != "GoogleLogin auth=alice/abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return false
+		}
+		return true
+	}
+
+	mux.HandleFunc("/reader/api/0/subscription/list", func(w http.ResponseWriter, r *http.Request) {
+		if !requireAuth(w, r) {
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"subscriptions": []Subscription{
+				{
+					ID:         "feed/1",
+					Title:      "Julia Evans",
+					URL:        "https://jvns.ca/atom.xml",
+					HTMLURL:    "https://jvns.ca/",
+					Categories: []Category{{ID: "user/-/label/Programming", Label: "Programming"}},
+				},
+			},
+		})
+	})
+
+	mux.HandleFunc("/reader/api/0/stream/contents/", func(w http.ResponseWriter, r *http.Request) {
+		if !requireAuth(w, r) {
+			return
+		}
+		if stream := strings.TrimPrefix(r.URL.Path, "/reader/api/0/stream/contents/"); stream != "feed/1" {
+			t.Errorf("Expected stream feed/1, got %s", stream)
+		}
+		if r.URL.Query().Get("c") == "" {
+			json.NewEncoder(w).Encode(StreamResponse{
+				Items: []Item{
+					{
+						ID:         "tag:google.com,2005:reader/item/0000000000000003",
+						Title:      "Third",
+						Published:  1700000300,
+						Canonical:  []Href{{Href: "https://jvns.ca/3"}},
+						Content:    Content{Content: "<p>third</p>"},
+						Categories: []string{"user/-/state/com.google/reading-list", "user/-/state/com.google/starred"},
+					},
+					{
+						ID:        "tag:google.com,2005:reader/item/0000000000000002",
+						Title:     "Second",
+						Published: 1700000200,
+						Alternate: []Href{{Href: "https://jvns.ca/2"}},
+						Summary:   Content{Content: "<p>second</p>"},
+					},
+				},
+				Continuation: "page2",
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(StreamResponse{
+			Items: []Item{
+				{ID: "tag:google.com,2005:reader/item/0000000000000001", Title: "First", Published: 1700000100},
+			},
+		})
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestClient_ListFeeds(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	client := NewClient(server.URL, "alice", "secret")
+	feeds, err := client.ListFeeds()
+	if err != nil {
+		t.Fatalf("ListFeeds error: %v", err)
+	}
+
+	if len(feeds) != 1 {
+		t.Fatalf("Expected 1 feed, got %d", len(feeds))
+	}
+	f := feeds[0]
+	if f.ID != "feed/1" || f.FeedURL != "https://jvns.ca/atom.xml" || f.SiteURL != "https://jvns.ca/" {
+		t.Errorf("Unexpected feed: %+v", f)
+	}
+	if len(f.Categories) != 1 || f.Categories[0] != "Programming" {
+		t.Errorf("Expected category Programming, got %v", f.Categories)
+	}
+}
+
+func TestClient_ListEntries_Paginates(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	client := NewClient(server.URL, "alice", "secret")
+	it := client.ListEntries("feed/1", source.Cursor{})
+
+	var entries []source.Entry
+	for it.Next() {
+		entries = append(entries, it.Entry())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Iteration error: %v", err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.ID != 3 || e.URL != "https://jvns.ca/3" || e.Content != "<p>third</p>" || !e.Starred {
+		t.Errorf("Unexpected first entry: %+v", e)
+	}
+	if !e.PublishedAt.Equal(time.Unix(1700000300, 0)) {
+		t.Errorf("Unexpected publish date: %v", e.PublishedAt)
+	}
+	if entries[1].URL != "https://jvns.ca/2" || entries[1].Content != "<p>second</p>" || entries[1].Starred {
+		t.Errorf("Expected alternate link and summary fallback, got %+v", entries[1])
+	}
+}
+
+func TestClient_ListEntries_StopsAtCursor(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	client := NewClient(server.URL, "alice", "secret")
+	it := client.ListEntries("feed/1", source.Cursor{AfterEntryID: 2})
+
+	var ids []int64
+	for it.Next() {
+		ids = append(ids, it.Entry().ID)
+	}
+	if len(ids) != 1 || ids[0] != 3 {
+		t.Errorf("Expected only entry 3, got %v", ids)
+	}
+}
+
+func TestClient_BadLogin(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	client := NewClient(server.URL, "alice", "wrong")
+	if _, err := client.ListFeeds(); err == nil {
+		t.Error("Expected error for bad credentials")
+	}
+}
+
+func TestClient_RelogsInOnExpiredToken(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	client := NewClient(server.URL, "alice", "secret")
+	client.authToken = "alice/expired"
+	feeds, err := client.ListFeeds()
+	if err != nil {
+		t.Fatalf("ListFeeds error: %v", err)
+	}
+	if len(feeds) != 1 || client.authToken != "alice/abc" {
+		t.Errorf("Expected the feed with a fresh token, got %v with %q", feeds, client.authToken)
+	}
+}
+
+func TestParseItemID(t *testing.T) {
+	tests := map[string]int64{
+		"tag:google.com,2005:reader/item/000000000000001f": 31,
+		"tag:google.com,2005:reader/item/7fffffffffffffff": 1<<63 - 1,
+		"tag:google.com,2005:reader/item/8000000000000000": 0,
+		"tag:google.com,2005:reader/item/ffffffffffffffff": 0,
+		"31":                  31,
+		"9223372036854775808": 0,
+		"not-an-id":           0,
+	}
+	for id, want := range tests {
+		if got := ParseItemID(id); got != want {
+			t.Errorf("ParseItemID(%q) = %d, want %d", id, got, want)
+		}
+	}
+}
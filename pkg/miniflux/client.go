@@ -2,14 +2,19 @@
 package miniflux
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
+
+	"github.com/daniel-butler/rss-graph/pkg/source"
 )
 
-// Client is a Miniflux API client.
+// Client is a Miniflux API client. It implements source.Source.
 type Client struct {
 	baseURL    string
 	apiKey     string
@@ -18,24 +23,33 @@ type Client struct {
 
 // Feed represents a Miniflux feed subscription.
 type Feed struct {
-	ID       int64  `json:"id"`
-	Title    string `json:"title"`
-	FeedURL  string `json:"feed_url"`
-	SiteURL  string `json:"site_url"`
-	Category struct {
-		ID    int64  `json:"id"`
-		Title string `json:"title"`
-	} `json:"category"`
+	ID       int64    `json:"id"`
+	Title    string   `json:"title"`
+	FeedURL  string   `json:"feed_url"`
+	SiteURL  string   `json:"site_url"`
+	Category Category `json:"category"`
+}
+
+// Category represents a Miniflux category.
+type Category struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
 }
 
 // Entry represents a feed entry/post.
 type Entry struct {
-	ID      int64  `json:"id"`
-	Title   string `json:"title"`
-	URL     string `json:"url"`
-	Content string `json:"content"`
-	Author  string `json:"author"`
-	FeedID  int64  `json:"feed_id"`
+	ID          int64     `json:"id"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Content     string    `json:"content"`
+	Author      string    `json:"author"`
+	FeedID      int64     `json:"feed_id"`
+	PublishedAt time.Time `json:"published_at"`
+	ChangedAt   time.Time `json:"changed_at"`
+	Hash        string    `json:"hash"` // Hash of the entry's GUID in its feed
+	Tags        []string  `json:"tags"`
+	ReadingTime int       `json:"reading_time"` // Minutes
+	Starred     bool      `json:"starred"`
 }
 
 // EntriesResponse is the API response for entries.
@@ -44,6 +58,13 @@ type EntriesResponse struct {
 	Entries []Entry `json:"entries"`
 }
 
+// Subscription is a feed found by Miniflux's discovery endpoint.
+type Subscription struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	Type  string `json:"type"`
+}
+
 // NewClient creates a new Miniflux API client.
 func NewClient(baseURL, apiKey string) *Client {
 	return &Client{
@@ -82,58 +103,299 @@ func (c *Client) GetFeeds() ([]Feed, error) {
 	return feeds, nil
 }
 
+// EntryFilter selects entries and controls pagination.
+type EntryFilter struct {
+	Limit          int       // Page size (default 100)
+	Offset         int       // Number of entries to skip
+	Order          string    // Sort field: id, published_at, ...
+	Direction      string    // asc or desc
+	AfterEntryID   int64     // Only entries with a greater ID
+	PublishedAfter time.Time // Only entries published after this time
+}
+
+const defaultPageSize = 100
+
+func (f EntryFilter) values() url.Values {
+	v := url.Values{}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	v.Set("limit", strconv.Itoa(limit))
+	if f.Offset > 0 {
+		v.Set("offset", strconv.Itoa(f.Offset))
+	}
+	if f.Order != "" {
+		v.Set("order", f.Order)
+	}
+	if f.Direction != "" {
+		v.Set("direction", f.Direction)
+	}
+	if f.AfterEntryID > 0 {
+		v.Set("after_entry_id", strconv.FormatInt(f.AfterEntryID, 10))
+	}
+	if !f.PublishedAfter.IsZero() {
+		v.Set("published_after", strconv.FormatInt(f.PublishedAfter.Unix(), 10))
+	}
+	return v
+}
+
 // GetEntries returns entries for a specific feed.
 func (c *Client) GetEntries(feedID int64, limit int) ([]Entry, error) {
-	url := fmt.Sprintf("%s/v1/feeds/%d/entries?limit=%d", c.baseURL, feedID, limit)
-	req, err := http.NewRequest("GET", url, nil)
+	response, err := c.GetEntriesPage(feedID, EntryFilter{Limit: limit})
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("X-Auth-Token", c.apiKey)
+	return response.Entries, nil
+}
 
-	resp, err := c.httpClient.Do(req)
+// GetAllEntries returns all recent entries across all feeds.
+func (c *Client) GetAllEntries(limit int) ([]Entry, error) {
+	response, err := c.GetEntriesPage(0, EntryFilter{Limit: limit, Order: "published_at", Direction: "desc"})
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return response.Entries, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+// GetEntriesPage returns a single page of entries matching the filter,
+// along with the total number of matching entries. A feedID of 0 selects
+// entries across all feeds.
+func (c *Client) GetEntriesPage(feedID int64, filter EntryFilter) (*EntriesResponse, error) {
+	path := "/v1/entries"
+	if feedID != 0 {
+		path = fmt.Sprintf("/v1/feeds/%d/entries", feedID)
 	}
 
 	var response EntriesResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	if err := c.doJSON("GET", path+"?"+filter.values().Encode(), nil, &response); err != nil {
+		return nil, err
 	}
+	return &response, nil
+}
 
-	return response.Entries, nil
+// EntryIterator pages through entries matching a filter.
+//
+//	it := client.IterEntries(feedID, filter)
+//	for it.Next() {
+//		entry := it.Entry()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type EntryIterator struct {
+	client *Client
+	feedID int64
+	filter EntryFilter
+
+	page    []Entry
+	pos     int
+	fetched int
+	total   int
+	done    bool
+	err     error
 }
 
-// GetAllEntries returns all recent entries across all feeds.
-func (c *Client) GetAllEntries(limit int) ([]Entry, error) {
-	url := fmt.Sprintf("%s/v1/entries?limit=%d&order=published_at&direction=desc", c.baseURL, limit)
-	req, err := http.NewRequest("GET", url, nil)
+// IterEntries returns an iterator over all entries matching the filter,
+// fetching pages of filter.Limit entries as needed. A feedID of 0 selects
+// entries across all feeds.
+func (c *Client) IterEntries(feedID int64, filter EntryFilter) *EntryIterator {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageSize
+	}
+	return &EntryIterator{client: c, feedID: feedID, filter: filter}
+}
+
+// Next advances to the next entry, fetching another page when needed.
+// It returns false when there are no more entries or an error occurred.
+func (it *EntryIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.pos+1 < len(it.page) {
+		it.pos++
+		return true
+	}
+	if it.done {
+		return false
+	}
+
+	response, err := it.client.GetEntriesPage(it.feedID, it.filter)
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	it.page = response.Entries
+	it.pos = 0
+	it.total = response.Total
+	it.fetched += len(response.Entries)
+	it.filter.Offset += len(response.Entries)
+	if len(response.Entries) < it.filter.Limit || it.fetched >= it.total {
+		it.done = true
+	}
+	return len(it.page) > 0
+}
+
+// Entry returns the current entry.
+func (it *EntryIterator) Entry() Entry {
+	return it.page[it.pos]
+}
+
+// Total returns the number of matching entries reported by the server.
+func (it *EntryIterator) Total() int {
+	return it.total
+}
+
+// Err returns the error that stopped iteration, if any.
+func (it *EntryIterator) Err() error {
+	return it.err
+}
+
+// Discover asks Miniflux to find the feeds advertised by a website.
+func (c *Client) Discover(siteURL string) ([]Subscription, error) {
+	var subs []Subscription
+	err := c.doJSON("POST", "/v1/discover", map[string]string{"url": siteURL}, &subs)
 	if err != nil {
 		return nil, err
 	}
+	return subs, nil
+}
+
+// CreateFeed subscribes to a feed in the given category, returning the new feed ID.
+func (c *Client) CreateFeed(feedURL string, categoryID int64) (int64, error) {
+	request := map[string]any{
+		"feed_url":    feedURL,
+		"category_id": categoryID,
+	}
+	var response struct {
+		FeedID int64 `json:"feed_id"`
+	}
+	if err := c.doJSON("POST", "/v1/feeds", request, &response); err != nil {
+		return 0, err
+	}
+	return response.FeedID, nil
+}
+
+// GetCategories returns all categories.
+func (c *Client) GetCategories() ([]Category, error) {
+	var categories []Category
+	if err := c.doJSON("GET", "/v1/categories", nil, &categories); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// CreateCategory creates a category with the given title.
+func (c *Client) CreateCategory(title string) (*Category, error) {
+	var category Category
+	if err := c.doJSON("POST", "/v1/categories", map[string]string{"title": title}, &category); err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// doJSON sends a request with an optional JSON body and decodes the JSON response into out.
+func (c *Client) doJSON(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
 	req.Header.Set("X-Auth-Token", c.apiKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		return fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
 	}
 
-	var response EntriesResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	if out == nil {
+		return nil
 	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
 
-	return response.Entries, nil
+var _ source.Source = (*Client)(nil)
+
+// Name implements source.Source.
+func (c *Client) Name() string {
+	return "miniflux"
+}
+
+// ListFeeds implements source.Source.
+func (c *Client) ListFeeds() ([]source.Feed, error) {
+	feeds, err := c.GetFeeds()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]source.Feed, 0, len(feeds))
+	for _, f := range feeds {
+		sf := source.Feed{
+			ID:      strconv.FormatInt(f.ID, 10),
+			Title:   f.Title,
+			FeedURL: f.FeedURL,
+			SiteURL: f.SiteURL,
+		}
+		if f.Category.Title != "" {
+			sf.Categories = []string{f.Category.Title}
+		}
+		result = append(result, sf)
+	}
+	return result, nil
+}
+
+// ListEntries implements source.Source.
+func (c *Client) ListEntries(feedID string, since source.Cursor) source.EntryIterator {
+	id, err := strconv.ParseInt(feedID, 10, 64)
+	if err != nil {
+		return &sourceEntries{it: &EntryIterator{err: fmt.Errorf("invalid feed ID %q", feedID)}}
+	}
+	return &sourceEntries{it: c.IterEntries(id, EntryFilter{
+		Order:          "id",
+		Direction:      "desc",
+		AfterEntryID:   since.AfterEntryID,
+		PublishedAfter: since.PublishedAfter,
+	})}
+}
+
+// sourceEntries adapts EntryIterator to source.EntryIterator.
+type sourceEntries struct {
+	it *EntryIterator
+}
+
+func (s *sourceEntries) Next() bool { return s.it.Next() }
+func (s *sourceEntries) Err() error { return s.it.Err() }
+
+func (s *sourceEntries) Entry() source.Entry {
+	e := s.it.Entry()
+	return source.Entry{
+		ID:          e.ID,
+		GUID:        e.Hash,
+		Title:       e.Title,
+		URL:         e.URL,
+		Content:     e.Content,
+		Author:      e.Author,
+		PublishedAt: e.PublishedAt,
+		UpdatedAt:   e.ChangedAt,
+		Starred:     e.Starred,
+	}
 }
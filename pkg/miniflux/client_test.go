@@ -4,7 +4,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
+
+	"github.com/daniel-butler/rss-graph/pkg/source"
 )
 
 func TestClient_GetFeeds(t *testing.T) {
@@ -93,3 +97,253 @@ func TestClient_ServerError(t *testing.T) {
 		t.Error("Expected error for server error")
 	}
 }
+
+func TestClient_Discover(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/v1/discover" {
+			t.Errorf("Expected POST /v1/discover, got %s %s", r.Method, r.URL.Path)
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["url"] != "https://jvns.ca/" {
+			t.Errorf("Expected url https://jvns.ca/, got %s", body["url"])
+		}
+
+		json.NewEncoder(w).Encode([]Subscription{
+			{Title: "Julia Evans", URL: "https://jvns.ca/atom.xml", Type: "atom"},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-api-key")
+	subs, err := client.Discover("https://jvns.ca/")
+	if err != nil {
+		t.Fatalf("Discover error: %v", err)
+	}
+
+	if len(subs) != 1 || subs[0].URL != "https://jvns.ca/atom.xml" {
+		t.Errorf("Expected atom feed, got %v", subs)
+	}
+}
+
+func TestClient_CreateFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/v1/feeds" {
+			t.Errorf("Expected POST /v1/feeds, got %s %s", r.Method, r.URL.Path)
+		}
+		var body struct {
+			FeedURL    string `json:"feed_url"`
+			CategoryID int64  `json:"category_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.FeedURL != "https://jvns.ca/atom.xml" || body.CategoryID != 3 {
+			t.Errorf("Unexpected request body: %+v", body)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"feed_id": 42}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-api-key")
+	id, err := client.CreateFeed("https://jvns.ca/atom.xml", 3)
+	if err != nil {
+		t.Fatalf("CreateFeed error: %v", err)
+	}
+	if id != 42 {
+		t.Errorf("Expected feed ID 42, got %d", id)
+	}
+}
+
+func TestClient_GetCategories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/v1/categories" {
+			t.Errorf("Expected GET /v1/categories, got %s %s", r.Method, r.URL.Path)
+		}
+		json.NewEncoder(w).Encode([]Category{
+			{ID: 1, Title: "All"},
+			{ID: 2, Title: "Discovered"},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-api-key")
+	categories, err := client.GetCategories()
+	if err != nil {
+		t.Fatalf("GetCategories error: %v", err)
+	}
+	if len(categories) != 2 || categories[1].Title != "Discovered" {
+		t.Errorf("Unexpected categories: %v", categories)
+	}
+}
+
+func TestClient_CreateCategory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/v1/categories" {
+			t.Errorf("Expected POST /v1/categories, got %s %s", r.Method, r.URL.Path)
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(Category{ID: 7, Title: body["title"]})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-api-key")
+	category, err := client.CreateCategory("Discovered")
+	if err != nil {
+		t.Fatalf("CreateCategory error: %v", err)
+	}
+	if category.ID != 7 || category.Title != "Discovered" {
+		t.Errorf("Unexpected category: %+v", category)
+	}
+}
+
+func TestClient_CreateFeed_Duplicate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error_message": "This feed already exists."}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-api-key")
+	_, err := client.CreateFeed("https://jvns.ca/atom.xml", 1)
+	if err == nil {
+		t.Error("Expected error for duplicate feed")
+	}
+}
+
+func TestClient_IterEntries_Paginates(t *testing.T) {
+	// 5 entries served 2 per page
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		q := r.URL.Query()
+		if q.Get("after_entry_id") != "10" {
+			t.Errorf("Expected after_entry_id=10, got %q", q.Get("after_entry_id"))
+		}
+		if q.Get("published_after") != "1700000000" {
+			t.Errorf("Expected published_after=1700000000, got %q", q.Get("published_after"))
+		}
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		offset, _ := strconv.Atoi(q.Get("offset"))
+
+		var entries []Entry
+		for id := 11 + offset; id <= 15 && len(entries) < limit; id++ {
+			entries = append(entries, Entry{ID: int64(id)})
+		}
+		json.NewEncoder(w).Encode(EntriesResponse{Total: 5, Entries: entries})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-api-key")
+	it := client.IterEntries(1, EntryFilter{
+		Limit:          2,
+		Order:          "id",
+		Direction:      "asc",
+		AfterEntryID:   10,
+		PublishedAfter: time.Unix(1700000000, 0),
+	})
+
+	var ids []int64
+	for it.Next() {
+		ids = append(ids, it.Entry().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Iteration error: %v", err)
+	}
+
+	if len(ids) != 5 || ids[0] != 11 || ids[4] != 15 {
+		t.Errorf("Expected entries 11-15, got %v", ids)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 page requests, got %d", requests)
+	}
+	if it.Total() != 5 {
+		t.Errorf("Expected total 5, got %d", it.Total())
+	}
+}
+
+func TestClient_IterEntries_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "key")
+	it := client.IterEntries(1, EntryFilter{})
+	if it.Next() {
+		t.Error("Expected no entries on server error")
+	}
+	if it.Err() == nil {
+		t.Error("Expected iteration error")
+	}
+}
+
+func TestClient_GetEntries_Metadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"total": 1, "entries": [{
+			"id": 100,
+			"title": "Post 1",
+			"published_at": "2024-03-01T12:30:00Z",
+			"tags": ["go", "sqlite"],
+			"reading_time": 4,
+			"starred": true
+		}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-api-key")
+	entries, err := client.GetEntries(1, 10)
+	if err != nil {
+		t.Fatalf("GetEntries error: %v", err)
+	}
+
+	e := entries[0]
+	if !e.PublishedAt.Equal(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected published_at: %v", e.PublishedAt)
+	}
+	if len(e.Tags) != 2 || e.ReadingTime != 4 || !e.Starred {
+		t.Errorf("Unexpected metadata: %+v", e)
+	}
+}
+
+func TestClient_Source(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/feeds":
+			w.Write([]byte(`[{"id": 1, "title": "Julia Evans", "feed_url": "https://jvns.ca/atom.xml", "category": {"id": 2, "title": "Programming"}}]`))
+		case "/v1/feeds/1/entries":
+			q := r.URL.Query()
+			if q.Get("after_entry_id") != "100" || q.Get("direction") != "desc" {
+				t.Errorf("Expected entries after 100 newest first, got %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"total": 1, "entries": [{"id": 101, "title": "New", "hash": "abc123", "starred": true}]}`))
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	var src source.Source = NewClient(server.URL, "test-api-key")
+
+	feeds, err := src.ListFeeds()
+	if err != nil {
+		t.Fatalf("ListFeeds error: %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].ID != "1" || len(feeds[0].Categories) != 1 || feeds[0].Categories[0] != "Programming" {
+		t.Errorf("Unexpected feeds: %+v", feeds)
+	}
+
+	it := src.ListEntries("1", source.Cursor{AfterEntryID: 100})
+	if !it.Next() {
+		t.Fatalf("Expected an entry, got error %v", it.Err())
+	}
+	if e := it.Entry(); e.ID != 101 || e.GUID != "abc123" || !e.Starred {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+	if it.Next() {
+		t.Error("Expected a single entry")
+	}
+}
@@ -2,6 +2,7 @@
 package ner
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/jdkato/prose/v2"
@@ -70,6 +71,27 @@ var notPeople = map[string]bool{
 	"comments":  true,
 }
 
+// NotPeople returns the blocklist of names ExtractPeople never reports,
+// sorted.
+func NotPeople() []string {
+	names := make([]string, 0, len(notPeople))
+	for name := range notPeople {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetNotPeople replaces the blocklist of names ExtractPeople never reports.
+// Names are matched case-insensitively. Call it before extracting, not
+// concurrently with it.
+func SetNotPeople(names []string) {
+	notPeople = make(map[string]bool, len(names))
+	for _, name := range names {
+		notPeople[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+}
+
 // ExtractEntities extracts all named entities from text.
 func ExtractEntities(text string) []Entity {
 	if text == "" {
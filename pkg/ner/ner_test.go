@@ -73,6 +73,20 @@ func TestExtractPeople_Deduplicates(t *testing.T) {
 	}
 }
 
+func TestSetNotPeople(t *testing.T) {
+	defaults := NotPeople()
+	defer SetNotPeople(defaults)
+
+	text := `Simon Willison wrote about LLMs.`
+	SetNotPeople(append(defaults, "Simon Willison"))
+	if people := ExtractPeople(text); len(people) != 0 {
+		t.Errorf("Expected the blocklisted name skipped, got %v", people)
+	}
+	if !contains(NotPeople(), "simon willison") {
+		t.Error("Expected the blocklist to hold the added name, lowercased")
+	}
+}
+
 // Helper functions
 func filterByLabel(entities []Entity, label string) []Entity {
 	var filtered []Entity
@@ -0,0 +1,69 @@
+// Package opml provides parsing of OPML subscription lists.
+package opml
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+)
+
+// Subscription represents a feed listed in an OPML document.
+type Subscription struct {
+	Title    string
+	FeedURL  string
+	SiteURL  string
+	Category string // Title of the enclosing outline, if any
+}
+
+type document struct {
+	XMLName xml.Name  `xml:"opml"`
+	Body    []outline `xml:"body>outline"`
+}
+
+type outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr"`
+	XMLURL   string    `xml:"xmlUrl,attr"`
+	HTMLURL  string    `xml:"htmlUrl,attr"`
+	Outlines []outline `xml:"outline"`
+}
+
+// Parse parses an OPML document and returns its feed subscriptions.
+// Nested outlines are flattened; the enclosing outline's title becomes the
+// subscription's category.
+func Parse(data []byte) ([]Subscription, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty OPML data")
+	}
+
+	var doc document
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	var subs []Subscription
+	var walk func(outlines []outline, category string)
+	walk = func(outlines []outline, category string) {
+		for _, o := range outlines {
+			title := strings.TrimSpace(o.Title)
+			if title == "" {
+				title = strings.TrimSpace(o.Text)
+			}
+
+			if o.XMLURL != "" {
+				subs = append(subs, Subscription{
+					Title:    title,
+					FeedURL:  strings.TrimSpace(o.XMLURL),
+					SiteURL:  strings.TrimSpace(o.HTMLURL),
+					Category: category,
+				})
+			}
+			if len(o.Outlines) > 0 {
+				walk(o.Outlines, title)
+			}
+		}
+	}
+	walk(doc.Body, "")
+
+	return subs, nil
+}
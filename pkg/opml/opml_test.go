@@ -0,0 +1,54 @@
+package opml
+
+import (
+	"testing"
+)
+
+func TestParse_Nested(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head><title>Subscriptions</title></head>
+  <body>
+    <outline text="AI" title="AI">
+      <outline type="rss" text="Simon Willison" xmlUrl="https://simonwillison.net/atom/everything/" htmlUrl="https://simonwillison.net/"/>
+      <outline type="rss" text="Hamel Husain" xmlUrl="https://hamel.dev/feed.xml" htmlUrl="https://hamel.dev/"/>
+    </outline>
+    <outline type="rss" text="Julia Evans" xmlUrl="https://jvns.ca/atom.xml"/>
+  </body>
+</opml>`
+
+	subs, err := Parse([]byte(doc))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	if len(subs) != 3 {
+		t.Fatalf("Expected 3 subscriptions, got %d", len(subs))
+	}
+	if subs[0].Title != "Simon Willison" {
+		t.Errorf("Expected title 'Simon Willison', got '%s'", subs[0].Title)
+	}
+	if subs[0].Category != "AI" {
+		t.Errorf("Expected category 'AI', got '%s'", subs[0].Category)
+	}
+	if subs[0].SiteURL != "https://simonwillison.net/" {
+		t.Errorf("Expected site URL https://simonwillison.net/, got %s", subs[0].SiteURL)
+	}
+	if subs[2].Category != "" {
+		t.Errorf("Expected no category for top-level outline, got '%s'", subs[2].Category)
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	_, err := Parse([]byte(""))
+	if err == nil {
+		t.Error("Expected error for empty input")
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse([]byte("not xml at all"))
+	if err == nil {
+		t.Error("Expected error for invalid XML")
+	}
+}
@@ -0,0 +1,180 @@
+// Package output renders command results as JSON, JSON Lines, CSV or TSV.
+//
+// Results are slices of the record types in records.go, whose JSON field
+// names double as CSV/TSV column headers. The schema is documented in
+// docs/output.md; fields are only ever added, never renamed or removed.
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Format is an output format.
+type Format string
+
+const (
+	Table Format = "table" // Human-readable text (the default)
+	JSON  Format = "json"  // A single JSON array
+	JSONL Format = "jsonl" // One JSON object per line
+	CSV   Format = "csv"
+	TSV   Format = "tsv"
+)
+
+// ParseFormat parses a format name.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case Table, JSON, JSONL, CSV, TSV:
+		return f, nil
+	case "":
+		return Table, nil
+	}
+	return "", fmt.Errorf("unknown output format: %s (want json, jsonl, csv, tsv or table)", s)
+}
+
+// Renderer writes records in one format.
+type Renderer struct {
+	w      io.Writer
+	format Format
+}
+
+// New creates a Renderer writing to w.
+func New(w io.Writer, format Format) *Renderer {
+	return &Renderer{w: w, format: format}
+}
+
+// Table reports whether commands should print their usual human-readable output.
+func (r *Renderer) Table() bool {
+	return r.format == Table
+}
+
+// Render writes rows, which must be a slice of structs. In Table format it
+// prints plain aligned columns, for commands without a custom text layout.
+func (r *Renderer) Render(rows any) error {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("output: Render wants a slice of structs, got %T", rows)
+	}
+
+	switch r.format {
+	case JSON:
+		if v.Len() == 0 {
+			// Encode as [] rather than null
+			v = reflect.MakeSlice(v.Type(), 0, 0)
+		}
+		enc := json.NewEncoder(r.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v.Interface())
+	case JSONL:
+		enc := json.NewEncoder(r.w)
+		for i := 0; i < v.Len(); i++ {
+			if err := enc.Encode(v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case CSV:
+		w := csv.NewWriter(r.w)
+		w.Write(columns(v.Type().Elem()))
+		for i := 0; i < v.Len(); i++ {
+			w.Write(values(v.Index(i)))
+		}
+		w.Flush()
+		return w.Error()
+	case TSV:
+		return writeDelimited(r.w, v, func(s string) string {
+			return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(s)
+		})
+	default:
+		tw := tabwriter.NewWriter(r.w, 0, 4, 2, ' ', 0)
+		if err := writeDelimited(tw, v, func(s string) string { return s }); err != nil {
+			return err
+		}
+		return tw.Flush()
+	}
+}
+
+// writeDelimited writes a header and one tab-separated line per record.
+func writeDelimited(w io.Writer, v reflect.Value, clean func(string) string) error {
+	row := func(fields []string) error {
+		for i, f := range fields {
+			fields[i] = clean(f)
+		}
+		_, err := fmt.Fprintln(w, strings.Join(fields, "\t"))
+		return err
+	}
+	if err := row(columns(v.Type().Elem())); err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		if err := row(values(v.Index(i))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columns returns the JSON field names of a record type.
+func columns(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		names = append(names, fieldName(t.Field(i)))
+	}
+	return names
+}
+
+func fieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// values formats each field of a record for CSV/TSV.
+func values(v reflect.Value) []string {
+	var out []string
+	for i := 0; i < v.NumField(); i++ {
+		out = append(out, formatValue(v.Field(i)))
+	}
+	return out
+}
+
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatValue(v.Index(i))
+		}
+		return strings.Join(items, " ")
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,138 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+)
+
+func testRows() []FeedRank {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []FeedRank{
+		NewFeedRank(1, graph.RankedFeed{
+			Feed:         &graph.FeedNode{ID: 7, URL: "https://jvns.ca/", Title: "Julia Evans, \"b0rk\"", CreatedAt: created},
+			InboundCount: 3,
+			Score:        4.5,
+		}),
+		NewFeedRank(2, graph.RankedFeed{
+			Feed:         &graph.FeedNode{ID: 9, URL: "https://example.com/", Title: "Tab\there", Subscribed: true, CreatedAt: created},
+			InboundCount: 1,
+			Score:        1,
+		}),
+	}
+}
+
+func render(t *testing.T, format Format, rows any) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := New(&buf, format).Render(rows); err != nil {
+		t.Fatalf("Render(%s) error: %v", format, err)
+	}
+	return buf.String()
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, s := range []string{"json", "JSONL", "csv", "tsv", "table", ""} {
+		if _, err := ParseFormat(s); err != nil {
+			t.Errorf("ParseFormat(%q) error: %v", s, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestRender_JSON(t *testing.T) {
+	out := render(t, JSON, testRows())
+
+	var decoded []map[string]any
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, out)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(decoded))
+	}
+	if decoded[0]["url"] != "https://jvns.ca/" || decoded[0]["score"] != 4.5 || decoded[0]["rank"] != float64(1) {
+		t.Errorf("Unexpected first row: %v", decoded[0])
+	}
+
+	if out := render(t, JSON, []FeedRank(nil)); strings.TrimSpace(out) != "[]" {
+		t.Errorf("Expected [] for no rows, got %q", out)
+	}
+}
+
+func TestRender_JSONL(t *testing.T) {
+	out := render(t, JSONL, testRows())
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	var row FeedRank
+	if err := json.Unmarshal([]byte(lines[1]), &row); err != nil {
+		t.Fatalf("Invalid JSON line: %v", err)
+	}
+	if row.FeedID != 9 || !row.Subscribed {
+		t.Errorf("Unexpected second row: %+v", row)
+	}
+}
+
+func TestRender_CSV(t *testing.T) {
+	out := render(t, CSV, testRows())
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if lines[0] != "rank,feed_id,url,title,inbound_count,score,subscribed,created_at,run_by" {
+		t.Errorf("Unexpected header: %s", lines[0])
+	}
+	if lines[1] != `1,7,https://jvns.ca/,"Julia Evans, ""b0rk""",3,4.5,false,2024-01-02T03:04:05Z,` {
+		t.Errorf("Unexpected first row: %s", lines[1])
+	}
+}
+
+func TestRender_TSV(t *testing.T) {
+	out := render(t, TSV, testRows())
+
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected header and 2 rows, got %d lines", len(lines))
+	}
+	fields := strings.Split(lines[2], "\t")
+	if len(fields) != 9 || fields[3] != "Tab here" {
+		t.Errorf("Expected tabs in values replaced, got %q", fields)
+	}
+}
+
+func TestRender_NullTime(t *testing.T) {
+	rows := []Link{NewLink("in", graph.LinkEdge{SourceID: 1, TargetID: 2}, nil, nil)}
+
+	if out := render(t, JSON, rows); !strings.Contains(out, `"published_at": null`) {
+		t.Errorf("Expected null published_at, got %s", out)
+	}
+	lines := strings.Split(strings.TrimSpace(render(t, CSV, rows)), "\n")
+	if !strings.HasPrefix(lines[1], "in,1,,2,,,,,,false,") {
+		t.Errorf("Expected empty published_at, got %s", lines[1])
+	}
+}
+
+func TestRender_Lists(t *testing.T) {
+	rows := []SimilarFeed{{Rank: 1, CiterURLs: []string{"https://a.com/", "https://b.com/"}, CitedURLs: []string{}}}
+
+	if out := render(t, JSON, rows); !strings.Contains(out, `"citer_urls": [
+      "https://a.com/",`) || !strings.Contains(out, `"cited_urls": []`) {
+		t.Errorf("Expected JSON arrays, got %s", out)
+	}
+	lines := strings.Split(strings.TrimSpace(render(t, CSV, rows)), "\n")
+	if !strings.HasSuffix(lines[1], ",https://a.com/ https://b.com/,") {
+		t.Errorf("Expected space-separated list, got %s", lines[1])
+	}
+}
+
+func TestRender_RejectsNonSlice(t *testing.T) {
+	if err := New(&bytes.Buffer{}, JSON).Render(FeedRank{}); err == nil {
+		t.Error("Expected error for a non-slice")
+	}
+}
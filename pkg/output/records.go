@@ -0,0 +1,473 @@
+package output
+
+import (
+	"time"
+
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+)
+
+// FeedRank is one row of `rank` output.
+type FeedRank struct {
+	Rank         int       `json:"rank"`
+	FeedID       int64     `json:"feed_id"`
+	URL          string    `json:"url"`
+	Title        string    `json:"title"`
+	InboundCount int       `json:"inbound_count"`
+	Score        float64   `json:"score"` // Inbound count with starred links weighted
+	Subscribed   bool      `json:"subscribed"`
+	CreatedAt    time.Time `json:"created_at"`
+	RunBy        string    `json:"run_by"` // Person likely running the site, if known (see people)
+}
+
+// NewFeedRank converts a ranked feed at 1-based position rank.
+func NewFeedRank(rank int, r graph.RankedFeed) FeedRank {
+	return FeedRank{
+		Rank:         rank,
+		FeedID:       r.Feed.ID,
+		URL:          r.Feed.URL,
+		Title:        r.Feed.Title,
+		InboundCount: r.InboundCount,
+		Score:        r.Score,
+		Subscribed:   r.Feed.Subscribed,
+		CreatedAt:    r.Feed.CreatedAt,
+	}
+}
+
+// MentionRank is one row of `mentions` output.
+type MentionRank struct {
+	Rank         int    `json:"rank"`
+	Name         string `json:"name"`
+	EntityType   string `json:"entity_type"`
+	MentionCount int    `json:"mention_count"`
+	Site         string `json:"site"` // Host of the person's likeliest site, if known (see people)
+}
+
+// NewMentionRank converts a ranked mention at 1-based position rank.
+func NewMentionRank(rank int, m graph.RankedMention) MentionRank {
+	return MentionRank{Rank: rank, Name: m.Name, EntityType: m.EntityType, MentionCount: m.MentionCount}
+}
+
+// RisingMention is one row of `mentions --rising` output.
+type RisingMention struct {
+	Rank          int     `json:"rank"`
+	Name          string  `json:"name"`
+	EntityType    string  `json:"entity_type"`
+	Status        string  `json:"status"` // hot, rising or new
+	CurrentCount  int     `json:"current_count"`
+	PreviousCount int     `json:"previous_count"`
+	Velocity      float64 `json:"velocity"`
+	CurrentDate   string  `json:"current_date"`
+	PreviousDate  string  `json:"previous_date"`
+}
+
+// NewRisingMention converts a rising mention between two snapshot dates.
+func NewRisingMention(rank int, m graph.RisingMention, currentDate, previousDate string) RisingMention {
+	return RisingMention{
+		Rank:          rank,
+		Name:          m.Name,
+		EntityType:    m.EntityType,
+		Status:        m.Status,
+		CurrentCount:  m.CurrentCount,
+		PreviousCount: m.PreviousCount,
+		Velocity:      m.Velocity,
+		CurrentDate:   currentDate,
+		PreviousDate:  previousDate,
+	}
+}
+
+// Link is one edge in `links` output.
+type Link struct {
+	Direction    string     `json:"direction"` // in or out, relative to the queried feed
+	SourceID     int64      `json:"source_id"`
+	SourceURL    string     `json:"source_url"`
+	TargetID     int64      `json:"target_id"`
+	TargetURL    string     `json:"target_url"`
+	PostURL      string     `json:"post_url"`
+	PostTitle    string     `json:"post_title"`
+	Context      string     `json:"context"`
+	PublishedAt  *time.Time `json:"published_at"` // Null if unknown
+	Starred      bool       `json:"starred"`
+	DiscoveredAt time.Time  `json:"discovered_at"`
+	RetractedAt  *time.Time `json:"retracted_at"` // Null unless the link was edited out of its post
+}
+
+// NewLink converts a link edge between source and target.
+func NewLink(direction string, l graph.LinkEdge, source, target *graph.FeedNode) Link {
+	link := Link{
+		Direction:    direction,
+		SourceID:     l.SourceID,
+		TargetID:     l.TargetID,
+		PostURL:      l.PostURL,
+		PostTitle:    l.PostTitle,
+		Context:      l.Context,
+		Starred:      l.Starred,
+		DiscoveredAt: l.DiscoveredAt,
+	}
+	if source != nil {
+		link.SourceURL = source.URL
+	}
+	if target != nil {
+		link.TargetURL = target.URL
+	}
+	if !l.PublishedAt.IsZero() {
+		published := l.PublishedAt
+		link.PublishedAt = &published
+	}
+	if !l.RetractedAt.IsZero() {
+		retracted := l.RetractedAt
+		link.RetractedAt = &retracted
+	}
+	return link
+}
+
+// Snapshot is one row of `snapshot --list` output.
+type Snapshot struct {
+	Date string `json:"date"` // YYYY-MM-DD
+}
+
+// CrawlResult is one row of `crawl` output, per feed.
+type CrawlResult struct {
+	FeedID    int64  `json:"feed_id"`
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	Entries   int    `json:"entries"`
+	Links     int    `json:"links"`
+	Mentions  int    `json:"mentions"`
+	Error     string `json:"error"`     // Empty on success
+	Unchanged int    `json:"unchanged"` // Entries skipped as already crawled and unchanged
+	Retracted int    `json:"retracted"` // Links and mentions edited out of changed entries
+}
+
+// PathPost is one post along one hop of a `path` result.
+type PathPost struct {
+	Path        int        `json:"path"` // 1-based path number
+	Hop         int        `json:"hop"`  // 1-based hop within the path
+	SourceID    int64      `json:"source_id"`
+	SourceURL   string     `json:"source_url"`
+	TargetID    int64      `json:"target_id"`
+	TargetURL   string     `json:"target_url"`
+	Links       int        `json:"links"` // Total links on this hop
+	PostURL     string     `json:"post_url"`
+	PostTitle   string     `json:"post_title"`
+	PublishedAt *time.Time `json:"published_at"` // Null if unknown
+}
+
+// NeighborhoodEdge is one edge of a `neighborhood` result.
+type NeighborhoodEdge struct {
+	SourceID    int64  `json:"source_id"`
+	SourceURL   string `json:"source_url"`
+	SourceDepth int    `json:"source_depth"` // Hops from the center
+	TargetID    int64  `json:"target_id"`
+	TargetURL   string `json:"target_url"`
+	TargetDepth int    `json:"target_depth"`
+	Weight      int    `json:"weight"` // Number of links
+}
+
+// ClusterTop is one top site or person of a cluster in `clusters` output.
+type ClusterTop struct {
+	Cluster int    `json:"cluster"`
+	Size    int    `json:"size"`    // Feeds in the cluster
+	Kind    string `json:"kind"`    // site or person
+	Rank    int    `json:"rank"`    // Position within the kind
+	FeedID  int64  `json:"feed_id"` // 0 for people
+	Name    string `json:"name"`    // Feed title or person name
+	URL     string `json:"url"`     // Empty for people
+	Count   int    `json:"count"`   // Inbound links or mentions
+}
+
+// NewClusterSite converts a site ranked at position rank within c.
+func NewClusterSite(c graph.Cluster, rank int, r graph.RankedFeed) ClusterTop {
+	return ClusterTop{
+		Cluster: c.ID,
+		Size:    c.Size,
+		Kind:    "site",
+		Rank:    rank,
+		FeedID:  r.Feed.ID,
+		Name:    r.Feed.Title,
+		URL:     r.Feed.URL,
+		Count:   r.InboundCount,
+	}
+}
+
+// NewClusterPerson converts a person ranked at position rank within c.
+func NewClusterPerson(c graph.Cluster, rank int, m graph.RankedMention) ClusterTop {
+	return ClusterTop{Cluster: c.ID, Size: c.Size, Kind: "person", Rank: rank, Name: m.Name, Count: m.MentionCount}
+}
+
+// SimilarFeed is one row of `similar` output.
+type SimilarFeed struct {
+	Rank         int      `json:"rank"`
+	FeedID       int64    `json:"feed_id"`
+	URL          string   `json:"url"`
+	Title        string   `json:"title"`
+	Subscribed   bool     `json:"subscribed"`
+	Score        float64  `json:"score"`         // Mean of co_citation and coupling
+	CoCitation   float64  `json:"co_citation"`   // Similarity of the sites citing each
+	Coupling     float64  `json:"coupling"`      // Similarity of the sites each cites
+	SharedCiters int      `json:"shared_citers"` // Sites citing both
+	SharedCited  int      `json:"shared_cited"`  // Sites both cite
+	CiterURLs    []string `json:"citer_urls"`    // Top shared citers, see --evidence
+	CitedURLs    []string `json:"cited_urls"`    // Top shared cited sites
+}
+
+// ReciprocalPair is one row of `conversations` output.
+type ReciprocalPair struct {
+	AID           int64     `json:"a_id"`
+	AURL          string    `json:"a_url"`
+	BID           int64     `json:"b_id"`
+	BURL          string    `json:"b_url"`
+	AToB          int       `json:"a_to_b"` // Links from A to B
+	BToA          int       `json:"b_to_a"`
+	AToBFirst     time.Time `json:"a_to_b_first"`
+	AToBLast      time.Time `json:"a_to_b_last"`
+	BToAFirst     time.Time `json:"b_to_a_first"`
+	BToALast      time.Time `json:"b_to_a_last"`
+	Conversations int       `json:"conversations"` // Back-and-forth threads, see --days
+}
+
+// NewReciprocalPair converts a reciprocal pair with its conversation count.
+func NewReciprocalPair(p graph.ReciprocalPair, nodes map[int64]*graph.FeedNode, conversations int) ReciprocalPair {
+	return ReciprocalPair{
+		AID:           p.AToB.SourceID,
+		AURL:          nodes[p.AToB.SourceID].URL,
+		BID:           p.AToB.TargetID,
+		BURL:          nodes[p.AToB.TargetID].URL,
+		AToB:          p.AToB.Weight,
+		BToA:          p.BToA.Weight,
+		AToBFirst:     p.AToB.FirstSeen,
+		AToBLast:      p.AToB.LastSeen,
+		BToAFirst:     p.BToA.FirstSeen,
+		BToALast:      p.BToA.LastSeen,
+		Conversations: conversations,
+	}
+}
+
+// ConversationLink is one link in a thread of `conversations <url>` output.
+type ConversationLink struct {
+	Conversation int        `json:"conversation"` // 1-based thread number
+	Start        time.Time  `json:"start"`
+	End          time.Time  `json:"end"`
+	SourceID     int64      `json:"source_id"`
+	SourceURL    string     `json:"source_url"`
+	TargetID     int64      `json:"target_id"`
+	TargetURL    string     `json:"target_url"`
+	PostURL      string     `json:"post_url"`
+	PostTitle    string     `json:"post_title"`
+	PublishedAt  *time.Time `json:"published_at"` // Null if unknown
+}
+
+// NewConversationLink converts a link in conversation c, identifying its
+// ends by their nodes in the host-merged network n.
+func NewConversationLink(conversation int, c graph.Conversation, l graph.LinkEdge, n *graph.Network) ConversationLink {
+	source, target := n.Resolve(l.SourceID), n.Resolve(l.TargetID)
+	link := ConversationLink{
+		Conversation: conversation,
+		Start:        c.Start,
+		End:          c.End,
+		SourceID:     source,
+		SourceURL:    n.Nodes[source].URL,
+		TargetID:     target,
+		TargetURL:    n.Nodes[target].URL,
+		PostURL:      l.PostURL,
+		PostTitle:    l.PostTitle,
+	}
+	if !l.PublishedAt.IsZero() {
+		published := l.PublishedAt
+		link.PublishedAt = &published
+	}
+	return link
+}
+
+// SearchResult is one row of `search` output.
+type SearchResult struct {
+	Rank        int        `json:"rank"`
+	PostURL     string     `json:"post_url"`
+	PostTitle   string     `json:"post_title"`
+	PublishedAt *time.Time `json:"published_at"` // Null if unknown
+	FeedID      int64      `json:"feed_id"`
+	FeedURL     string     `json:"feed_url"`
+	FeedTitle   string     `json:"feed_title"`
+	Snippet     string     `json:"snippet"`  // Matches marked with [ and ]
+	LinksTo     []string   `json:"links_to"` // URLs of the feeds the post links to
+}
+
+// NewSearchResult converts a search match at 1-based position rank.
+func NewSearchResult(rank int, m graph.PostMatch) SearchResult {
+	r := SearchResult{
+		Rank:      rank,
+		PostURL:   m.Post.URL,
+		PostTitle: m.Post.Title,
+		FeedID:    m.Feed.ID,
+		FeedURL:   m.Feed.URL,
+		FeedTitle: m.Feed.Title,
+		Snippet:   m.Snippet,
+		LinksTo:   []string{},
+	}
+	if !m.Post.PublishedAt.IsZero() {
+		published := m.Post.PublishedAt
+		r.PublishedAt = &published
+	}
+	for _, f := range m.Targets {
+		r.LinksTo = append(r.LinksTo, f.URL)
+	}
+	return r
+}
+
+// FeedHealth is one row of `health` output.
+type FeedHealth struct {
+	FeedID        int64      `json:"feed_id"`
+	URL           string     `json:"url"`
+	Title         string     `json:"title"`
+	Status        string     `json:"status"` // dead, malformed, moved, stale or ok
+	Attempts      int        `json:"attempts"`
+	Failures      int        `json:"failures"`        // Consecutive failed fetches
+	LastAttemptAt *time.Time `json:"last_attempt_at"` // Null if never fetched
+	HTTPStatus    int        `json:"http_status"`     // Of the latest attempt, 0 if no response
+	Error         string     `json:"error"`
+	ErrorClass    string     `json:"error_class"`
+	LatencyMS     int64      `json:"latency_ms"`
+	Bytes         int        `json:"bytes"`
+	Items         int        `json:"items"`
+	LastSuccessAt *time.Time `json:"last_success_at"` // Null if never fetched successfully
+	NewestPostAt  *time.Time `json:"newest_post_at"`  // Null if unknown
+	MovedTo       string     `json:"moved_to"`
+}
+
+// NewFeedHealth converts a feed's health.
+func NewFeedHealth(h graph.FeedHealth) FeedHealth {
+	r := FeedHealth{
+		FeedID:   h.Feed.ID,
+		URL:      h.Feed.URL,
+		Title:    h.Feed.Title,
+		Status:   h.Status,
+		Attempts: h.Attempts,
+		Failures: h.Failures,
+		MovedTo:  h.MovedTo,
+	}
+	if a := h.Last; a != nil {
+		attempted := a.AttemptedAt
+		r.LastAttemptAt = &attempted
+		r.HTTPStatus = a.Status
+		r.Error = a.Error
+		r.ErrorClass = a.ErrorClass
+		r.LatencyMS = a.Latency.Milliseconds()
+		r.Bytes = a.Bytes
+		r.Items = a.ItemCount
+	}
+	if !h.LastSuccess.IsZero() {
+		success := h.LastSuccess
+		r.LastSuccessAt = &success
+	}
+	if !h.NewestPost.IsZero() {
+		newest := h.NewestPost
+		r.NewestPostAt = &newest
+	}
+	return r
+}
+
+// ExpandResult is one row of `expand` output: a site expanded in this run,
+// or with --list, a site still queued.
+type ExpandResult struct {
+	FeedID   int64   `json:"feed_id"`
+	URL      string  `json:"url"`
+	Depth    int     `json:"depth"`    // Hops from our subscriptions
+	Priority float64 `json:"priority"` // Distinct feeds linking to the site
+	Status   string  `json:"status"`   // queued, done, failed or skipped
+	FeedURL  string  `json:"feed_url"` // The site's feed, if found
+	Links    int     `json:"links"`    // Outbound links found in the feed
+	Queued   int     `json:"queued"`   // Sites newly queued one hop further out
+	Error    string  `json:"error"`    // Why the site failed or was skipped
+}
+
+// NewExpandResult converts a frontier entry.
+func NewExpandResult(e graph.FrontierEntry) ExpandResult {
+	return ExpandResult{
+		FeedID:   e.FeedID,
+		URL:      e.URL,
+		Depth:    e.Depth,
+		Priority: e.Priority,
+		Status:   e.Status,
+		FeedURL:  e.FeedURL,
+		Error:    e.Error,
+	}
+}
+
+// DomainFilter is one row of `filter list` output: a rule from the database
+// or, with ID 0, a common domain from the config file.
+type DomainFilter struct {
+	ID        int64      `json:"id"`
+	Action    string     `json:"action"` // deny or allow
+	Match     string     `json:"match"`  // exact, suffix or regex
+	Pattern   string     `json:"pattern"`
+	Source    string     `json:"source"`     // config or db
+	CreatedAt *time.Time `json:"created_at"` // Null for config rules
+}
+
+// NewDomainFilter converts a domain filter rule.
+func NewDomainFilter(f graph.DomainFilter) DomainFilter {
+	r := DomainFilter{
+		ID:      f.ID,
+		Action:  f.Action,
+		Match:   f.Match,
+		Pattern: f.Pattern,
+		Source:  "config",
+	}
+	if f.ID != 0 {
+		created := f.CreatedAt
+		r.Source, r.CreatedAt = "db", &created
+	}
+	return r
+}
+
+// Entity is one row of `entity list` output.
+type Entity struct {
+	ID         int64    `json:"id"`
+	Name       string   `json:"name"` // Canonical name
+	EntityType string   `json:"entity_type"`
+	Aliases    []string `json:"aliases"`  // Every name counted as the entity, including name
+	Mentions   int      `json:"mentions"` // Posts mentioning any alias
+}
+
+// NewEntity converts an entity.
+func NewEntity(e graph.Entity) Entity {
+	return Entity{ID: e.ID, Name: e.Name, EntityType: e.EntityType, Aliases: e.Aliases, Mentions: e.Mentions}
+}
+
+// MergeSuggestion is one row of `entity suggest` output.
+type MergeSuggestion struct {
+	Name     string `json:"name"`
+	Into     string `json:"into"`
+	Reason   string `json:"reason"`   // possessive, case, last-name or spelling
+	Mentions int    `json:"mentions"` // Posts mentioning name
+}
+
+// NewMergeSuggestion converts a merge suggestion.
+func NewMergeSuggestion(s graph.MergeSuggestion) MergeSuggestion {
+	return MergeSuggestion{Name: s.Name, Into: s.Into, Reason: s.Reason, Mentions: s.Mentions}
+}
+
+// PersonSite is one row of `people` output.
+type PersonSite struct {
+	Name     string  `json:"name"` // Canonical name of the person
+	Host     string  `json:"host"`
+	FeedID   int64   `json:"feed_id"`
+	Anchors  int     `json:"anchors"`  // Feeds linking to the site with the person's name as the link text
+	Posts    int     `json:"posts"`    // Posts on the site by the person, per the feed's author field
+	Homepage bool    `json:"homepage"` // The site's homepage names the person as its author
+	RelMe    bool    `json:"rel_me"`   // rel="me" links tie the site to another of the person's sites
+	Score    float64 `json:"score"`
+}
+
+// NewPersonSite converts a person's site.
+func NewPersonSite(s graph.PersonSite) PersonSite {
+	return PersonSite{
+		Name:     s.Name,
+		Host:     s.Host,
+		FeedID:   s.FeedID,
+		Anchors:  s.Anchors,
+		Posts:    s.Posts,
+		Homepage: s.Homepage,
+		RelMe:    s.RelMe,
+		Score:    s.Score,
+	}
+}
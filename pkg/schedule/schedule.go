@@ -0,0 +1,90 @@
+// Package schedule works out how often to poll each feed.
+package schedule
+
+import (
+	"sort"
+	"time"
+)
+
+// Policy bounds polling intervals.
+type Policy struct {
+	Min        time.Duration // Never poll a feed more often than this
+	Max        time.Duration // Never wait longer than this while a feed is healthy, unless it asks to
+	Default    time.Duration // Used when a feed has too few dated posts to go on
+	MaxBackoff time.Duration // Never wait longer than this after failures
+}
+
+// DefaultPolicy polls between every 15 minutes and once a day, backing off
+// to once a week for failing feeds.
+func DefaultPolicy() Policy {
+	return Policy{
+		Min:        15 * time.Minute,
+		Max:        24 * time.Hour,
+		Default:    time.Hour,
+		MaxBackoff: 7 * 24 * time.Hour,
+	}
+}
+
+// Hints are what a feed and its server say about how often it changes.
+type Hints struct {
+	Published    []time.Time   // Publish dates of the feed's items, in any order
+	TTL          time.Duration // RSS <ttl>
+	UpdatePeriod time.Duration // sy:updatePeriod over sy:updateFrequency
+	MaxAge       time.Duration // Cache-Control max-age
+}
+
+// recentPosts is how many of a feed's newest posts Interval looks at.
+const recentPosts = 10
+
+// Interval returns how long to wait before polling a feed again: half the
+// median gap between its recent posts, counting the time since the newest
+// one so that feeds which have gone quiet are polled less, clamped to the
+// policy. The feed or its server may ask for longer, even beyond Max.
+func (p Policy) Interval(h Hints, now time.Time) time.Duration {
+	interval := p.Default
+	if gap := medianGap(h.Published, now); gap > 0 {
+		interval = gap / 2
+	}
+	interval = min(max(interval, p.Min), p.Max)
+	for _, hint := range []time.Duration{h.TTL, h.UpdatePeriod, h.MaxAge} {
+		interval = max(interval, hint)
+	}
+	return interval
+}
+
+// Backoff returns how long to wait after the given number of consecutive
+// failed polls: the feed's interval doubled for each failure, up to
+// MaxBackoff.
+func (p Policy) Backoff(interval time.Duration, failures int) time.Duration {
+	limit := max(p.MaxBackoff, interval)
+	for i := 0; i < failures && interval < limit; i++ {
+		interval *= 2
+	}
+	return min(interval, limit)
+}
+
+// medianGap returns the median time between a feed's most recent posts and
+// from the newest post until now, or zero if it has fewer than two dated
+// posts.
+func medianGap(published []time.Time, now time.Time) time.Duration {
+	var dates []time.Time
+	for _, t := range published {
+		if !t.IsZero() && !t.After(now) {
+			dates = append(dates, t)
+		}
+	}
+	if len(dates) < 2 {
+		return 0
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].After(dates[j]) })
+	if len(dates) > recentPosts {
+		dates = dates[:recentPosts]
+	}
+
+	gaps := []time.Duration{now.Sub(dates[0])}
+	for i := 1; i < len(dates); i++ {
+		gaps = append(gaps, dates[i-1].Sub(dates[i]))
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+	return gaps[len(gaps)/2]
+}
@@ -0,0 +1,74 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicy_Interval(t *testing.T) {
+	p := DefaultPolicy()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	every := func(gap time.Duration, n int) []time.Time {
+		var dates []time.Time
+		for i := 0; i < n; i++ {
+			dates = append(dates, now.Add(-time.Duration(i+1)*gap))
+		}
+		return dates
+	}
+
+	tests := []struct {
+		name  string
+		hints Hints
+		want  time.Duration
+	}{
+		{"no dates", Hints{}, time.Hour},
+		{"one dated post", Hints{Published: every(time.Hour, 1)}, time.Hour},
+		{"posts every 4 hours", Hints{Published: every(4*time.Hour, 5)}, 2 * time.Hour},
+		{"posts every few minutes", Hints{Published: every(5*time.Minute, 10)}, 15 * time.Minute},
+		{"posts weekly", Hints{Published: every(7*24*time.Hour, 5)}, 24 * time.Hour},
+		{"ttl asks for less", Hints{Published: every(4*time.Hour, 5), TTL: 3 * time.Hour}, 3 * time.Hour},
+		{"update period asks for less", Hints{Published: every(time.Hour, 5), UpdatePeriod: 6 * time.Hour}, 6 * time.Hour},
+		{"max-age asks for less", Hints{MaxAge: 90 * time.Minute}, 90 * time.Minute},
+		{"ttl beyond the max", Hints{Published: every(time.Hour, 5), TTL: 2880 * time.Minute}, 48 * time.Hour},
+		{"max-age beyond the max", Hints{Published: every(7*24*time.Hour, 5), MaxAge: 36 * time.Hour}, 36 * time.Hour},
+		{"undated and future posts ignored", Hints{Published: append(every(4*time.Hour, 3), time.Time{}, now.Add(time.Hour))}, 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Interval(tt.hints, now); got != tt.want {
+				t.Errorf("Interval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Interval_QuietFeed(t *testing.T) {
+	p := DefaultPolicy()
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	// Two posts an hour apart, then months of silence
+	quiet := Hints{Published: []time.Time{now.AddDate(0, -3, 0), now.AddDate(0, -3, 0).Add(-time.Hour)}}
+	if got := p.Interval(quiet, now); got != p.Max {
+		t.Errorf("Expected a quiet feed to be polled at the max interval, got %v", got)
+	}
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	p := DefaultPolicy()
+	tests := []struct {
+		interval time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{time.Hour, 0, time.Hour},
+		{time.Hour, 1, 2 * time.Hour},
+		{time.Hour, 3, 8 * time.Hour},
+		{time.Hour, 20, 7 * 24 * time.Hour},
+		{24 * time.Hour, 10, 7 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := p.Backoff(tt.interval, tt.failures); got != tt.want {
+			t.Errorf("Backoff(%v, %d) = %v, want %v", tt.interval, tt.failures, got, tt.want)
+		}
+	}
+}
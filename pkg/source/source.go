@@ -0,0 +1,58 @@
+// Package source defines the interface implemented by RSS reader backends
+// that supply our subscriptions and their entries.
+package source
+
+import "time"
+
+// Feed is a subscription in a reader.
+type Feed struct {
+	ID         string // Backend-specific feed ID
+	Title      string
+	FeedURL    string
+	SiteURL    string
+	Categories []string
+}
+
+// Entry is a post from a subscribed feed.
+type Entry struct {
+	ID          int64  // Backend entry ID; increases as entries arrive
+	GUID        string // Stable ID of the entry within its feed, if the backend has one
+	Title       string
+	URL         string
+	Content     string
+	Author      string
+	PublishedAt time.Time
+	UpdatedAt   time.Time // When the backend last saw the entry change, if known
+	Starred     bool
+}
+
+// Cursor limits which entries ListEntries returns.
+type Cursor struct {
+	AfterEntryID   int64     // Only entries with a greater ID
+	PublishedAfter time.Time // Only entries published after this time
+}
+
+// EntryIterator iterates over entries, fetching pages as needed.
+//
+//	it := src.ListEntries(feedID, cursor)
+//	for it.Next() {
+//		entry := it.Entry()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type EntryIterator interface {
+	Next() bool
+	Entry() Entry
+	Err() error
+}
+
+// Source is an RSS reader backend.
+type Source interface {
+	// Name identifies the backend, e.g. "miniflux".
+	Name() string
+
+	// ListFeeds returns all subscriptions.
+	ListFeeds() ([]Feed, error)
+
+	// ListEntries iterates over a feed's entries after the cursor, newest first.
+	ListEntries(feedID string, since Cursor) EntryIterator
+}
@@ -0,0 +1,147 @@
+// Force-directed view of a feed's neighborhood, drawn on a canvas.
+(function () {
+  "use strict";
+
+  var canvas = document.getElementById("graph");
+  if (!canvas) return;
+  var ctx = canvas.getContext("2d");
+  var hover = document.getElementById("hover");
+
+  var nodes = [], edges = [], byId = {}, centerId = 0;
+  var width = 0, height = 0, ticks = 0, hovered = null;
+
+  function resize() {
+    var ratio = window.devicePixelRatio || 1;
+    width = canvas.clientWidth;
+    height = canvas.clientHeight;
+    canvas.width = width * ratio;
+    canvas.height = height * ratio;
+    ctx.setTransform(ratio, 0, 0, ratio, 0, 0);
+  }
+
+  function radius(n) {
+    return n.id === centerId ? 9 : 4 + Math.min(n.degree, 10) * 0.5;
+  }
+
+  function step() {
+    var i, j, a, b, dx, dy, d2, d, f;
+
+    // Repulsion between every pair of nodes
+    for (i = 0; i < nodes.length; i++) {
+      for (j = i + 1; j < nodes.length; j++) {
+        a = nodes[i]; b = nodes[j];
+        dx = b.x - a.x; dy = b.y - a.y;
+        d2 = dx * dx + dy * dy || 0.01;
+        f = 800 / d2;
+        a.vx -= dx * f; a.vy -= dy * f;
+        b.vx += dx * f; b.vy += dy * f;
+      }
+    }
+
+    // Springs along edges
+    edges.forEach(function (e) {
+      a = byId[e.source]; b = byId[e.target];
+      dx = b.x - a.x; dy = b.y - a.y;
+      d = Math.sqrt(dx * dx + dy * dy) || 0.1;
+      f = (d - 80) * 0.02 / d;
+      a.vx += dx * f; a.vy += dy * f;
+      b.vx -= dx * f; b.vy -= dy * f;
+    });
+
+    // Gravity toward the middle, then integrate with damping
+    nodes.forEach(function (n) {
+      n.vx += (width / 2 - n.x) * 0.002;
+      n.vy += (height / 2 - n.y) * 0.002;
+      if (n.id === centerId) {
+        n.x = width / 2; n.y = height / 2;
+        n.vx = n.vy = 0;
+        return;
+      }
+      n.vx *= 0.6; n.vy *= 0.6;
+      n.x += Math.max(-10, Math.min(10, n.vx));
+      n.y += Math.max(-10, Math.min(10, n.vy));
+    });
+  }
+
+  function draw() {
+    ctx.clearRect(0, 0, width, height);
+
+    ctx.strokeStyle = "rgba(107, 114, 128, 0.35)";
+    edges.forEach(function (e) {
+      var a = byId[e.source], b = byId[e.target];
+      ctx.lineWidth = Math.min(1 + Math.log(e.weight), 5);
+      ctx.beginPath();
+      ctx.moveTo(a.x, a.y);
+      ctx.lineTo(b.x, b.y);
+      ctx.stroke();
+    });
+
+    nodes.forEach(function (n) {
+      ctx.beginPath();
+      ctx.arc(n.x, n.y, radius(n), 0, Math.PI * 2);
+      ctx.fillStyle = n.subscribed ? "#2563eb" : "#9ca3af";
+      if (n === hovered) ctx.fillStyle = "#f59e0b";
+      ctx.fill();
+      if (n.id === centerId || n === hovered || n.depth === 1 && nodes.length < 40) {
+        ctx.fillStyle = "#111827";
+        ctx.font = "12px sans-serif";
+        ctx.fillText(n.title, n.x + radius(n) + 3, n.y + 4);
+      }
+    });
+  }
+
+  function frame() {
+    if (ticks < 300) {
+      step();
+      ticks++;
+    }
+    draw();
+    window.requestAnimationFrame(frame);
+  }
+
+  function nodeAt(evt) {
+    var rect = canvas.getBoundingClientRect();
+    var x = evt.clientX - rect.left, y = evt.clientY - rect.top;
+    for (var i = nodes.length - 1; i >= 0; i--) {
+      var n = nodes[i], r = radius(n) + 2;
+      if ((n.x - x) * (n.x - x) + (n.y - y) * (n.y - y) <= r * r) return n;
+    }
+    return null;
+  }
+
+  canvas.addEventListener("mousemove", function (evt) {
+    hovered = nodeAt(evt);
+    canvas.style.cursor = hovered ? "pointer" : "default";
+    hover.textContent = hovered ? hovered.title + " — " + hovered.url : " ";
+  });
+
+  canvas.addEventListener("click", function (evt) {
+    var n = nodeAt(evt);
+    if (n) window.location = "/feeds/" + n.id;
+  });
+
+  window.addEventListener("resize", resize);
+  resize();
+
+  fetch(canvas.dataset.src)
+    .then(function (res) { return res.json(); })
+    .then(function (data) {
+      centerId = data.center;
+      nodes = data.nodes;
+      edges = data.edges || [];
+      nodes.forEach(function (n, i) {
+        var angle = i / nodes.length * Math.PI * 2;
+        var dist = 60 * n.depth + Math.random() * 20;
+        n.x = width / 2 + Math.cos(angle) * dist;
+        n.y = height / 2 + Math.sin(angle) * dist;
+        n.vx = n.vy = 0;
+        n.degree = 0;
+        byId[n.id] = n;
+      });
+      edges.forEach(function (e) {
+        byId[e.source].degree++;
+        byId[e.target].degree++;
+      });
+      frame();
+    });
+})();
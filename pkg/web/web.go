@@ -0,0 +1,353 @@
+// Package web serves a browser UI for exploring the feed graph.
+package web
+
+import (
+	"embed"
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+)
+
+//go:embed templates static
+var assets embed.FS
+
+// maxNeighborhoodNodes caps the force-directed view so it stays readable.
+const maxNeighborhoodNodes = 150
+
+// Server renders pages backed by graph queries.
+type Server struct {
+	g     *graph.Graph
+	pages map[string]*template.Template
+}
+
+// New creates a Server for the given graph.
+func New(g *graph.Graph) (*Server, error) {
+	funcs := template.FuncMap{
+		"title": displayTitle,
+		"pct":   func(v float64) string { return strconv.FormatFloat(v*100, 'f', 0, 64) },
+	}
+
+	pages := make(map[string]*template.Template)
+	for _, name := range []string{"rank", "feed", "mentions", "neighborhood"} {
+		t, err := template.New("layout.html").Funcs(funcs).ParseFS(assets, "templates/layout.html", "templates/"+name+".html")
+		if err != nil {
+			return nil, err
+		}
+		pages[name] = t
+	}
+	return &Server{g: g, pages: pages}, nil
+}
+
+// Handler returns the HTTP handler for the UI.
+func (s *Server) Handler() http.Handler {
+	static, _ := fs.Sub(assets, "static")
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.FS(static))))
+	mux.HandleFunc("GET /{$}", s.handleRank)
+	mux.HandleFunc("GET /feeds/{id}", s.handleFeed)
+	mux.HandleFunc("GET /feeds/{id}/graph", s.handleNeighborhoodPage)
+	mux.HandleFunc("GET /feeds/{id}/neighborhood.json", s.handleNeighborhood)
+	mux.HandleFunc("GET /mentions", s.handleMentions)
+	return mux
+}
+
+func (s *Server) render(w http.ResponseWriter, page string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.pages[page].Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleRank(w http.ResponseWriter, r *http.Request) {
+	category := r.URL.Query().Get("category")
+	ranked, err := s.g.RankFeeds(graph.RankOptions{
+		Limit:    intParam(r, "n", 50),
+		Category: category,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.render(w, "rank", map[string]any{
+		"Title":    "Most linked",
+		"Category": category,
+		"Ranked":   ranked,
+	})
+}
+
+// edgeGroup is the set of links between a feed and one other site.
+type edgeGroup struct {
+	Feed  *graph.FeedNode
+	Links []graph.LinkEdge
+}
+
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	feed, ok := s.feedParam(w, r)
+	if !ok {
+		return
+	}
+
+	inbound, err := s.g.GetInboundLinks(feed.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	outbound, err := s.g.GetOutboundLinks(feed.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tags, err := s.g.GetFeedTags(feed.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	in, err := s.groupEdges(inbound, func(l graph.LinkEdge) int64 { return l.SourceID })
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out, err := s.groupEdges(outbound, func(l graph.LinkEdge) int64 { return l.TargetID })
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.render(w, "feed", map[string]any{
+		"Title":    displayTitle(feed),
+		"Feed":     feed,
+		"Tags":     tags,
+		"Inbound":  in,
+		"Outbound": out,
+	})
+}
+
+// groupEdges groups links by the feed on the other end, most links first.
+func (s *Server) groupEdges(links []graph.LinkEdge, other func(graph.LinkEdge) int64) ([]edgeGroup, error) {
+	byFeed := make(map[int64]*edgeGroup)
+	var order []int64
+	for _, l := range links {
+		id := other(l)
+		group, ok := byFeed[id]
+		if !ok {
+			feed, err := s.g.GetFeedByID(id)
+			if err != nil {
+				return nil, err
+			}
+			if feed == nil {
+				continue
+			}
+			group = &edgeGroup{Feed: feed}
+			byFeed[id] = group
+			order = append(order, id)
+		}
+		group.Links = append(group.Links, l)
+	}
+
+	groups := make([]edgeGroup, 0, len(order))
+	for _, id := range order {
+		groups = append(groups, *byFeed[id])
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Links) > len(groups[j].Links)
+	})
+	return groups, nil
+}
+
+func (s *Server) handleMentions(w http.ResponseWriter, r *http.Request) {
+	entityType := r.URL.Query().Get("type")
+	if entityType == "" {
+		entityType = "PERSON"
+	}
+	limit := intParam(r, "n", 30)
+
+	mentions, err := s.g.GetMostMentioned(entityType, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]any{
+		"Title":    "Mentions",
+		"Mentions": mentions,
+	}
+
+	dates, err := s.g.GetSnapshotDates()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(dates) >= 2 {
+		rising, err := s.g.GetRisingMentions(entityType, dates[0], dates[1], limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data["Rising"] = rising
+		data["CurrentDate"] = dates[0]
+		data["PreviousDate"] = dates[1]
+	}
+
+	s.render(w, "mentions", data)
+}
+
+func (s *Server) handleNeighborhoodPage(w http.ResponseWriter, r *http.Request) {
+	feed, ok := s.feedParam(w, r)
+	if !ok {
+		return
+	}
+	s.render(w, "neighborhood", map[string]any{
+		"Title": "Around " + displayTitle(feed),
+		"Feed":  feed,
+		"Depth": intParam(r, "depth", 1),
+	})
+}
+
+type jsonNode struct {
+	ID         int64  `json:"id"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	Subscribed bool   `json:"subscribed"`
+	Depth      int    `json:"depth"`
+}
+
+type jsonEdge struct {
+	Source int64 `json:"source"`
+	Target int64 `json:"target"`
+	Weight int   `json:"weight"`
+}
+
+// handleNeighborhood returns the nodes and edges within depth hops of a feed,
+// following links in both directions.
+func (s *Server) handleNeighborhood(w http.ResponseWriter, r *http.Request) {
+	center, ok := s.feedParam(w, r)
+	if !ok {
+		return
+	}
+	depth := intParam(r, "depth", 1)
+	if depth < 1 || depth > 3 {
+		depth = 1
+	}
+
+	nodes := map[int64]*jsonNode{center.ID: toJSONNode(center, 0)}
+	weights := make(map[[2]int64]int)
+	counted := make(map[int64]bool) // Link IDs, since a link is seen from both ends
+	frontier := []int64{center.ID}
+
+	for d := 1; d <= depth && len(frontier) > 0; d++ {
+		var next []int64
+		for _, id := range frontier {
+			inbound, err := s.g.GetInboundLinks(id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			outbound, err := s.g.GetOutboundLinks(id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			for _, l := range append(inbound, outbound...) {
+				other := l.SourceID
+				if other == id {
+					other = l.TargetID
+				}
+				if _, seen := nodes[other]; !seen {
+					if len(nodes) >= maxNeighborhoodNodes {
+						continue
+					}
+					feed, err := s.g.GetFeedByID(other)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					}
+					if feed == nil {
+						// A link to a feed that's gone; its edges are dropped below
+						continue
+					}
+					nodes[other] = toJSONNode(feed, d)
+					next = append(next, other)
+				}
+				if !counted[l.ID] {
+					counted[l.ID] = true
+					weights[[2]int64{l.SourceID, l.TargetID}]++
+				}
+			}
+		}
+		frontier = next
+	}
+
+	response := struct {
+		Center int64      `json:"center"`
+		Nodes  []jsonNode `json:"nodes"`
+		Edges  []jsonEdge `json:"edges"`
+	}{Center: center.ID}
+	for _, n := range nodes {
+		response.Nodes = append(response.Nodes, *n)
+	}
+	sort.Slice(response.Nodes, func(i, j int) bool { return response.Nodes[i].ID < response.Nodes[j].ID })
+	for key, weight := range weights {
+		// Links found from a frontier node can point outside the capped set
+		if nodes[key[0]] == nil || nodes[key[1]] == nil {
+			continue
+		}
+		response.Edges = append(response.Edges, jsonEdge{Source: key[0], Target: key[1], Weight: weight})
+	}
+	sort.Slice(response.Edges, func(i, j int) bool {
+		a, b := response.Edges[i], response.Edges[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Target < b.Target
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func toJSONNode(f *graph.FeedNode, depth int) *jsonNode {
+	return &jsonNode{ID: f.ID, Title: displayTitle(f), URL: f.URL, Subscribed: f.Subscribed, Depth: depth}
+}
+
+// feedParam loads the feed named by the {id} path parameter, writing a 404 if it doesn't exist.
+func (s *Server) feedParam(w http.ResponseWriter, r *http.Request) (*graph.FeedNode, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	feed, err := s.g.GetFeedByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if feed == nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	return feed, true
+}
+
+func intParam(r *http.Request, name string, def int) int {
+	v, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil || v <= 0 {
+		return def
+	}
+	return v
+}
+
+func displayTitle(f *graph.FeedNode) string {
+	if f.Title == "" {
+		return "(untitled)"
+	}
+	return f.Title
+}
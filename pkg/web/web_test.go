@@ -0,0 +1,212 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+)
+
+// newTestServer returns a server over a small graph: two subscriptions both
+// linking to a third feed, and the third linking back to the first.
+func newTestServer(t *testing.T) (*httptest.Server, map[string]int64) {
+	t.Helper()
+	g, err := graph.NewGraph(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewGraph error: %v", err)
+	}
+	t.Cleanup(func() { g.Close() })
+
+	ids := make(map[string]int64)
+	for _, f := range []*graph.FeedNode{
+		{URL: "https://a.com/feed", Title: "Alpha", Subscribed: true, Source: "manual"},
+		{URL: "https://b.com/feed", Title: "Beta", Subscribed: true, Source: "manual"},
+		{URL: "https://c.com/feed", Title: "Gamma"},
+	} {
+		id, err := g.AddFeed(f)
+		if err != nil {
+			t.Fatalf("AddFeed error: %v", err)
+		}
+		ids[f.Title] = id
+	}
+
+	published := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, l := range []*graph.LinkEdge{
+		{SourceID: ids["Alpha"], TargetID: ids["Gamma"], PostURL: "https://a.com/1", PostTitle: "Reading Gamma", Context: "a great post", PublishedAt: published},
+		{SourceID: ids["Beta"], TargetID: ids["Gamma"], PostURL: "https://b.com/1", PostTitle: "Also Gamma"},
+		{SourceID: ids["Gamma"], TargetID: ids["Alpha"], PostURL: "https://c.com/1", PostTitle: "Reply to Alpha"},
+	} {
+		if err := g.AddLink(l); err != nil {
+			t.Fatalf("AddLink error: %v", err)
+		}
+	}
+	if err := g.AddMention(&graph.Mention{SourceID: ids["Alpha"], Name: "Ada Lovelace", EntityType: "PERSON"}); err != nil {
+		t.Fatalf("AddMention error: %v", err)
+	}
+
+	s, err := New(g)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+	return srv, ids
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s error: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestServer_Rank(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	status, body := get(t, srv.URL+"/")
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if !strings.Contains(body, "Gamma") || !strings.Contains(body, "2 links") {
+		t.Errorf("Expected Gamma ranked with 2 links, got:\n%s", body)
+	}
+}
+
+func TestServer_Feed(t *testing.T) {
+	srv, ids := newTestServer(t)
+
+	status, body := get(t, srv.URL+"/feeds/"+strconv.FormatInt(ids["Gamma"], 10))
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	for _, want := range []string{"Linked from (2 sites)", "Links to (1 sites)", "Reading Gamma", "a great post", "2024-03-01", "Reply to Alpha"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected feed page to contain %q", want)
+		}
+	}
+}
+
+func TestServer_Feed_NotFound(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	for _, path := range []string{"/feeds/999", "/feeds/abc", "/feeds/999/neighborhood.json"} {
+		if status, _ := get(t, srv.URL+path); status != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, status)
+		}
+	}
+}
+
+func TestServer_Mentions(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	status, body := get(t, srv.URL+"/mentions")
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if !strings.Contains(body, "Ada Lovelace") {
+		t.Error("Expected mentions page to list Ada Lovelace")
+	}
+	if !strings.Contains(body, "Need at least 2 snapshots") {
+		t.Error("Expected snapshot hint without snapshots")
+	}
+}
+
+func TestServer_Neighborhood(t *testing.T) {
+	srv, ids := newTestServer(t)
+
+	status, body := get(t, srv.URL+"/feeds/"+strconv.FormatInt(ids["Alpha"], 10)+"/neighborhood.json?depth=1")
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+
+	var resp struct {
+		Center int64      `json:"center"`
+		Nodes  []jsonNode `json:"nodes"`
+		Edges  []jsonEdge `json:"edges"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if resp.Center != ids["Alpha"] {
+		t.Errorf("Expected center %d, got %d", ids["Alpha"], resp.Center)
+	}
+	// Alpha links to and from Gamma; Beta is two hops away
+	if len(resp.Nodes) != 2 {
+		t.Errorf("Expected 2 nodes at depth 1, got %d", len(resp.Nodes))
+	}
+	if len(resp.Edges) != 2 {
+		t.Errorf("Expected 2 edges at depth 1, got %d", len(resp.Edges))
+	}
+
+	_, body = get(t, srv.URL+"/feeds/"+strconv.FormatInt(ids["Alpha"], 10)+"/neighborhood.json?depth=2")
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(resp.Nodes) != 3 || len(resp.Edges) != 3 {
+		t.Errorf("Expected 3 nodes and 3 edges at depth 2, got %d and %d", len(resp.Nodes), len(resp.Edges))
+	}
+	for _, e := range resp.Edges {
+		if e.Weight != 1 {
+			t.Errorf("Expected weight 1 for %d->%d, got %d", e.Source, e.Target, e.Weight)
+		}
+	}
+}
+
+func TestServer_Neighborhood_DanglingLink(t *testing.T) {
+	g, err := graph.NewGraph(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewGraph error: %v", err)
+	}
+	defer g.Close()
+	a, _ := g.AddFeed(&graph.FeedNode{URL: "https://a.com/feed", Title: "Alpha", Subscribed: true})
+	b, _ := g.AddFeed(&graph.FeedNode{URL: "https://b.com/feed", Title: "Beta"})
+	g.AddLink(&graph.LinkEdge{SourceID: a, TargetID: b, PostURL: "https://a.com/1"})
+	// A link whose target feed no longer exists
+	g.AddLink(&graph.LinkEdge{SourceID: a, TargetID: b + 100, PostURL: "https://a.com/2"})
+
+	s, err := New(g)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	status, body := get(t, srv.URL+"/feeds/"+strconv.FormatInt(a, 10)+"/neighborhood.json")
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", status, body)
+	}
+	var resp struct {
+		Nodes []jsonNode `json:"nodes"`
+		Edges []jsonEdge `json:"edges"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(resp.Nodes) != 2 || len(resp.Edges) != 1 {
+		t.Errorf("Expected the dangling link left out, got %d nodes and %d edges", len(resp.Nodes), len(resp.Edges))
+	}
+}
+
+func TestServer_Static(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	for _, path := range []string{"/static/style.css", "/static/graph.js"} {
+		if status, _ := get(t, srv.URL+path); status != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", path, status)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+// Package websub subscribes to the WebSub hubs feeds advertise and receives
+// the content they push.
+package websub
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+)
+
+const (
+	// renewBefore is how long before a lease ends Renew asks for a new one.
+	renewBefore = time.Hour
+	// verifyTimeout is how long Renew waits for a hub to verify a
+	// subscription before asking again.
+	verifyTimeout = time.Hour
+	// maxBody caps the content a hub can push in one request.
+	maxBody = 10 << 20
+)
+
+// ErrGone is returned by a DeliverFunc for feeds we no longer want; the hub
+// is told so and the subscription is dropped.
+var ErrGone = errors.New("feed no longer wanted")
+
+// DeliverFunc ingests the content a hub pushed for a feed.
+type DeliverFunc func(feedID int64, body []byte) error
+
+// Subscriber asks hubs for subscriptions and serves the callbacks they
+// verify them with and push content to. Each feed's callback is the
+// callback URL followed by "/" and its feed ID.
+type Subscriber struct {
+	g           *graph.Graph
+	callbackURL string
+	deliver     DeliverFunc
+	client      *http.Client
+	lease       time.Duration
+	log         *log.Logger
+}
+
+// Option configures a Subscriber.
+type Option func(*Subscriber)
+
+// WithClient sets the HTTP client used to talk to hubs.
+func WithClient(c *http.Client) Option {
+	return func(s *Subscriber) {
+		s.client = c
+	}
+}
+
+// WithLease sets the lease requested from hubs, which may grant another.
+func WithLease(d time.Duration) Option {
+	return func(s *Subscriber) {
+		s.lease = d
+	}
+}
+
+// WithLogger sets where rejected pushes and failed deliveries are logged.
+func WithLogger(l *log.Logger) Option {
+	return func(s *Subscriber) {
+		s.log = l
+	}
+}
+
+// New creates a Subscriber whose callbacks live under callbackURL and
+// which passes pushed content to deliver.
+func New(g *graph.Graph, callbackURL string, deliver DeliverFunc, opts ...Option) *Subscriber {
+	s := &Subscriber{
+		g:           g,
+		callbackURL: strings.TrimSuffix(callbackURL, "/"),
+		deliver:     deliver,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		lease:       7 * 24 * time.Hour,
+		log:         log.Default(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Callback returns the callback URL for a feed.
+func (s *Subscriber) Callback(feedID int64) string {
+	return s.callbackURL + "/" + strconv.FormatInt(feedID, 10)
+}
+
+// Follow subscribes to a feed through the first of its hubs, unless it is
+// already subscribed, or was denied, through one of them for the same
+// topic. It reports whether it asked a hub.
+func (s *Subscriber) Follow(feedID int64, hubs []string, topic string) (bool, error) {
+	if len(hubs) == 0 || topic == "" {
+		return false, nil
+	}
+	sub, err := s.g.GetWebSub(feedID)
+	if err != nil {
+		return false, err
+	}
+	if sub != nil && sub.Topic == topic && slices.Contains(hubs, sub.Hub) && sub.State != graph.WebSubUnsubscribing {
+		return false, nil
+	}
+	return true, s.Subscribe(feedID, hubs[0], topic)
+}
+
+// Subscribe asks hub to push a feed's updates to its callback. The
+// subscription is pending until the hub verifies it; a renewal of an active
+// one stays active meanwhile, keeping its secret so pushes in between still
+// check out.
+func (s *Subscriber) Subscribe(feedID int64, hub, topic string) error {
+	sub, err := s.g.GetWebSub(feedID)
+	if err != nil {
+		return err
+	}
+	if sub == nil || sub.Hub != hub || sub.Topic != topic || sub.State == graph.WebSubUnsubscribing {
+		secret, err := newSecret()
+		if err != nil {
+			return err
+		}
+		sub = &graph.WebSubSubscription{FeedID: feedID, Hub: hub, Topic: topic, Secret: secret}
+	}
+	if sub.State != graph.WebSubActive {
+		sub.State = graph.WebSubPending
+		sub.LeaseExpires = time.Time{}
+	}
+	sub.Error = ""
+	if err := s.g.SetWebSub(sub); err != nil {
+		return err
+	}
+
+	return s.request(hub, url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {topic},
+		"hub.callback":      {s.Callback(feedID)},
+		"hub.secret":        {sub.Secret},
+		"hub.lease_seconds": {strconv.Itoa(int(s.lease / time.Second))},
+	})
+}
+
+// Unsubscribe asks a feed's hub to stop pushing to it. The subscription is
+// removed once the hub verifies the request.
+func (s *Subscriber) Unsubscribe(feedID int64) error {
+	sub, err := s.g.GetWebSub(feedID)
+	if err != nil || sub == nil {
+		return err
+	}
+	sub.State = graph.WebSubUnsubscribing
+	if err := s.g.SetWebSub(sub); err != nil {
+		return err
+	}
+	return s.request(sub.Hub, url.Values{
+		"hub.mode":     {"unsubscribe"},
+		"hub.topic":    {sub.Topic},
+		"hub.callback": {s.Callback(feedID)},
+	})
+}
+
+// request sends a subscription request, which hubs accept with 202.
+func (s *Subscriber) request(hub string, form url.Values) error {
+	resp, err := s.client.PostForm(hub, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("hub %s refused %s: %s %s", hub, form.Get("hub.mode"), resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Renew asks hubs again for subscriptions whose lease is about to end and
+// for those they never verified, returning how many it asked for.
+func (s *Subscriber) Renew(now time.Time) (int, error) {
+	subs, err := s.g.GetWebSubs()
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	renewed := 0
+	for _, sub := range subs {
+		switch {
+		case sub.State == graph.WebSubActive && now.Add(renewBefore).After(sub.LeaseExpires):
+		case sub.State == graph.WebSubPending && now.Sub(sub.UpdatedAt) > verifyTimeout:
+		default:
+			continue
+		}
+		renewed++
+		if err := s.Subscribe(sub.FeedID, sub.Hub, sub.Topic); err != nil {
+			errs = append(errs, fmt.Errorf("feed %d: %w", sub.FeedID, err))
+		}
+	}
+	return renewed, errors.Join(errs...)
+}
+
+// Handler returns the HTTP handler for the callbacks, to be served at the
+// callback URL's path.
+func (s *Subscriber) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{id}", s.handleVerify)
+	mux.HandleFunc("POST /{id}", s.handlePush)
+	return mux
+}
+
+// handleVerify answers a hub checking that we asked for a subscription or
+// unsubscription, or telling us it denied one.
+func (s *Subscriber) handleVerify(w http.ResponseWriter, r *http.Request) {
+	sub, ok := s.subscription(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	if q.Get("hub.topic") != sub.Topic {
+		http.NotFound(w, r)
+		return
+	}
+
+	var err error
+	switch mode := q.Get("hub.mode"); {
+	case mode == "denied":
+		sub.State, sub.Error = graph.WebSubDenied, q.Get("hub.reason")
+		if sub.Error == "" {
+			sub.Error = "denied by hub"
+		}
+		err = s.g.SetWebSub(sub)
+	case mode == "subscribe" && sub.State != graph.WebSubUnsubscribing:
+		lease := s.lease
+		if seconds, perr := strconv.Atoi(q.Get("hub.lease_seconds")); perr == nil && seconds > 0 {
+			lease = time.Duration(seconds) * time.Second
+		}
+		sub.State, sub.Error = graph.WebSubActive, ""
+		sub.LeaseExpires = time.Now().Add(lease)
+		err = s.g.SetWebSub(sub)
+	case mode == "unsubscribe" && sub.State == graph.WebSubUnsubscribing:
+		err = s.g.DeleteWebSub(sub.FeedID)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	io.WriteString(w, q.Get("hub.challenge"))
+}
+
+// handlePush passes on content a hub pushed, provided it is signed with
+// the subscription's secret. Per the spec, badly signed content is
+// acknowledged but ignored.
+func (s *Subscriber) handlePush(w http.ResponseWriter, r *http.Request) {
+	sub, ok := s.subscription(w, r)
+	if !ok {
+		return
+	}
+	if sub.State == graph.WebSubDenied || sub.State == graph.WebSubUnsubscribing {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !VerifySignature(sub.Secret, r.Header.Get("X-Hub-Signature"), body) {
+		s.log.Printf("WebSub: ignoring push for feed %d with a bad signature", sub.FeedID)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	err = s.deliver(sub.FeedID, body)
+	switch {
+	case errors.Is(err, ErrGone):
+		if err := s.g.DeleteWebSub(sub.FeedID); err != nil {
+			s.log.Printf("WebSub: feed %d: %v", sub.FeedID, err)
+		}
+		w.WriteHeader(http.StatusGone)
+	case err != nil:
+		s.log.Printf("WebSub: failed to ingest push for feed %d: %v", sub.FeedID, err)
+		http.Error(w, "failed to ingest content", http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// subscription looks up the subscription for a callback request, answering
+// 404 if there is none.
+func (s *Subscriber) subscription(w http.ResponseWriter, r *http.Request) (*graph.WebSubSubscription, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	sub, err := s.g.GetWebSub(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if sub == nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	return sub, true
+}
+
+// signatureHashes are the X-Hub-Signature methods hubs may use.
+var signatureHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// Sign returns the X-Hub-Signature a hub sends with body, using SHA-256.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether an X-Hub-Signature header is the HMAC of
+// body with secret.
+func VerifySignature(secret, header string, body []byte) bool {
+	method, sig, ok := strings.Cut(header, "=")
+	newHash := signatureHashes[strings.ToLower(method)]
+	if !ok || newHash == nil {
+		return false
+	}
+	want, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// newSecret returns a random secret for a hub to sign pushes with.
+func newSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,241 @@
+package websub
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daniel-butler/rss-graph/pkg/graph"
+)
+
+// testHub stands in for a WebSub hub, recording subscription requests and
+// calling back the subscriber when told to.
+type testHub struct {
+	t        *testing.T
+	server   *httptest.Server
+	mu       sync.Mutex
+	requests []url.Values
+}
+
+func newTestHub(t *testing.T) *testHub {
+	h := &testHub{t: t}
+	h.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.mu.Lock()
+		h.requests = append(h.requests, r.PostForm)
+		h.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(h.server.Close)
+	return h
+}
+
+func (h *testHub) last() url.Values {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.requests) == 0 {
+		h.t.Fatal("Expected a subscription request")
+	}
+	return h.requests[len(h.requests)-1]
+}
+
+// verify calls back the last request's callback as a hub verifying it,
+// returning the response status and body.
+func (h *testHub) verify(params url.Values) (int, string) {
+	req := h.last()
+	q := url.Values{"hub.challenge": {"challenge-123"}}
+	for k, v := range params {
+		q[k] = v
+	}
+	resp, err := http.Get(req.Get("hub.callback") + "?" + q.Encode())
+	if err != nil {
+		h.t.Fatalf("Verification error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+// push delivers content to the last request's callback with a signature.
+func (h *testHub) push(body, signature string) int {
+	req, _ := http.NewRequest(http.MethodPost, h.last().Get("hub.callback"), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/rss+xml")
+	req.Header.Set("X-Hub-Signature", signature)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		h.t.Fatalf("Push error: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func newTestSubscriber(t *testing.T, deliver DeliverFunc) (*Subscriber, *graph.Graph, int64) {
+	t.Helper()
+	g, err := graph.NewGraph(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewGraph error: %v", err)
+	}
+	t.Cleanup(func() { g.Close() })
+	feedID, err := g.AddFeed(&graph.FeedNode{URL: "https://blog.example/feed.xml", Subscribed: true})
+	if err != nil {
+		t.Fatalf("AddFeed error: %v", err)
+	}
+
+	var handler http.Handler
+	callbacks := httptest.NewServer(http.StripPrefix("/websub", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+	})))
+	t.Cleanup(callbacks.Close)
+
+	s := New(g, callbacks.URL+"/websub/", deliver, WithLease(time.Hour), WithLogger(log.New(io.Discard, "", 0)))
+	handler = s.Handler()
+	return s, g, feedID
+}
+
+func TestSubscriber_SubscribeVerifyPush(t *testing.T) {
+	var delivered [][]byte
+	s, g, feedID := newTestSubscriber(t, func(id int64, body []byte) error {
+		delivered = append(delivered, body)
+		return nil
+	})
+	hub := newTestHub(t)
+	topic := "https://blog.example/feed.xml"
+
+	asked, err := s.Follow(feedID, []string{hub.server.URL}, topic)
+	if err != nil || !asked {
+		t.Fatalf("Follow = %v, %v, want a subscription request", asked, err)
+	}
+	req := hub.last()
+	if req.Get("hub.mode") != "subscribe" || req.Get("hub.topic") != topic || req.Get("hub.lease_seconds") != "3600" {
+		t.Errorf("Unexpected subscription request %v", req)
+	}
+	if !strings.HasSuffix(req.Get("hub.callback"), "/websub/1") || req.Get("hub.secret") == "" {
+		t.Errorf("Expected a per-feed callback and a secret, got %v", req)
+	}
+	if sub, _ := g.GetWebSub(feedID); sub == nil || sub.State != graph.WebSubPending {
+		t.Fatalf("Expected a pending subscription, got %+v", sub)
+	}
+
+	if status, _ := hub.verify(url.Values{"hub.mode": {"subscribe"}, "hub.topic": {"https://other.example/"}}); status != http.StatusNotFound {
+		t.Errorf("Expected 404 verifying another topic, got %d", status)
+	}
+	status, body := hub.verify(url.Values{"hub.mode": {"subscribe"}, "hub.topic": {topic}, "hub.lease_seconds": {"600"}})
+	if status != http.StatusOK || body != "challenge-123" {
+		t.Fatalf("Expected the challenge echoed, got %d %q", status, body)
+	}
+	sub, _ := g.GetWebSub(feedID)
+	if sub.State != graph.WebSubActive || time.Until(sub.LeaseExpires) > 11*time.Minute || time.Until(sub.LeaseExpires) < 9*time.Minute {
+		t.Errorf("Expected an active subscription with the hub's 10m lease, got %+v", sub)
+	}
+
+	content := `<rss version="2.0"><channel><title>T</title></channel></rss>`
+	if status := hub.push(content, Sign(req.Get("hub.secret"), []byte(content))); status != http.StatusAccepted {
+		t.Errorf("Expected 202 for a signed push, got %d", status)
+	}
+	if status := hub.push(content, Sign("wrong", []byte(content))); status != http.StatusAccepted {
+		t.Errorf("Expected a badly signed push acknowledged, got %d", status)
+	}
+	if len(delivered) != 1 || !bytes.Equal(delivered[0], []byte(content)) {
+		t.Errorf("Expected only the signed push delivered, got %d", len(delivered))
+	}
+
+	// The 10m lease ends within the renewal window; renewing keeps the
+	// secret and the subscription active
+	renewed, err := s.Renew(time.Now())
+	if err != nil || renewed != 1 {
+		t.Fatalf("Renew = %d, %v, want 1", renewed, err)
+	}
+	if again := hub.last(); again.Get("hub.secret") != req.Get("hub.secret") {
+		t.Error("Expected the renewal to keep the secret")
+	}
+	if sub, _ := g.GetWebSub(feedID); sub.State != graph.WebSubActive {
+		t.Errorf("Expected the subscription to stay active while renewing, got %s", sub.State)
+	}
+	if asked, _ := s.Follow(feedID, []string{hub.server.URL}, topic); asked {
+		t.Error("Expected Follow to leave an existing subscription alone")
+	}
+
+	if err := s.Unsubscribe(feedID); err != nil {
+		t.Fatalf("Unsubscribe error: %v", err)
+	}
+	if hub.last().Get("hub.mode") != "unsubscribe" {
+		t.Errorf("Expected an unsubscribe request, got %v", hub.last())
+	}
+	if status := hub.push(content, Sign(req.Get("hub.secret"), []byte(content))); status != http.StatusGone {
+		t.Errorf("Expected 410 for a push while unsubscribing, got %d", status)
+	}
+	if status, _ := hub.verify(url.Values{"hub.mode": {"unsubscribe"}, "hub.topic": {topic}}); status != http.StatusOK {
+		t.Errorf("Expected the unsubscribe verified, got %d", status)
+	}
+	if sub, _ := g.GetWebSub(feedID); sub != nil {
+		t.Errorf("Expected the subscription removed, got %+v", sub)
+	}
+}
+
+func TestSubscriber_Denied(t *testing.T) {
+	s, g, feedID := newTestSubscriber(t, func(int64, []byte) error { return nil })
+	hub := newTestHub(t)
+	topic := "https://blog.example/feed.xml"
+
+	if err := s.Subscribe(feedID, hub.server.URL, topic); err != nil {
+		t.Fatalf("Subscribe error: %v", err)
+	}
+	if status, _ := hub.verify(url.Values{"hub.mode": {"denied"}, "hub.topic": {topic}, "hub.reason": {"quota"}}); status != http.StatusOK {
+		t.Errorf("Expected 200 acknowledging the denial, got %d", status)
+	}
+	if sub, _ := g.GetWebSub(feedID); sub.State != graph.WebSubDenied || sub.Error != "quota" {
+		t.Errorf("Expected a denied subscription with the hub's reason, got %+v", sub)
+	}
+	if asked, _ := s.Follow(feedID, []string{hub.server.URL}, topic); asked {
+		t.Error("Expected Follow not to ask a hub that denied us again")
+	}
+}
+
+func TestSubscriber_Gone(t *testing.T) {
+	s, g, feedID := newTestSubscriber(t, func(int64, []byte) error { return ErrGone })
+	hub := newTestHub(t)
+	topic := "https://blog.example/feed.xml"
+
+	if err := s.Subscribe(feedID, hub.server.URL, topic); err != nil {
+		t.Fatalf("Subscribe error: %v", err)
+	}
+	hub.verify(url.Values{"hub.mode": {"subscribe"}, "hub.topic": {topic}})
+	if status := hub.push("x", Sign(hub.last().Get("hub.secret"), []byte("x"))); status != http.StatusGone {
+		t.Errorf("Expected 410 for a feed we no longer want, got %d", status)
+	}
+	if sub, _ := g.GetWebSub(feedID); sub != nil {
+		t.Errorf("Expected the subscription dropped, got %+v", sub)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte("hello")
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{Sign("s3cret", body), true},
+		{"sha1=f875ad165d97aba6b68e2c2f7ea314aeb2a0082f", true},
+		{"sha1=0000ad165d97aba6b68e2c2f7ea314aeb2a0082f", false},
+		{Sign("other", body), false},
+		{"md5=abcd", false},
+		{"", false},
+		{"sha256=not-hex", false},
+	}
+	for _, tt := range tests {
+		if got := VerifySignature("s3cret", tt.header, body); got != tt.want {
+			t.Errorf("VerifySignature(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}